@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"sync-bot/util"
+)
+
+// divergenceOptions are the options of the divergence subcommand.
+type divergenceOptions struct {
+	org        string
+	repos      string
+	references string
+	format     string
+}
+
+func (o *divergenceOptions) addFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.org, "org", "", "Organization of the repositories.")
+	fs.StringVar(&o.repos, "repos", "",
+		"Comma separated repositories to compare the branches of. Every repository of the organization if empty.")
+	fs.StringVar(&o.references, "references", "",
+		"Comma separated patterns of the reference branches. master and *-LTS-Next if empty.")
+	fs.StringVar(&o.format, "format", "markdown", "Format of the report, markdown or json.")
+}
+
+func (o *divergenceOptions) validate() error {
+	if o.org == "" {
+		return errors.New("--org is required")
+	}
+	if o.format != "markdown" && o.format != "json" {
+		return fmt.Errorf("unsupported format %q, use markdown or json", o.format)
+	}
+	return nil
+}
+
+// runDivergence prints which protected branches of the repositories lack the
+// commits of the reference branches, and how their package versions differ.
+func runDivergence(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("divergence", flag.ExitOnError)
+	var o divergenceOptions
+	o.addFlags(fs)
+	bot, stop, err := newCommandRobot(fs, args)
+	if err != nil {
+		return err
+	}
+	defer stop()
+	if err = o.validate(); err != nil {
+		return err
+	}
+
+	r, err := bot.DivergenceReport(o.org, util.SplitList(o.repos), util.SplitList(o.references))
+	if err != nil {
+		return err
+	}
+	var b []byte
+	if o.format == "json" {
+		b, err = r.JSON()
+	} else {
+		var md string
+		md, err = r.Markdown()
+		b = []byte(md)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, string(b))
+	return err
+}
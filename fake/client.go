@@ -0,0 +1,595 @@
+// Package fake provides an in-memory platform client for tests and replays.
+package fake
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"sync-bot/platform"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+)
+
+// Comment is a comment created through the client.
+type Comment struct {
+	Org, Repo, Number string
+	Body              string
+}
+
+// Client is an in-memory platform client. The scripted state is read and
+// written through the exported fields, which are keyed with RepoKey and Key.
+// Lock the client while touching them from another goroutine. Create with
+// NewClient.
+type Client struct {
+	sync.Mutex
+
+	// User is the commenter of the comments created through the client.
+	User string
+
+	// PullRequests holds the pull requests by Key.
+	PullRequests map[string]client.PullRequest
+	// Authors holds the users who opened the pull requests by Key.
+	Authors map[string]string
+	// Reviewers holds the reviewers requested on pull requests by Key.
+	Reviewers map[string][]string
+	// Assignees holds the users assigned to pull requests by Key.
+	Assignees map[string][]string
+	// Commits holds the commits of pull requests by Key, the latest first.
+	Commits map[string][]client.PRCommit
+	// Comments holds the comments of pull requests and issues by Key, the latest first.
+	Comments map[string][]client.PRComment
+	// Issues holds the issues linked to pull requests by Key.
+	Issues map[string][]client.Issue
+	// Changes holds the files changed by pull requests by Key.
+	Changes map[string][]client.CommitFile
+	// Labels holds the labels of pull requests and issues by Key.
+	Labels map[string][]string
+	// Branches holds the branches of repositories by RepoKey.
+	Branches map[string][]string
+	// Protected holds the protected branches by Key(org, repo, branch).
+	Protected map[string]bool
+	// RepoLabels holds the labels of repositories by RepoKey.
+	RepoLabels map[string][]string
+	// Contents holds the base64 encoded files by Key(org, repo, branch+":"+path).
+	Contents map[string]string
+	// Writers holds the users allowed to push by Key(org, repo, user).
+	Writers map[string]bool
+	// Fail makes the named methods fail, like "CreatePR".
+	Fail map[string]bool
+
+	// CreatedPRs records the pull requests created, also found in PullRequests.
+	CreatedPRs []client.PullRequest
+	// PRComments records the comments created on pull requests.
+	PRComments []Comment
+	// IssueComments records the comments created on issues.
+	IssueComments []Comment
+	// EditedComments records the new bodies of the comments edited on pull requests.
+	EditedComments []Comment
+	// CreatedBranches records the branches created by Key(org, repo, branch).
+	CreatedBranches []string
+
+	// next is the number of the next pull request created.
+	next int
+}
+
+// NewClient returns an empty client.
+func NewClient() *Client {
+	return &Client{
+		User:         "sync-bot",
+		PullRequests: make(map[string]client.PullRequest),
+		Authors:      make(map[string]string),
+		Reviewers:    make(map[string][]string),
+		Assignees:    make(map[string][]string),
+		Commits:      make(map[string][]client.PRCommit),
+		Comments:     make(map[string][]client.PRComment),
+		Issues:       make(map[string][]client.Issue),
+		Changes:      make(map[string][]client.CommitFile),
+		Labels:       make(map[string][]string),
+		Branches:     make(map[string][]string),
+		Protected:    make(map[string]bool),
+		RepoLabels:   make(map[string][]string),
+		Contents:     make(map[string]string),
+		Writers:      make(map[string]bool),
+		Fail:         make(map[string]bool),
+		next:         1000,
+	}
+}
+
+// RepoKey returns the key of a repository.
+func RepoKey(org, repo string) string {
+	return org + "/" + repo
+}
+
+// Key returns the key of a pull request, an issue or another item of a repository.
+func Key(org, repo, number string) string {
+	return org + "/" + repo + "#" + number
+}
+
+// AddPullRequest adds a pull request with its commits, the latest first.
+func (c *Client) AddPullRequest(org, repo string, pr client.PullRequest, commits ...client.PRCommit) {
+	c.Lock()
+	defer c.Unlock()
+	key := Key(org, repo, utils.GetString(pr.Number))
+	c.PullRequests[key] = pr
+	c.Commits[key] = commits
+}
+
+// AddComment adds a comment of user as the latest one of a pull request.
+func (c *Client) AddComment(org, repo, number, user, body string) {
+	c.Lock()
+	defer c.Unlock()
+	key := Key(org, repo, number)
+	c.Comments[key] = append([]client.PRComment{{Commenter: user, Body: body}}, c.Comments[key]...)
+}
+
+// GetPullRequest gets a pull request in a specified organization and repository
+func (c *Client) GetPullRequest(org, repo, number string) (result client.PullRequest, success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["GetPullRequest"] {
+		return
+	}
+	result, success = c.PullRequests[Key(org, repo, number)]
+	return
+}
+
+// GetAuthenticatedUser gets the user of the client
+func (c *Client) GetAuthenticatedUser() (login string, success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["GetAuthenticatedUser"] {
+		return
+	}
+	return c.User, true
+}
+
+// ListOpenPullRequests lists the pull requests of a repository whose state is
+// unset or open, sorted by number
+func (c *Client) ListOpenPullRequests(org, repo string) (result []client.PullRequest, success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["ListOpenPullRequests"] {
+		return
+	}
+	prefix := Key(org, repo, "")
+	for key, pr := range c.PullRequests {
+		if strings.HasPrefix(key, prefix) && isOpen(pr) {
+			result = append(result, pr)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, _ := strconv.Atoi(utils.GetString(result[i].Number))
+		b, _ := strconv.Atoi(utils.GetString(result[j].Number))
+		return a < b
+	})
+	return result, true
+}
+
+// SetPullRequestState sets the state of a pull request, like "merged" or "closed"
+func (c *Client) SetPullRequestState(org, repo, number, state string) {
+	c.Lock()
+	defer c.Unlock()
+	key := Key(org, repo, number)
+	pr := c.PullRequests[key]
+	pr.State = &state
+	c.PullRequests[key] = pr
+}
+
+func isOpen(pr client.PullRequest) bool {
+	switch utils.GetString(pr.State) {
+	case "", "open", "opened":
+		return true
+	}
+	return false
+}
+
+// GetPathContent gets the base64 encoded content of a file on branch
+func (c *Client) GetPathContent(org, repo, path, branch string) (result client.RepoContent, success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["GetPathContent"] {
+		return
+	}
+	content, ok := c.Contents[Key(org, repo, branch+":"+path)]
+	if !ok {
+		return
+	}
+	return client.RepoContent{Content: &content}, true
+}
+
+// GetRepoAllBranch lists all branches of a repository
+func (c *Client) GetRepoAllBranch(org, repo string) (result []client.Branch, success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["GetRepoAllBranch"] {
+		return
+	}
+	for _, b := range c.Branches[RepoKey(org, repo)] {
+		result = append(result, client.Branch{Name: b})
+	}
+	return result, true
+}
+
+// ListProtectedBranches lists the branches of a repository marked in Protected
+func (c *Client) ListProtectedBranches(org, repo string) (result []string, success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["ListProtectedBranches"] {
+		return
+	}
+	for _, b := range c.Branches[RepoKey(org, repo)] {
+		if c.Protected[Key(org, repo, b)] {
+			result = append(result, b)
+		}
+	}
+	return result, true
+}
+
+// ListOrgRepos lists the repositories of an organization holding branches,
+// sorted by name
+func (c *Client) ListOrgRepos(org string) (result []string, success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["ListOrgRepos"] {
+		return
+	}
+	for key := range c.Branches {
+		if o, repo, ok := strings.Cut(key, "/"); ok && o == org {
+			result = append(result, repo)
+		}
+	}
+	sort.Strings(result)
+	return result, true
+}
+
+// ListPullRequestComments lists the comments of a pull request, the latest first
+func (c *Client) ListPullRequestComments(org, repo, number string) (result []client.PRComment, success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["ListPullRequestComments"] {
+		return
+	}
+	return append(result, c.Comments[Key(org, repo, number)]...), true
+}
+
+// ListPullRequestCommentsWithID lists the comments of a pull request with their
+// ids, the latest first. A comment's id is its position from the first one, from 1 on.
+func (c *Client) ListPullRequestCommentsWithID(org, repo, number string) (result []platform.Comment, success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["ListPullRequestComments"] {
+		return
+	}
+	comments := c.Comments[Key(org, repo, number)]
+	for i, cm := range comments {
+		result = append(result, platform.Comment{ID: strconv.Itoa(len(comments) - i), Commenter: cm.Commenter, Body: cm.Body})
+	}
+	return result, true
+}
+
+// GetPullRequestAuthor gets the user who opened a pull request
+func (c *Client) GetPullRequestAuthor(org, repo, number string) (login string, success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["GetPullRequestAuthor"] {
+		return
+	}
+	login, success = c.Authors[Key(org, repo, number)]
+	return
+}
+
+// RequestPRReviewers adds the users to the reviewers of a pull request
+func (c *Client) RequestPRReviewers(org, repo, number string, reviewers []string) (success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["RequestPRReviewers"] {
+		return false
+	}
+	key := Key(org, repo, number)
+	for _, r := range reviewers {
+		if !contains(c.Reviewers[key], r) {
+			c.Reviewers[key] = append(c.Reviewers[key], r)
+		}
+	}
+	return true
+}
+
+// GetPullRequestReviewers gets the reviewers of a pull request
+func (c *Client) GetPullRequestReviewers(org, repo, number string) (result []string, success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["GetPullRequestReviewers"] {
+		return
+	}
+	return append(result, c.Reviewers[Key(org, repo, number)]...), true
+}
+
+// AssignPR adds the users to the assignees of a pull request
+func (c *Client) AssignPR(org, repo, number string, assignees []string) (success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["AssignPR"] {
+		return false
+	}
+	key := Key(org, repo, number)
+	for _, a := range assignees {
+		if !contains(c.Assignees[key], a) {
+			c.Assignees[key] = append(c.Assignees[key], a)
+		}
+	}
+	return true
+}
+
+// EditPRComment replaces the body of the comment commentID of a pull request
+func (c *Client) EditPRComment(org, repo, number, commentID, comment string) (success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["EditPRComment"] {
+		return false
+	}
+	key := Key(org, repo, number)
+	comments := c.Comments[key]
+	id, err := strconv.Atoi(commentID)
+	if err != nil || id < 1 || id > len(comments) {
+		return false
+	}
+	comments[len(comments)-id].Body = comment
+	c.EditedComments = append(c.EditedComments, Comment{Org: org, Repo: repo, Number: number, Body: comment})
+	return true
+}
+
+// GetPRLinkedIssue gets the issues linked to a pull request
+func (c *Client) GetPRLinkedIssue(org, repo, number string) (result []client.Issue, success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["GetPRLinkedIssue"] {
+		return
+	}
+	return append(result, c.Issues[Key(org, repo, number)]...), true
+}
+
+// CreatePRComment comments on a pull request
+func (c *Client) CreatePRComment(org, repo, number, comment string) (success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["CreatePRComment"] {
+		return false
+	}
+	key := Key(org, repo, number)
+	c.Comments[key] = append([]client.PRComment{{Commenter: c.User, Body: comment}}, c.Comments[key]...)
+	c.PRComments = append(c.PRComments, Comment{Org: org, Repo: repo, Number: number, Body: comment})
+	return true
+}
+
+// CreateIssueComment comments on an issue
+func (c *Client) CreateIssueComment(org, repo, number, comment string) (success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["CreateIssueComment"] {
+		return false
+	}
+	key := Key(org, repo, number)
+	c.Comments[key] = append([]client.PRComment{{Commenter: c.User, Body: comment}}, c.Comments[key]...)
+	c.IssueComments = append(c.IssueComments, Comment{Org: org, Repo: repo, Number: number, Body: comment})
+	return true
+}
+
+// ListIssueCommentsWithID lists the comments of an issue with their ids, the
+// latest first, numbered like those of pull requests.
+func (c *Client) ListIssueCommentsWithID(org, repo, number string) (result []platform.Comment, success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["ListIssueComments"] {
+		return
+	}
+	comments := c.Comments[Key(org, repo, number)]
+	for i, cm := range comments {
+		result = append(result, platform.Comment{ID: strconv.Itoa(len(comments) - i), Commenter: cm.Commenter, Body: cm.Body})
+	}
+	return result, true
+}
+
+// EditIssueComment replaces the body of the comment commentID of an issue
+func (c *Client) EditIssueComment(org, repo, number, commentID, comment string) (success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["EditIssueComment"] {
+		return false
+	}
+	comments := c.Comments[Key(org, repo, number)]
+	id, err := strconv.Atoi(commentID)
+	if err != nil || id < 1 || id > len(comments) {
+		return false
+	}
+	comments[len(comments)-id].Body = comment
+	c.EditedComments = append(c.EditedComments, Comment{Org: org, Repo: repo, Number: number, Body: comment})
+	return true
+}
+
+// AddIssueLabels adds labels to an issue
+func (c *Client) AddIssueLabels(org, repo, number string, labels []string) (success bool) {
+	return c.addLabels("AddIssueLabels", Key(org, repo, number), labels)
+}
+
+// RemoveIssueLabels removes labels from an issue
+func (c *Client) RemoveIssueLabels(org, repo, number string, labels []string) (success bool) {
+	return c.removeLabels("RemoveIssueLabels", Key(org, repo, number), labels)
+}
+
+// AddPRLabels adds labels to a pull request
+func (c *Client) AddPRLabels(org, repo, number string, labels []string) (success bool) {
+	return c.addLabels("AddPRLabels", Key(org, repo, number), labels)
+}
+
+// RemovePRLabels removes labels from a pull request
+func (c *Client) RemovePRLabels(org, repo, number string, labels []string) (success bool) {
+	return c.removeLabels("RemovePRLabels", Key(org, repo, number), labels)
+}
+
+func (c *Client) addLabels(method, key string, labels []string) bool {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail[method] {
+		return false
+	}
+	for _, l := range labels {
+		if !contains(c.Labels[key], l) {
+			c.Labels[key] = append(c.Labels[key], l)
+		}
+	}
+	return true
+}
+
+func (c *Client) removeLabels(method, key string, labels []string) bool {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail[method] {
+		return false
+	}
+	var kept []string
+	for _, l := range c.Labels[key] {
+		if !contains(labels, l) {
+			kept = append(kept, l)
+		}
+	}
+	c.Labels[key] = kept
+	return true
+}
+
+// GetPullRequestCommits lists the commits of a pull request, the latest first
+func (c *Client) GetPullRequestCommits(org, repo, number string) (result []client.PRCommit, success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["GetPullRequestCommits"] {
+		return
+	}
+	return append(result, c.Commits[Key(org, repo, number)]...), true
+}
+
+// GetPullRequestLabels lists the labels of a pull request
+func (c *Client) GetPullRequestLabels(org, repo, number string) (result []string, success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["GetPullRequestLabels"] {
+		return
+	}
+	return append(result, c.Labels[Key(org, repo, number)]...), true
+}
+
+// GetIssueLabels lists the labels of an issue, looked up by Key(org, "", issueID)
+func (c *Client) GetIssueLabels(org, issueID string) (result []string, success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["GetIssueLabels"] {
+		return
+	}
+	return append(result, c.Labels[Key(org, "", issueID)]...), true
+}
+
+// GetRepoIssueLabels lists the labels of a repository
+func (c *Client) GetRepoIssueLabels(org, repo string) (result []string, success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["GetRepoIssueLabels"] {
+		return
+	}
+	return append(result, c.RepoLabels[RepoKey(org, repo)]...), true
+}
+
+// CheckPermissionWithBranch checks if the user is one of the writers of the repository
+func (c *Client) CheckPermissionWithBranch(org, repo, username, branch string) (pass, success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["CheckPermissionWithBranch"] {
+		return false, false
+	}
+	return c.Writers[Key(org, repo, username)], true
+}
+
+// GetPullRequestChanges lists the files changed by a pull request
+func (c *Client) GetPullRequestChanges(org, repo, number string) (result []client.CommitFile, success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["GetPullRequestChanges"] {
+		return
+	}
+	return append(result, c.Changes[Key(org, repo, number)]...), true
+}
+
+// CreatePR creates a pull request, numbered from 1000 on
+func (c *Client) CreatePR(org, repo string, prContent client.PullRequest) (number string, success bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Fail["CreatePR"] {
+		return "", false
+	}
+	number = strconv.Itoa(c.next)
+	c.next++
+	prContent.Number = &number
+	url := "https://fake/" + path.Join(org, repo, "pulls", number)
+	prContent.URL = &url
+	c.PullRequests[Key(org, repo, number)] = prContent
+	c.Authors[Key(org, repo, number)] = c.User
+	c.CreatedPRs = append(c.CreatedPRs, prContent)
+	return number, true
+}
+
+// CreateRepoBranch creates branch from createFrom, it fails if createFrom is
+// not a branch of the repository
+func (c *Client) CreateRepoBranch(org, repo, createFrom, branch string) (success bool) {
+	c.Lock()
+	defer c.Unlock()
+	key := RepoKey(org, repo)
+	if c.Fail["CreateRepoBranch"] || !contains(c.Branches[key], createFrom) {
+		return false
+	}
+	if !contains(c.Branches[key], branch) {
+		c.Branches[key] = append(c.Branches[key], branch)
+	}
+	c.CreatedBranches = append(c.CreatedBranches, Key(org, repo, branch))
+	return true
+}
+
+// CheckIfPRCreateEvent checks if the event opens a pull request
+func (c *Client) CheckIfPRCreateEvent(evt *client.GenericEvent) (yes bool) {
+	return isAction(evt, "open", "opened")
+}
+
+// CheckIfPRReopenEvent checks if the event reopens a pull request
+func (c *Client) CheckIfPRReopenEvent(evt *client.GenericEvent) (yes bool) {
+	return isAction(evt, "reopen", "reopened")
+}
+
+// CheckIfPRMergeEvent checks if the event merges a pull request
+func (c *Client) CheckIfPRMergeEvent(evt *client.GenericEvent) (yes bool) {
+	return isAction(evt, "merge", "merged") ||
+		isAction(evt, "close", "closed") && utils.GetString(evt.State) == "merged"
+}
+
+// CheckIfPRCloseEvent checks if the event closes a pull request without merging it
+func (c *Client) CheckIfPRCloseEvent(evt *client.GenericEvent) (yes bool) {
+	return isAction(evt, "close", "closed") && utils.GetString(evt.State) != "merged"
+}
+
+// CheckIfPRSourceCodeUpdateEvent checks if the event pushes to the head of a pull request
+func (c *Client) CheckIfPRSourceCodeUpdateEvent(evt *client.GenericEvent) (yes bool) {
+	return isAction(evt, "update", "synchronize")
+}
+
+// isAction checks the action of an event against the names used by the platforms.
+func isAction(evt *client.GenericEvent, actions ...string) bool {
+	return contains(actions, utils.GetString(evt.Action))
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
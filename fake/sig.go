@@ -0,0 +1,17 @@
+package fake
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"sync-bot/sig"
+)
+
+// NewSigServer starts a local stand-in of the SIG info API answering the SIGs
+// of sigs by RepoKey, and none for the other repositories. Close it when done.
+func NewSigServer(sigs map[string][]sig.SIG) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(sig.Answer{Data: sigs[r.URL.Query().Get("repo")]})
+	}))
+}
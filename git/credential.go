@@ -0,0 +1,116 @@
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"sync-bot/util"
+)
+
+// environment variables read by the askpass script
+const (
+	usernameEnv = "SYNC_BOT_GIT_USERNAME"
+	passwordEnv = "SYNC_BOT_GIT_PASSWORD"
+)
+
+// askPassScript answers the username and password prompts of git from the
+// environment, so credentials are never written into remote URLs.
+const askPassScript = `#!/bin/sh
+case "$1" in
+Username*) printf '%s\n' "$` + usernameEnv + `" ;;
+*) printf '%s\n' "$` + passwordEnv + `" ;;
+esac
+`
+
+// credential is a user with the generator of its token.
+type credential struct {
+	user           string
+	tokenGenerator func() []byte
+}
+
+// writeAskPass writes the askpass script into a private temporary file and
+// returns its path.
+func writeAskPass() (string, error) {
+	f, err := os.CreateTemp("", "sync-bot-askpass-*")
+	if err != nil {
+		return "", fmt.Errorf("create askpass script failed: %v", err)
+	}
+	defer f.Close()
+	if _, err = f.WriteString(askPassScript); err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("write askpass script failed: %v", err)
+	}
+	if err = f.Chmod(0700); err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("chmod askpass script failed: %v", err)
+	}
+	return f.Name(), nil
+}
+
+// gitEnv returns the environment of git commands talking to the repos of org.
+// The credentials are read on every call, so a rotated token is used by the
+// next command without re-cloning.
+func (c *Client) gitEnv(org string) []string {
+	user, pass := c.getCredentials(org)
+	return append(os.Environ(),
+		"GIT_ASKPASS="+c.askPass,
+		"GIT_TERMINAL_PROMPT=0",
+		// ignore credential helpers configured on the host, they could persist the token
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=credential.helper",
+		"GIT_CONFIG_VALUE_0=",
+		usernameEnv+"="+user,
+		passwordEnv+"="+pass,
+	)
+}
+
+// Clean removes the askpass script. The client is unusable after calling.
+func (c *Client) Clean() error {
+	return os.RemoveAll(c.askPass)
+}
+
+// ScrubRemotes removes the credentials embedded in the remote URLs of every
+// cached clone, which used to be persisted in .git/config.
+func (c *Client) ScrubRemotes() error {
+	dirs, err := filepath.Glob(filepath.Join(c.dir, "*", "*", ".git"))
+	if err != nil {
+		return err
+	}
+	for _, gitDir := range dirs {
+		r := &Repo{dir: filepath.Dir(gitDir), git: c.git, client: c}
+		if err = r.scrubRemotes(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scrubRemotes rewrites the remote URLs of the repo which contain credentials.
+func (r *Repo) scrubRemotes() error {
+	co := r.gitCommand("config", "--local", "--get-regexp", `^remote\..*\.url$`)
+	out, err := co.CombinedOutput()
+	if err != nil {
+		// exit status 1 means no remote is configured
+		if co.ProcessState != nil && co.ProcessState.ExitCode() == 1 {
+			return nil
+		}
+		return fmt.Errorf("list remotes of %s failed, output: %q, error: %v", r.dir, string(out), err)
+	}
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || !util.MatchSecretURL(fields[1]) {
+			continue
+		}
+		logrus.Infof("Scrub credentials from %s of %s.", fields[0], r.dir)
+		if err = r.Config(fields[0], util.RemoveSecret(fields[1])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
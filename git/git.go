@@ -2,11 +2,14 @@
 package git
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -34,21 +37,22 @@ const (
 )
 
 const repoPath = "repos"
-const gitcode = "gitcode.com"
 
 var largeRepos = map[string]bool{
 	"LiYanghang00/kernel": true,
 }
 
 // Client can clone repos. It keeps a local cache, so successive clones of the
-// same repo should be quick. Create with NewClient. Be sure to clean it up.
+// same repo should be quick. Create with NewClientWithHost. Be sure to clean it up.
 type Client struct {
 	credLock sync.RWMutex
 	// user is used when pushing or pulling code if specified.
 	user string
 
 	// needed to generate the token.
-	tokenGenerator []byte
+	tokenGenerator func() []byte
+	// orgCredentials overrides the credentials for the repos of an organization.
+	orgCredentials map[string]credential
 
 	// dir is the location of the git cache.
 	dir string
@@ -58,6 +62,12 @@ type Client struct {
 	base string
 	// host is the git host.
 	host string
+	// askPass is the path of the script answering git credential prompts.
+	askPass string
+	// prRef is the format of the ref holding the head of a pull request.
+	prRef string
+	// committerName and committerEmail are the identity of the commits made, if set.
+	committerName, committerEmail string
 
 	// rlm protects repoLocks which protect individual repos
 	// Lock with Client.lockRepo, unlock with Client.unlockRepo.
@@ -65,24 +75,37 @@ type Client struct {
 	repoLocks map[string]*sync.Mutex
 }
 
-// NewClient returns a client
-func NewClient() (*Client, error) {
-	return NewClientWithHost(gitcode)
-}
-
 // NewClientWithHost creates a client with specified host.
 func NewClientWithHost(host string) (*Client, error) {
+	return NewClientWithBase(fmt.Sprintf("https://%s", host), repoPath)
+}
+
+// NewClientWithBase creates a client cloning from and pushing to the
+// repositories under base, like "https://gitcode.com" or "file:///srv/git",
+// and keeping its cache in dir.
+func NewClientWithBase(base, dir string) (*Client, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
 	g, err := exec.LookPath("git")
 	if err != nil {
 		return nil, err
 	}
+	askPass, err := writeAskPass()
+	if err != nil {
+		return nil, err
+	}
 
 	return &Client{
 		tokenGenerator: nil,
-		dir:            repoPath,
+		dir:            dir,
 		git:            g,
-		base:           fmt.Sprintf("https://%s", host),
-		host:           host,
+		base:           strings.TrimSuffix(base, "/"),
+		host:           u.Host,
+		askPass:        askPass,
+		prRef:          "refs/merge-requests/%d/head",
+		orgCredentials: make(map[string]credential),
 		repoLocks:      make(map[string]*sync.Mutex),
 	}, nil
 }
@@ -90,16 +113,12 @@ func NewClientWithHost(host string) (*Client, error) {
 // PrewarmLargeRepos performs a full clone for known large repositories at startup if missing
 func (c *Client) PrewarmLargeRepos() error {
 	for fullName := range largeRepos {
+		owner := strings.Split(fullName, "/")[0]
 		dir := filepath.Join(c.dir, fullName)
 		if _, err := os.Stat(dir); os.IsNotExist(err) {
-			base := c.base
-			user, pass := c.getCredentials()
-			if user != "" && pass != "" {
-				base = fmt.Sprintf("https://%s:%s@%s", user, pass, c.host)
-			}
-			remote := fmt.Sprintf("%s/%s.git", base, fullName)
+			remote := fmt.Sprintf("%s/%s.git", c.base, fullName)
 			logrus.WithFields(logrus.Fields{
-				"remote": util.DeSecret(remote),
+				"remote": remote,
 				"dir":    dir,
 			}).Infof("Prewarm full clone (init+fetch) for large repo %s", fullName)
 
@@ -107,52 +126,52 @@ func (c *Client) PrewarmLargeRepos() error {
 				return fmt.Errorf("mkdir for prewarm failed: %v", err2)
 			}
 
-			if _, errInit := retryCmd(dir, c.git, "init"); errInit != nil {
+			if _, errInit := retryCmd(dir, c.gitEnv(owner), c.git, "init"); errInit != nil {
 				return fmt.Errorf("git init failed: %v", errInit)
 			}
 
-			_, _ = retryCmd(dir, c.git, "config", "http.postBuffer", "524288000")
-			_, _ = retryCmd(dir, c.git, "config", "core.compression", "0")
-			_, _ = retryCmd(dir, c.git, "config", "gc.auto", "0")
+			_, _ = retryCmd(dir, c.gitEnv(owner), c.git, "config", "http.postBuffer", "524288000")
+			_, _ = retryCmd(dir, c.gitEnv(owner), c.git, "config", "core.compression", "0")
+			_, _ = retryCmd(dir, c.gitEnv(owner), c.git, "config", "gc.auto", "0")
 
-			if _, errRemote := retryCmd(dir, c.git, "remote", "add", "origin", remote); errRemote != nil {
+			if _, errRemote := retryCmd(dir, c.gitEnv(owner), c.git, "remote", "add", "origin", remote); errRemote != nil {
 				logrus.Debugf("remote add failed (might exist): %v", errRemote)
 			}
 
-			r := &Repo{dir: dir, git: c.git, host: c.host, base: c.base, owner: strings.Split(fullName, "/")[0], repo: strings.Split(fullName, "/")[1], user: user, pass: pass}
+			r := &Repo{dir: dir, git: c.git, host: c.host, base: c.base, owner: owner, repo: strings.Split(fullName, "/")[1], client: c}
 			if errFetch := r.FetchRemoteRobust("origin"); errFetch != nil {
 				logrus.WithFields(logrus.Fields{
-					"remote": util.DeSecret(remote),
+					"remote": remote,
 					"dir":    dir,
 				}).Errorf("Prewarm full fetch failed: %v", errFetch)
 				// fallback: if LiYanghang00/kernel fails, try openFuyao-test/kernel as mirror to populate objects
 				if fullName == "LiYanghang00/kernel" {
 					fallbackFull := "openEuler/kernel"
-					fallbackRemote := fmt.Sprintf("%s/%s.git", base, fallbackFull)
+					fallbackRemote := fmt.Sprintf("%s/%s.git", c.base, fallbackFull)
 					logrus.WithFields(logrus.Fields{
-						"mirror": util.DeSecret(fallbackRemote),
+						"mirror": fallbackRemote,
 						"dir":    dir,
 					}).Warnf("Attempt mirror fallback for %s", fullName)
-					_, _ = retryCmd(dir, c.git, "remote", "add", "mirror", fallbackRemote)
+					_, _ = retryCmd(dir, c.gitEnv(owner), c.git, "remote", "add", "mirror", fallbackRemote)
 					refspec := "+refs/heads/*:refs/remotes/mirror/*"
 					// fetch heads from mirror to populate objects
 					if ferr := r.fetchRefspecRobust("mirror", refspec); ferr != nil {
 						logrus.WithFields(logrus.Fields{
-							"mirror":  util.DeSecret(fallbackRemote),
+							"mirror":  fallbackRemote,
 							"dir":     dir,
 							"refspec": refspec,
 						}).Errorf("Mirror fetch failed: %v", ferr)
 						return fmt.Errorf("prewarm full fetch %s failed: %v", fullName, errFetch)
 					}
 					// cleanup mirror remote, keep origin
-					_, _ = retryCmd(dir, c.git, "remote", "remove", "mirror")
+					_, _ = retryCmd(dir, c.gitEnv(owner), c.git, "remote", "remove", "mirror")
 				} else {
 					return fmt.Errorf("prewarm full fetch %s failed: %v", fullName, errFetch)
 				}
 			}
 
-			_, _ = retryCmd(dir, c.git, "remote", "set-head", "origin", "--auto")
-			if b, errCo := retryCmd(dir, c.git, "checkout", "-f", "origin/HEAD"); errCo != nil {
+			_, _ = retryCmd(dir, c.gitEnv(owner), c.git, "remote", "set-head", "origin", "--auto")
+			if b, errCo := retryCmd(dir, c.gitEnv(owner), c.git, "checkout", "-f", "origin/HEAD"); errCo != nil {
 				logrus.Warnf("Prewarm checkout failed (non-fatal): %v, output: %s", errCo, string(b))
 			}
 
@@ -164,18 +183,54 @@ func (c *Client) PrewarmLargeRepos() error {
 }
 
 // SetCredentials sets credentials in the client to be used for pushing to
-// or pulling from remote repositories.
-func (c *Client) SetCredentials(user string, tokenGenerator []byte) {
+// or pulling from remote repositories. The token is generated for every git
+// command, so a rotated secret takes effect without restarting.
+func (c *Client) SetCredentials(user string, tokenGenerator func() []byte) {
 	c.credLock.Lock()
 	defer c.credLock.Unlock()
 	c.user = user
 	c.tokenGenerator = tokenGenerator
 }
 
-func (c *Client) getCredentials() (string, string) {
+// SetOrgCredentials sets credentials used instead of the default ones for the
+// repositories of org.
+func (c *Client) SetOrgCredentials(org, user string, tokenGenerator func() []byte) {
+	c.credLock.Lock()
+	defer c.credLock.Unlock()
+	c.orgCredentials[org] = credential{user: user, tokenGenerator: tokenGenerator}
+}
+
+func (c *Client) getCredentials(org string) (string, string) {
+	c.credLock.RLock()
+	defer c.credLock.RUnlock()
+	cred, ok := c.orgCredentials[org]
+	if !ok {
+		cred = credential{user: c.user, tokenGenerator: c.tokenGenerator}
+	}
+	if cred.tokenGenerator == nil {
+		return cred.user, ""
+	}
+	return cred.user, string(cred.tokenGenerator())
+}
+
+// SetPullRequestRef sets the format of the ref holding the head of a pull
+// request on the git host, it takes the pull request number.
+func (c *Client) SetPullRequestRef(format string) {
+	c.prRef = format
+}
+
+// SetCommitter sets the identity of the committer of the commits made, like
+// cherry-picks. The authors of the commits picked are kept.
+func (c *Client) SetCommitter(name, email string) {
+	c.credLock.Lock()
+	defer c.credLock.Unlock()
+	c.committerName, c.committerEmail = name, email
+}
+
+func (c *Client) getCommitter() (name, email string) {
 	c.credLock.RLock()
 	defer c.credLock.RUnlock()
-	return c.user, string(c.tokenGenerator)
+	return c.committerName, c.committerEmail
 }
 
 func (c *Client) lockRepo(repo string) {
@@ -199,11 +254,6 @@ func (c *Client) Clone(owner, repo string) (*Repo, error) {
 	fullName := owner + "/" + repo
 	c.lockRepo(fullName)
 	defer c.unlockRepo(fullName)
-	base := c.base
-	user, pass := c.getCredentials()
-	if user != "" && pass != "" {
-		base = fmt.Sprintf("https://%s:%s@%s", user, pass, c.host)
-	}
 	dir := filepath.Join(c.dir, fullName)
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
 		logrus.Infof("Cloning %s.", fullName)
@@ -216,8 +266,8 @@ func (c *Client) Clone(owner, repo string) (*Repo, error) {
 			fullName = "Liyanghang00" + "/" + repo
 		}
 
-		remote := fmt.Sprintf("%s/%s.git", base, fullName)
-		if b, err2 := retryCmd("", c.git, "clone", remote, dir); err2 != nil {
+		remote := fmt.Sprintf("%s/%s.git", c.base, fullName)
+		if b, err2 := retryCmd("", c.gitEnv(owner), c.git, "clone", remote, dir); err2 != nil {
 			return nil, fmt.Errorf("git dir clone error: %v. output: %s", err2, string(b))
 		}
 	} else if err != nil {
@@ -226,33 +276,31 @@ func (c *Client) Clone(owner, repo string) (*Repo, error) {
 
 		if owner == "openEuler" && repo == "kernel" {
 			return &Repo{
-				dir:   dir,
-				git:   c.git,
-				host:  c.host,
-				base:  base,
-				owner: owner,
-				repo:  repo,
-				user:  user,
-				pass:  pass,
+				dir:    dir,
+				git:    c.git,
+				host:   c.host,
+				base:   c.base,
+				owner:  owner,
+				repo:   repo,
+				client: c,
 			}, nil
 		}
 
 		// Cache hit. Do a git fetch to keep updated.
 		logrus.Infof("Fetching %s.", fullName)
-		if b, err := retryCmd(dir, c.git, "fetch"); err != nil {
+		if b, err := retryCmd(dir, c.gitEnv(owner), c.git, "fetch"); err != nil {
 			return nil, fmt.Errorf("git fetch error: %v. output: %s", err, string(b))
 		}
 	}
 
 	return &Repo{
-		dir:   dir,
-		git:   c.git,
-		host:  c.host,
-		base:  base,
-		owner: owner,
-		repo:  repo,
-		user:  user,
-		pass:  pass,
+		dir:    dir,
+		git:    c.git,
+		host:   c.host,
+		base:   c.base,
+		owner:  owner,
+		repo:   repo,
+		client: c,
 	}, nil
 }
 
@@ -270,10 +318,8 @@ type Repo struct {
 	owner string
 	// repo is the repository name: "repo" in "owner/repo".
 	repo string
-	// user is used for pushing to the remote repo.
-	user string
-	// pass is used for pushing to the remote repo.
-	pass string
+	// client provides the credentials for talking to the remote repo.
+	client *Client
 }
 
 // Directory exposes the location of the git repo
@@ -289,6 +335,7 @@ func (r *Repo) Destroy() error {
 func (r *Repo) gitCommand(arg ...string) *exec.Cmd {
 	cmd := exec.Command(r.git, arg...)
 	cmd.Dir = r.dir
+	cmd.Env = r.client.gitEnv(r.owner)
 
 	// hide secret in command arguments
 	for i, a := range arg {
@@ -449,6 +496,23 @@ func (r *Repo) RemoteBranchExists(branch string) bool {
 	return co.Run() == nil
 }
 
+// RemoteBranches lists the branches of origin matching the glob pattern, as
+// they are on the remote and not as last fetched.
+func (r *Repo) RemoteBranches(pattern string) ([]string, error) {
+	co := r.gitCommand("ls-remote", "--heads", "origin", "refs/heads/"+pattern)
+	out, err := co.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("list remote branches failed, output: %q, error: %v", string(out), err)
+	}
+	var branches []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if _, ref, ok := strings.Cut(line, "\t"); ok {
+			branches = append(branches, strings.TrimPrefix(ref, "refs/heads/"))
+		}
+	}
+	return branches, nil
+}
+
 // CheckoutNewBranch creates a new branch and checks it out.
 func (r *Repo) CheckoutNewBranch(branch string, force bool) error {
 	if force {
@@ -477,6 +541,454 @@ func (r *Repo) CherryPick(first, last string, strategyOption StrategyOption) err
 	return nil
 }
 
+// CherryPickSquash cherry-picks the commits from first to last into the index
+// and commits them once, as the author of the first one, with message followed
+// by a "(cherry picked from commit <sha>)" line for every commit picked. Merge
+// commits are left out, the commits they merge are picked instead, so first
+// and last may both be a merge commit to squash the commits it merges.
+func (r *Repo) CherryPickSquash(first, last, message string) error {
+	if err := r.ensureIdentity(); err != nil {
+		return fmt.Errorf("git identity setup failed before cherry-pick: %v", err)
+	}
+	rangeSpec := fmt.Sprintf("%s^..%s", first, last)
+	out, err := r.gitCommand("rev-list", "--reverse", "--no-merges", rangeSpec).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("list commits failed, output: %q, error: %v", string(out), err)
+	}
+	shas := strings.Fields(string(out))
+	if len(shas) == 0 {
+		return fmt.Errorf("no commits from %s to %s", first, last)
+	}
+	author, err := r.gitCommand("log", "-1", "--format=%an <%ae>", shas[0]).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("get author failed, output: %q, error: %v", string(author), err)
+	}
+
+	logrus.Infof("Cherry Pick from %s to %s into one commit.", first, last)
+	out, err = r.gitCommand(append([]string{"cherry-pick", "--no-commit"}, shas...)...).CombinedOutput()
+	if err != nil {
+		logrus.Errorf("Cherry pick failed with error: %v and output: %q", err, string(out))
+		return fmt.Errorf("cherry pick failed, output: %q, error: %v", string(out), err)
+	}
+	message = strings.TrimRight(message, "\n") + "\n"
+	for _, sha := range shas {
+		message += fmt.Sprintf("\n(cherry picked from commit %s)", sha)
+	}
+	co := r.gitCommand("commit", "--no-verify", "--author", strings.TrimSpace(string(author)), "-m", message)
+	out, err = co.CombinedOutput()
+	if err != nil {
+		logrus.Errorf("Commit failed with error: %v and output: %q", err, string(out))
+		return fmt.Errorf("commit failed, output: %q, error: %v", string(out), err)
+	}
+	return nil
+}
+
+// AddTrailer appends the trailer, like "Synced-from: org/repo!1", to the
+// messages of the commits of the current branch after since. The authors of
+// the commits are kept.
+func (r *Repo) AddTrailer(since, trailer string) error {
+	if err := r.ensureIdentity(); err != nil {
+		return fmt.Errorf("git identity setup failed before adding trailer: %v", err)
+	}
+	amend := "git commit --amend --no-edit --no-verify --trailer " + shellQuote(trailer)
+	co := r.gitCommand("rebase", "--exec", amend, since)
+	out, err := co.CombinedOutput()
+	if err != nil {
+		logrus.Errorf("Add trailer failed with error: %v and output: %q", err, string(out))
+		_ = r.gitCommand("rebase", "--abort").Run()
+		return fmt.Errorf("add trailer failed, output: %q, error: %v", string(out), err)
+	}
+	return nil
+}
+
+// shellQuote quotes s as a single word of sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// CommitTime returns the committer date of the commit commitLike points to.
+func (r *Repo) CommitTime(commitLike string) (time.Time, error) {
+	co := r.gitCommand("log", "-1", "--format=%ct", commitLike)
+	out, err := co.CombinedOutput()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("get commit time failed, output: %q, error: %v", string(out), err)
+	}
+	sec, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse commit time %q failed: %v", string(out), err)
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// CherryPickCommits cherry-picks the commits one by one in the order given.
+func (r *Repo) CherryPickCommits(shas []string) error {
+	if err := r.ensureIdentity(); err != nil {
+		return fmt.Errorf("git identity setup failed before cherry-pick: %v", err)
+	}
+	logrus.Infof("Cherry Pick %d commits.", len(shas))
+	co := r.gitCommand(append([]string{"cherry-pick", "-x"}, shas...)...)
+	out, err := co.CombinedOutput()
+	if err != nil {
+		logrus.Errorf("Cherry pick failed with error: %v and output: %q", err, string(out))
+		return fmt.Errorf("cherry pick failed, output: %q, error: %v", string(out), err)
+	}
+	return nil
+}
+
+// CherryPickPaths cherry-picks the commits one by one in the order given,
+// keeping only the changes to the files matching one of the glob patterns of
+// paths, like "*.patch". The other files are checked out as they are on HEAD,
+// so conflicts in them don't stop the pick. Commits left without changes are
+// skipped. The authors and messages of the commits are kept, followed by a
+// "(cherry picked from commit <sha>)" line.
+func (r *Repo) CherryPickPaths(shas, paths []string) error {
+	if len(paths) == 0 {
+		return r.CherryPickCommits(shas)
+	}
+	if err := r.ensureIdentity(); err != nil {
+		return fmt.Errorf("git identity setup failed before cherry-pick: %v", err)
+	}
+	others := []string{"."}
+	for _, p := range paths {
+		others = append(others, ":(exclude,glob)"+p)
+	}
+	logrus.Infof("Cherry Pick %d commits restricted to %v.", len(shas), paths)
+	for _, sha := range shas {
+		picked, pickErr := r.gitCommand("cherry-pick", "--no-commit", sha).CombinedOutput()
+		if pickErr != nil {
+			// only conflicts may be solved by leaving the other files out
+			if files, err := r.ConflictFiles(); err != nil || len(files) == 0 {
+				logrus.Errorf("Cherry pick failed with error: %v and output: %q", pickErr, string(picked))
+				return fmt.Errorf("cherry pick failed, output: %q, error: %v", string(picked), pickErr)
+			}
+		}
+		// the index is reset first, restore refuses the unmerged files missing on HEAD
+		for _, args := range [][]string{
+			{"reset", "-q", "--"},
+			{"restore", "--source=HEAD", "--staged", "--worktree", "--"},
+			{"clean", "-fdq", "--"},
+		} {
+			if out, err := r.gitCommand(append(args, others...)...).CombinedOutput(); err != nil {
+				return fmt.Errorf("%s failed, output: %q, error: %v", args[0], string(out), err)
+			}
+		}
+		if files, err := r.ConflictFiles(); err != nil || len(files) > 0 {
+			logrus.Errorf("Cherry pick failed with error: %v and output: %q", pickErr, string(picked))
+			return fmt.Errorf("cherry pick failed, output: %q, error: %v", string(picked), pickErr)
+		}
+		if err := r.gitCommand("diff", "--cached", "--quiet").Run(); err == nil {
+			logrus.Infof("Skip %s changing no files of %v.", sha, paths)
+			_ = r.gitCommand("reset", "-q").Run()
+			continue
+		}
+		out, err := r.gitCommand("log", "-1", "--format=%an <%ae>%n%aI%n%B", sha).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("get commit failed, output: %q, error: %v", string(out), err)
+		}
+		fields := strings.SplitN(string(out), "\n", 3)
+		if len(fields) < 3 {
+			return fmt.Errorf("unexpected commit %q of %s", string(out), sha)
+		}
+		message := strings.TrimRight(fields[2], "\n") + fmt.Sprintf("\n\n(cherry picked from commit %s)", sha)
+		co := r.gitCommand("commit", "--no-verify", "--author", fields[0], "--date", fields[1], "-m", message)
+		if out, err = co.CombinedOutput(); err != nil {
+			logrus.Errorf("Commit failed with error: %v and output: %q", err, string(out))
+			return fmt.Errorf("commit failed, output: %q, error: %v", string(out), err)
+		}
+	}
+	return nil
+}
+
+// AddWorktree checks commitLike out detached in a new worktree at dir, the
+// returned repo works in it. Remove it with RemoveWorktree.
+func (r *Repo) AddWorktree(dir, commitLike string) (*Repo, error) {
+	logrus.Infof("Add worktree %s at %s.", dir, commitLike)
+	co := r.gitCommand("worktree", "add", "--force", "--detach", dir, commitLike)
+	if out, err := co.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("add worktree failed, output: %q, error: %v", string(out), err)
+	}
+	wt := *r
+	wt.dir = dir
+	return &wt, nil
+}
+
+// RemoveWorktree removes the worktree at dir with its changes.
+func (r *Repo) RemoveWorktree(dir string) error {
+	co := r.gitCommand("worktree", "remove", "--force", dir)
+	if out, err := co.CombinedOutput(); err != nil {
+		return fmt.Errorf("remove worktree failed, output: %q, error: %v", string(out), err)
+	}
+	return nil
+}
+
+// Cherry compares the commits from first to last with upstream by patch id,
+// it returns the commits not applied to upstream yet and those already applied.
+func (r *Repo) Cherry(upstream, first, last string) (pending, applied []string, err error) {
+	co := r.gitCommand("cherry", upstream, last, first+"^")
+	out, err := co.CombinedOutput()
+	if err != nil {
+		return nil, nil, fmt.Errorf("cherry failed, output: %q, error: %v", string(out), err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if sign, sha, ok := strings.Cut(line, " "); ok {
+			if sign == "-" {
+				applied = append(applied, sha)
+			} else {
+				pending = append(pending, sha)
+			}
+		}
+	}
+	return pending, applied, nil
+}
+
+// Commit is a commit listed by MissingCommits.
+type Commit struct {
+	SHA     string `json:"sha"`
+	Subject string `json:"subject"`
+}
+
+// MissingCommits lists the commits of head not reachable from upstream, oldest
+// first. Those whose change is already on upstream, compared by patch id, are
+// returned as present. Merge commits are left out.
+func (r *Repo) MissingCommits(upstream, head string) (missing, present []Commit, err error) {
+	co := r.gitCommand("log", "--no-merges", "--right-only", "--cherry-mark", "--reverse",
+		"--format=%m %H %s", upstream+"..."+head)
+	out, err := co.CombinedOutput()
+	if err != nil {
+		return nil, nil, fmt.Errorf("log failed, output: %q, error: %v", string(out), err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) < 2 {
+			continue
+		}
+		c := Commit{SHA: fields[1]}
+		if len(fields) == 3 {
+			c.Subject = fields[2]
+		}
+		if fields[0] == "=" {
+			present = append(present, c)
+		} else {
+			missing = append(missing, c)
+		}
+	}
+	return missing, present, nil
+}
+
+// MergeMethod is how a pull request was merged into its base.
+type MergeMethod string
+
+// MergeMethod enum
+const (
+	// MergeUnknown is returned when the changes of the pull request can't be
+	// found on the base.
+	MergeUnknown MergeMethod = ""
+	// MergeFastForward moved the base to the head of the pull request.
+	MergeFastForward MergeMethod = "fast-forward"
+	// MergeCommit added a merge commit with the head as a parent.
+	MergeCommit MergeMethod = "merge"
+	// MergeRebase put a copy of every commit of the pull request on the base.
+	MergeRebase MergeMethod = "rebase"
+	// MergeSquash put one commit with all the changes on the base.
+	MergeSquash MergeMethod = "squash"
+)
+
+// Merged tells how head was merged into base by FindMerged.
+type Merged struct {
+	Method MergeMethod
+	// Commits are the commits of base carrying the changes of head, oldest
+	// first: the merge commit, the squashed commit or the rebased commits. It
+	// is empty for MergeFastForward and MergeUnknown, the commits of head are
+	// on base as they are.
+	Commits []string
+}
+
+// FindMerged tells how head was merged into base. Merge commits are found on
+// the ancestry path from head to base, rebased commits by their patch ids and
+// a squashed commit by the patch id of all the changes of head.
+func (r *Repo) FindMerged(base, head string) (*Merged, error) {
+	if err := r.gitCommand("merge-base", "--is-ancestor", head, base).Run(); err == nil {
+		out, err := r.gitCommand("rev-list", "--merges", "--ancestry-path", "--reverse", "--parents",
+			head+".."+base).CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("list merges failed, output: %q, error: %v", string(out), err)
+		}
+		sha, err := r.revParse(head)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+			// the merge commit, its first parent and the others
+			fields := strings.Fields(line)
+			for i := 2; i < len(fields); i++ {
+				if fields[i] == sha {
+					return &Merged{Method: MergeCommit, Commits: fields[:1]}, nil
+				}
+			}
+		}
+		return &Merged{Method: MergeFastForward}, nil
+	}
+
+	out, err := r.gitCommand("merge-base", base, head).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("merge-base failed, output: %q, error: %v", string(out), err)
+	}
+	mergeBase := strings.TrimSpace(string(out))
+
+	out, err = r.gitCommand("rev-list", "--count", "--no-merges", mergeBase+".."+head).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("count commits failed, output: %q, error: %v", string(out), err)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		return nil, fmt.Errorf("parse commit count %q failed: %v", string(out), err)
+	}
+	out, err = r.gitCommand("log", "--no-merges", "--left-only", "--cherry-mark", "--reverse",
+		"--format=%m %H", base+"..."+head).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("log failed, output: %q, error: %v", string(out), err)
+	}
+	var rebased []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if mark, sha, ok := strings.Cut(line, " "); ok && mark == "=" {
+			rebased = append(rebased, sha)
+		}
+	}
+	if count > 0 && len(rebased) == count {
+		return &Merged{Method: MergeRebase, Commits: rebased}, nil
+	}
+
+	diff, err := r.gitCommand("diff", mergeBase, head).Output()
+	if err != nil {
+		return nil, fmt.Errorf("diff failed, error: %v", err)
+	}
+	ids, err := r.patchIDs(diff)
+	if err != nil || len(ids) == 0 {
+		return &Merged{Method: MergeUnknown}, err
+	}
+	log, err := r.gitCommand("log", "--no-merges", "-p", mergeBase+".."+base).Output()
+	if err != nil {
+		return nil, fmt.Errorf("log failed, error: %v", err)
+	}
+	onBase, err := r.patchIDs(log)
+	if err != nil {
+		return nil, err
+	}
+	for id := range ids {
+		if sha, ok := onBase[id]; ok {
+			return &Merged{Method: MergeSquash, Commits: []string{sha}}, nil
+		}
+	}
+	return &Merged{Method: MergeUnknown}, nil
+}
+
+// patchIDs maps the patch ids of the patches, a diff or the output of log -p,
+// to the commits they come from.
+func (r *Repo) patchIDs(patches []byte) (map[string]string, error) {
+	co := r.gitCommand("patch-id", "--stable")
+	co.Stdin = bytes.NewReader(patches)
+	out, err := co.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("patch-id failed, output: %q, error: %v", string(out), err)
+	}
+	ids := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if id, sha, ok := strings.Cut(line, " "); ok {
+			ids[id] = sha
+		}
+	}
+	return ids, nil
+}
+
+// revParse returns the SHA of the commit commitLike points to.
+func (r *Repo) revParse(commitLike string) (string, error) {
+	out, err := r.gitCommand("rev-parse", "--verify", commitLike+"^{commit}").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("rev-parse failed, output: %q, error: %v", string(out), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// LFSAvailable checks if git lfs is installed.
+func (r *Repo) LFSAvailable() bool {
+	return r.gitCommand("lfs", "version").Run() == nil
+}
+
+// LFSFetch downloads from origin the Git LFS objects commitLike refers to.
+func (r *Repo) LFSFetch(commitLike string) error {
+	logrus.Infof("Fetch LFS objects of %s.", commitLike)
+	co := r.gitCommand("lfs", "fetch", "origin", commitLike)
+	if out, err := co.CombinedOutput(); err != nil {
+		return fmt.Errorf("lfs fetch failed, output: %q, error: %v", string(out), err)
+	}
+	return nil
+}
+
+// LFSPush uploads to origin the Git LFS objects branch refers to, so that they
+// are there when branch is pushed.
+func (r *Repo) LFSPush(branch string) error {
+	logrus.Infof("Push LFS objects of %s.", branch)
+	co := r.gitCommand("lfs", "push", "origin", branch)
+	if out, err := co.CombinedOutput(); err != nil {
+		return fmt.Errorf("lfs push failed, output: %q, error: %v", string(out), err)
+	}
+	return nil
+}
+
+// CherryPickMerge cherry-picks the merge commit sha as the changes it brings
+// to its first parent.
+func (r *Repo) CherryPickMerge(sha string) error {
+	if err := r.ensureIdentity(); err != nil {
+		return fmt.Errorf("git identity setup failed before cherry-pick: %v", err)
+	}
+	logrus.Infof("Cherry Pick merge %s.", sha)
+	co := r.gitCommand("cherry-pick", "-x", "-m", "1", sha)
+	out, err := co.CombinedOutput()
+	if err != nil {
+		logrus.Errorf("Cherry pick failed with error: %v and output: %q", err, string(out))
+		return fmt.Errorf("cherry pick failed, output: %q, error: %v", string(out), err)
+	}
+	return nil
+}
+
+// ReadFile reads the file at path in commitLike.
+func (r *Repo) ReadFile(commitLike, path string) ([]byte, error) {
+	co := r.gitCommand("show", commitLike+":"+path)
+	out, err := co.Output()
+	if err != nil {
+		var stderr []byte
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			stderr = exitErr.Stderr
+		}
+		return nil, fmt.Errorf("show failed, output: %q, error: %v", string(stderr), err)
+	}
+	return out, nil
+}
+
+// ConflictFiles lists the files left unmerged by a failed cherry-pick or merge.
+func (r *Repo) ConflictFiles() ([]string, error) {
+	co := r.gitCommand("diff", "--name-only", "--diff-filter=U")
+	out, err := co.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("diff failed, output: %q, error: %v", string(out), err)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// HasDiff checks if the trees of two commits differ.
+func (r *Repo) HasDiff(from, to string) (bool, error) {
+	co := r.gitCommand("diff", "--quiet", from, to)
+	out, err := co.CombinedOutput()
+	if err == nil {
+		return false, nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return true, nil
+	}
+	return false, fmt.Errorf("diff failed, output: %q, error: %v", string(out), err)
+}
+
 // CherryPickAbort abort cherry-pick
 func (r *Repo) CherryPickAbort() error {
 	logrus.Infof("Cherry pick abort.")
@@ -489,16 +1001,16 @@ func (r *Repo) CherryPickAbort() error {
 
 // Push pushes over https to the provided owner/repo#branch using a password for basic auth.
 func (r *Repo) Push(branch string, force bool) error {
-	if r.user == "" || r.pass == "" {
-		return errors.New("cannot push without credentials - configure your git client")
-	}
-	logrus.Infof("Pushing to '%s/%s (branch: %s)'.", r.owner, r.repo, branch)
-	remote := fmt.Sprintf("https://%s:%s@%s/%s/%s", r.user, r.pass, r.host, r.owner, r.repo)
-
+	owner := r.owner
 	// check if repo is one of the big repos
 	if r.owner == "openEuler" && r.repo == "kernel" {
-		remote = fmt.Sprintf("https://%s:%s@%s/%s/%s", r.user, r.pass, r.host, "LiYanghang00", r.repo)
+		owner = "LiYanghang00"
+	}
+	if user, pass := r.client.getCredentials(owner); user == "" || pass == "" {
+		return errors.New("cannot push without credentials - configure your git client")
 	}
+	logrus.Infof("Pushing to '%s/%s (branch: %s)'.", owner, r.repo, branch)
+	remote := fmt.Sprintf("%s/%s/%s", r.base, owner, r.repo)
 
 	var co *exec.Cmd
 	if force {
@@ -506,6 +1018,7 @@ func (r *Repo) Push(branch string, force bool) error {
 	} else {
 		co = r.gitCommand("push", remote, branch)
 	}
+	co.Env = r.client.gitEnv(owner)
 	out, err := co.CombinedOutput()
 	if err != nil {
 		logrus.Errorf("Pushing failed with error: %v and output: %q", err, string(out))
@@ -531,11 +1044,11 @@ func (r *Repo) DeleteBranch(branch string, force bool) error {
 
 // DeleteRemoteBranch delete remote branch
 func (r *Repo) DeleteRemoteBranch(branch string) error {
-	if r.user == "" || r.pass == "" {
+	if user, pass := r.client.getCredentials(r.owner); user == "" || pass == "" {
 		return errors.New("cannot push without credentials - configure your git client")
 	}
 	logrus.Infof("Delete remote branch '%s/%s (branch: %s)'.", r.owner, r.repo, branch)
-	remote := fmt.Sprintf("https://%s:%s@%s/%s/%s", r.user, r.pass, r.host, r.owner, r.repo)
+	remote := fmt.Sprintf("%s/%s/%s", r.base, r.owner, r.repo)
 	co := r.gitCommand("push", remote, "--delete", branch)
 	out, err := co.CombinedOutput()
 	if err != nil {
@@ -548,8 +1061,8 @@ func (r *Repo) DeleteRemoteBranch(branch string) error {
 // FetchPullRequest just fetch
 func (r *Repo) FetchPullRequest(number int) error {
 	logrus.Infof("Fetching %s/%s#%d.", r.owner, r.repo, number)
-	if b, err := retryCmd(r.dir, r.git, "fetch", r.base+"/"+r.owner+"/"+r.repo,
-		fmt.Sprintf("+refs/merge-requests/%d/head:refs/remotes/origin/merge-requests/%d", number, number)); err != nil {
+	if b, err := retryCmd(r.dir, r.client.gitEnv(r.owner), r.git, "fetch", r.base+"/"+r.owner+"/"+r.repo,
+		fmt.Sprintf("+"+r.client.prRef+":refs/remotes/origin/merge-requests/%d", number, number)); err != nil {
 		return fmt.Errorf("git fetch failed for PR %d: %v. output: %s", number, err, string(b))
 	}
 	return nil
@@ -575,13 +1088,14 @@ func (r *Repo) Merge(ref string, option MergeOption) error {
 
 // retryCmd will retry the command a few times with backoff. Use this for any
 // commands that will be talking to GitHub, such as clones or fetches.
-func retryCmd(dir, cmd string, arg ...string) ([]byte, error) {
+func retryCmd(dir string, env []string, cmd string, arg ...string) ([]byte, error) {
 	var b []byte
 	var err error
 	sleepyTime := time.Second
 	for i := 0; i < 3; i++ {
 		c := exec.Command(cmd, arg...)
 		c.Dir = dir
+		c.Env = env
 		b, err = c.CombinedOutput()
 		if err != nil {
 			err = fmt.Errorf("running %q %v returned error %w with output %q", cmd, arg, err, string(b))
@@ -616,13 +1130,13 @@ func (r *Repo) DisablePartialClone() error {
 }
 
 func (r *Repo) FetchRemoteRobust(remote string) error {
-	if fb, ferr := retryCmd(r.dir, r.git, "fetch", "--no-tags", remote); ferr != nil {
+	if fb, ferr := retryCmd(r.dir, r.client.gitEnv(r.owner), r.git, "fetch", "--no-tags", remote); ferr != nil {
 		out := string(fb)
 		if strings.Contains(out, "RPC failed") || strings.Contains(out, "expected 'packfile'") || strings.Contains(out, "504") ||
 			strings.Contains(out, "invalid index-pack output") || strings.Contains(out, "promisor remote") || strings.Contains(out, "could not fetch") {
-			if _, ferr2 := retryCmd(r.dir, r.git, "fetch", "--no-tags", "--depth", "1", remote); ferr2 != nil {
+			if _, ferr2 := retryCmd(r.dir, r.client.gitEnv(r.owner), r.git, "fetch", "--no-tags", "--depth", "1", remote); ferr2 != nil {
 				_ = r.DisablePartialClone()
-				if fb3, ferr3 := retryCmd(r.dir, r.git, "fetch", "--no-tags", remote); ferr3 != nil {
+				if fb3, ferr3 := retryCmd(r.dir, r.client.gitEnv(r.owner), r.git, "fetch", "--no-tags", remote); ferr3 != nil {
 					logrus.WithFields(logrus.Fields{
 						"dir":    r.dir,
 						"remote": remote,
@@ -642,13 +1156,13 @@ func (r *Repo) FetchRemoteRobust(remote string) error {
 }
 
 func (r *Repo) fetchRefspecRobust(remote, refspec string) error {
-	if fb, ferr := retryCmd(r.dir, r.git, "fetch", "--no-tags", "--filter=blob:none", remote, refspec); ferr != nil {
+	if fb, ferr := retryCmd(r.dir, r.client.gitEnv(r.owner), r.git, "fetch", "--no-tags", "--filter=blob:none", remote, refspec); ferr != nil {
 		out := string(fb)
 		if strings.Contains(out, "RPC failed") || strings.Contains(out, "expected 'packfile'") || strings.Contains(out, "504") ||
 			strings.Contains(out, "invalid index-pack output") || strings.Contains(out, "promisor remote") || strings.Contains(out, "could not fetch") {
-			if _, ferr2 := retryCmd(r.dir, r.git, "fetch", "--no-tags", "--depth", "1", remote, refspec); ferr2 != nil {
+			if _, ferr2 := retryCmd(r.dir, r.client.gitEnv(r.owner), r.git, "fetch", "--no-tags", "--depth", "1", remote, refspec); ferr2 != nil {
 				_ = r.DisablePartialClone()
-				if fb3, ferr3 := retryCmd(r.dir, r.git, "fetch", "--no-tags", remote, refspec); ferr3 != nil {
+				if fb3, ferr3 := retryCmd(r.dir, r.client.gitEnv(r.owner), r.git, "fetch", "--no-tags", remote, refspec); ferr3 != nil {
 					logrus.WithFields(logrus.Fields{
 						"dir":     r.dir,
 						"remote":  remote,
@@ -670,6 +1184,12 @@ func (r *Repo) fetchRefspecRobust(remote, refspec string) error {
 }
 
 func (r *Repo) ensureIdentity() error {
+	if name, email := r.client.getCommitter(); name != "" && email != "" {
+		if err := r.Config("user.name", name); err != nil {
+			return err
+		}
+		return r.Config("user.email", email)
+	}
 	getName := r.gitCommand("config", "--get", "user.name")
 	n, ne := getName.CombinedOutput()
 	name := strings.TrimSpace(string(n))
@@ -2,14 +2,13 @@ package git
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 	"testing"
 )
 
 func TestCherryPick(t *testing.T) {
 
-	c, err := NewClient()
+	c, err := NewClientWithHost("gitcode.com")
 	if err != nil {
 		t.Fatalf("New Client failed: %v", err)
 	}
@@ -47,7 +46,7 @@ func TestCherryPick(t *testing.T) {
 		t.Fatalf("Checkout new branch %v failed: %v", targetBranch, err)
 	}
 
-	err = r.FetchPullRequest(strconv.Itoa(pr))
+	err = r.FetchPullRequest(pr)
 	if err != nil {
 		t.Fatalf("Fetch pull request %v failed: %v", pr, err)
 	}
@@ -0,0 +1,513 @@
+// Package gitee implements the platform client of sync-bot on the Gitee v5 API.
+package gitee
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"sync-bot/platform"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+type user struct {
+	Login string `json:"login"`
+}
+
+type branchRef struct {
+	Ref string `json:"ref"`
+}
+
+type pullRequest struct {
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	HTMLURL   string    `json:"html_url"`
+	State     string    `json:"state"`
+	Mergeable *bool     `json:"mergeable"`
+	Head      branchRef `json:"head"`
+	Base      branchRef `json:"base"`
+	User      user      `json:"user"`
+	// Assignees are the reviewers of the pull request.
+	Assignees []user `json:"assignees"`
+}
+
+type comment struct {
+	ID      int64  `json:"id"`
+	Body    string `json:"body"`
+	HTMLURL string `json:"html_url"`
+	User    user   `json:"user"`
+}
+
+type label struct {
+	Name string `json:"name"`
+}
+
+type commit struct {
+	SHA     string `json:"sha"`
+	HTMLURL string `json:"html_url"`
+	Commit  struct {
+		Message   string `json:"message"`
+		Committer struct {
+			Date string `json:"date"`
+		} `json:"committer"`
+	} `json:"commit"`
+}
+
+// Client is a platform client of Gitee. Create with NewClient.
+type Client struct {
+	api *platform.API
+	log *logrus.Entry
+}
+
+// NewClient returns a client of the Gitee v5 API at apiBase authenticated with token.
+func NewClient(token []byte, apiBase string, logger *logrus.Entry) *Client {
+	t := string(token)
+	return &Client{
+		api: platform.NewAPI(apiBase, func(req *http.Request) {
+			q := req.URL.Query()
+			q.Set("access_token", t)
+			req.URL.RawQuery = q.Encode()
+		}),
+		log: logger,
+	}
+}
+
+func repoPath(org, repo string) string {
+	return fmt.Sprintf("/repos/%s/%s", url.PathEscape(org), url.PathEscape(repo))
+}
+
+// GetPullRequest gets a pull request in a specified organization and repository
+func (c *Client) GetPullRequest(org, repo, number string) (result client.PullRequest, success bool) {
+	var pr pullRequest
+	if err := c.api.Do(http.MethodGet, repoPath(org, repo)+"/pulls/"+number, nil, nil, &pr); err != nil {
+		c.log.WithError(err).Errorf("Get pull request %s/%s#%s failed", org, repo, number)
+		return
+	}
+	return toPullRequest(&pr), true
+}
+
+// ListOpenPullRequests lists the pull requests open on a repository
+func (c *Client) ListOpenPullRequests(org, repo string) (result []client.PullRequest, success bool) {
+	prs, err := platform.GetAll[pullRequest](c.api, repoPath(org, repo)+"/pulls", url.Values{"state": {"open"}})
+	if err != nil {
+		c.log.WithError(err).Errorf("List open pull requests of %s/%s failed", org, repo)
+		return
+	}
+	for i := range prs {
+		result = append(result, toPullRequest(&prs[i]))
+	}
+	return result, true
+}
+
+func toPullRequest(pr *pullRequest) client.PullRequest {
+	num := strconv.Itoa(pr.Number)
+	return client.PullRequest{
+		Number:    &num,
+		Title:     &pr.Title,
+		Body:      &pr.Body,
+		Head:      &pr.Head.Ref,
+		Base:      &pr.Base.Ref,
+		URL:       &pr.HTMLURL,
+		State:     &pr.State,
+		MergeAble: pr.Mergeable,
+	}
+}
+
+// GetAuthenticatedUser gets the login of the user the token belongs to
+func (c *Client) GetAuthenticatedUser() (login string, success bool) {
+	var u user
+	if err := c.api.Do(http.MethodGet, "/user", nil, nil, &u); err != nil {
+		c.log.WithError(err).Errorln("Get the authenticated user failed")
+		return
+	}
+	return u.Login, true
+}
+
+// GetPullRequestAuthor gets the login of the user who opened a pull request
+func (c *Client) GetPullRequestAuthor(org, repo, number string) (login string, success bool) {
+	var pr pullRequest
+	if err := c.api.Do(http.MethodGet, repoPath(org, repo)+"/pulls/"+number, nil, nil, &pr); err != nil {
+		c.log.WithError(err).Errorf("Get pull request %s/%s#%s failed", org, repo, number)
+		return
+	}
+	return pr.User.Login, true
+}
+
+// GetPathContent gets the base64 encoded content of a file on branch
+func (c *Client) GetPathContent(org, repo, path, branch string) (result client.RepoContent, success bool) {
+	var content struct {
+		Content string `json:"content"`
+	}
+	query := url.Values{"ref": {branch}}
+	if err := c.api.Do(http.MethodGet, repoPath(org, repo)+"/contents/"+path, query, nil, &content); err != nil {
+		c.log.WithError(err).Errorf("Get content of %s/%s:%s on %s failed", org, repo, path, branch)
+		return
+	}
+	return client.RepoContent{Content: &content.Content}, true
+}
+
+// GetRepoAllBranch lists all branches of a repository
+func (c *Client) GetRepoAllBranch(org, repo string) (result []client.Branch, success bool) {
+	var branches []struct {
+		Name string `json:"name"`
+	}
+	// the branch list of Gitee is not paged
+	if err := c.api.Do(http.MethodGet, repoPath(org, repo)+"/branches", nil, nil, &branches); err != nil {
+		c.log.WithError(err).Errorf("List branches of %s/%s failed", org, repo)
+		return
+	}
+	for _, b := range branches {
+		result = append(result, client.Branch{Name: b.Name})
+	}
+	return result, true
+}
+
+// ListProtectedBranches lists the protected branches of a repository
+func (c *Client) ListProtectedBranches(org, repo string) (result []string, success bool) {
+	var branches []struct {
+		Name      string `json:"name"`
+		Protected bool   `json:"protected"`
+	}
+	if err := c.api.Do(http.MethodGet, repoPath(org, repo)+"/branches", nil, nil, &branches); err != nil {
+		c.log.WithError(err).Errorf("List branches of %s/%s failed", org, repo)
+		return
+	}
+	for _, b := range branches {
+		if b.Protected {
+			result = append(result, b.Name)
+		}
+	}
+	return result, true
+}
+
+// ListOrgRepos lists the names of the repositories of an organization
+func (c *Client) ListOrgRepos(org string) (result []string, success bool) {
+	// the name of a Gitee repository may differ from its path, which is the
+	// one of its URL
+	repos, err := platform.GetAll[struct {
+		Path string `json:"path"`
+	}](c.api, "/orgs/"+url.PathEscape(org)+"/repos", nil)
+	if err != nil {
+		c.log.WithError(err).Errorf("List repositories of %s failed", org)
+		return
+	}
+	for _, r := range repos {
+		result = append(result, r.Path)
+	}
+	return result, true
+}
+
+// ListPullRequestComments lists the comments of a pull request, the latest first
+func (c *Client) ListPullRequestComments(org, repo, number string) (result []client.PRComment, success bool) {
+	comments, ok := c.ListPullRequestCommentsWithID(org, repo, number)
+	for _, cm := range comments {
+		result = append(result, client.PRComment{Commenter: cm.Commenter, Body: cm.Body})
+	}
+	return result, ok
+}
+
+// ListPullRequestCommentsWithID lists the comments of a pull request with their ids, the latest first
+func (c *Client) ListPullRequestCommentsWithID(org, repo, number string) (result []platform.Comment, success bool) {
+	comments, err := platform.GetAll[comment](c.api, repoPath(org, repo)+"/pulls/"+number+"/comments", nil)
+	if err != nil {
+		c.log.WithError(err).Errorf("List comments of %s/%s#%s failed", org, repo, number)
+		return
+	}
+	for i := len(comments) - 1; i >= 0; i-- {
+		result = append(result, platform.Comment{
+			ID:        strconv.FormatInt(comments[i].ID, 10),
+			Commenter: comments[i].User.Login,
+			Body:      comments[i].Body,
+		})
+	}
+	return result, true
+}
+
+// EditPRComment replaces the body of the comment commentID of a pull request
+func (c *Client) EditPRComment(org, repo, number, commentID, comment string) (success bool) {
+	body := map[string]string{"body": comment}
+	if err := c.api.Do(http.MethodPatch, repoPath(org, repo)+"/pulls/comments/"+commentID, nil, body, nil); err != nil {
+		c.log.WithError(err).Errorf("Edit comment %s on %s/%s#%s failed", commentID, org, repo, number)
+		return false
+	}
+	return true
+}
+
+// ListIssueCommentsWithID lists the comments of an issue with their ids, the latest first
+func (c *Client) ListIssueCommentsWithID(org, repo, number string) (result []platform.Comment, success bool) {
+	comments, err := platform.GetAll[comment](c.api, repoPath(org, repo)+"/issues/"+number+"/comments", nil)
+	if err != nil {
+		c.log.WithError(err).Errorf("List comments of %s/%s#%s failed", org, repo, number)
+		return
+	}
+	for i := len(comments) - 1; i >= 0; i-- {
+		result = append(result, platform.Comment{
+			ID:        strconv.FormatInt(comments[i].ID, 10),
+			Commenter: comments[i].User.Login,
+			Body:      comments[i].Body,
+		})
+	}
+	return result, true
+}
+
+// EditIssueComment replaces the body of the comment commentID of an issue
+func (c *Client) EditIssueComment(org, repo, number, commentID, comment string) (success bool) {
+	body := map[string]string{"body": comment}
+	if err := c.api.Do(http.MethodPatch, repoPath(org, repo)+"/issues/comments/"+commentID, nil, body, nil); err != nil {
+		c.log.WithError(err).Errorf("Edit comment %s on %s/%s#%s failed", commentID, org, repo, number)
+		return false
+	}
+	return true
+}
+
+// RequestPRReviewers adds the users as the reviewers of a pull request, which
+// Gitee calls its assignees
+func (c *Client) RequestPRReviewers(org, repo, number string, reviewers []string) (success bool) {
+	body := map[string]string{"assignees": strings.Join(reviewers, ",")}
+	if err := c.api.Do(http.MethodPost, repoPath(org, repo)+"/pulls/"+number+"/assignees", nil, body, nil); err != nil {
+		c.log.WithError(err).Errorf("Request reviewers of %s/%s#%s failed", org, repo, number)
+		return false
+	}
+	return true
+}
+
+// GetPullRequestReviewers gets the reviewers of a pull request, which Gitee
+// calls its assignees
+func (c *Client) GetPullRequestReviewers(org, repo, number string) (result []string, success bool) {
+	var pr pullRequest
+	if err := c.api.Do(http.MethodGet, repoPath(org, repo)+"/pulls/"+number, nil, nil, &pr); err != nil {
+		c.log.WithError(err).Errorf("Get pull request %s/%s#%s failed", org, repo, number)
+		return
+	}
+	for _, u := range pr.Assignees {
+		result = append(result, u.Login)
+	}
+	return result, true
+}
+
+// AssignPR assigns the users to a pull request. Gitee has no assignees of pull
+// requests but the reviewers, the users are added to them.
+func (c *Client) AssignPR(org, repo, number string, assignees []string) (success bool) {
+	return c.RequestPRReviewers(org, repo, number, assignees)
+}
+
+// GetPRLinkedIssue gets the issues linked to a pull request
+func (c *Client) GetPRLinkedIssue(org, repo, number string) (result []client.Issue, success bool) {
+	issues, err := platform.GetAll[struct {
+		HTMLURL string `json:"html_url"`
+	}](c.api, repoPath(org, repo)+"/pulls/"+number+"/issues", nil)
+	if err != nil {
+		c.log.WithError(err).Errorf("List issues of %s/%s#%s failed", org, repo, number)
+		return
+	}
+	for _, is := range issues {
+		result = append(result, client.Issue{HtmlURL: is.HTMLURL})
+	}
+	return result, true
+}
+
+// CreatePRComment comments on a pull request
+func (c *Client) CreatePRComment(org, repo, number, comment string) (success bool) {
+	body := map[string]string{"body": comment}
+	if err := c.api.Do(http.MethodPost, repoPath(org, repo)+"/pulls/"+number+"/comments", nil, body, nil); err != nil {
+		c.log.WithError(err).Errorf("Comment on %s/%s#%s failed", org, repo, number)
+		return false
+	}
+	return true
+}
+
+// CreateIssueComment comments on an issue
+func (c *Client) CreateIssueComment(org, repo, number, comment string) (success bool) {
+	body := map[string]string{"body": comment}
+	if err := c.api.Do(http.MethodPost, repoPath(org, repo)+"/issues/"+number+"/comments", nil, body, nil); err != nil {
+		c.log.WithError(err).Errorf("Comment on %s/%s#%s failed", org, repo, number)
+		return false
+	}
+	return true
+}
+
+// AddIssueLabels adds labels to an issue
+func (c *Client) AddIssueLabels(org, repo, number string, labels []string) (success bool) {
+	return c.addLabels(repoPath(org, repo)+"/issues/"+number+"/labels", labels)
+}
+
+// RemoveIssueLabels removes labels from an issue
+func (c *Client) RemoveIssueLabels(org, repo, number string, labels []string) (success bool) {
+	return c.removeLabels(repoPath(org, repo)+"/issues/"+number+"/labels/", labels)
+}
+
+// AddPRLabels adds labels to a pull request
+func (c *Client) AddPRLabels(org, repo, number string, labels []string) (success bool) {
+	return c.addLabels(repoPath(org, repo)+"/pulls/"+number+"/labels", labels)
+}
+
+// RemovePRLabels removes labels from a pull request
+func (c *Client) RemovePRLabels(org, repo, number string, labels []string) (success bool) {
+	return c.removeLabels(repoPath(org, repo)+"/pulls/"+number+"/labels/", labels)
+}
+
+func (c *Client) addLabels(path string, labels []string) bool {
+	if err := c.api.Do(http.MethodPost, path, nil, labels, nil); err != nil {
+		c.log.WithError(err).Errorf("Add labels %v to %s failed", labels, path)
+		return false
+	}
+	return true
+}
+
+func (c *Client) removeLabels(path string, labels []string) bool {
+	success := true
+	for _, l := range labels {
+		if err := c.api.Do(http.MethodDelete, path+url.PathEscape(l), nil, nil, nil); err != nil && !platform.IsNotFound(err) {
+			c.log.WithError(err).Errorf("Remove label %s from %s failed", l, path)
+			success = false
+		}
+	}
+	return success
+}
+
+// GetPullRequestCommits lists the commits of a pull request, the latest first
+func (c *Client) GetPullRequestCommits(org, repo, number string) (result []client.PRCommit, success bool) {
+	commits, err := platform.GetAll[commit](c.api, repoPath(org, repo)+"/pulls/"+number+"/commits", nil)
+	if err != nil {
+		c.log.WithError(err).Errorf("List commits of %s/%s#%s failed", org, repo, number)
+		return
+	}
+	for i := len(commits) - 1; i >= 0; i-- {
+		result = append(result, client.PRCommit{
+			SHA:        commits[i].SHA,
+			HTMLURL:    commits[i].HTMLURL,
+			CommitTime: commits[i].Commit.Committer.Date,
+			Message:    commits[i].Commit.Message,
+		})
+	}
+	return result, true
+}
+
+// GetPullRequestLabels lists the labels of a pull request
+func (c *Client) GetPullRequestLabels(org, repo, number string) (result []string, success bool) {
+	return c.listLabels(repoPath(org, repo) + "/pulls/" + number + "/labels")
+}
+
+// GetIssueLabels lists the labels of an issue, issues of Gitee are unique in an organization
+func (c *Client) GetIssueLabels(org, issueID string) (result []string, success bool) {
+	var is struct {
+		Labels []label `json:"labels"`
+	}
+	path := fmt.Sprintf("/repos/%s/issues/%s", url.PathEscape(org), url.PathEscape(issueID))
+	if err := c.api.Do(http.MethodGet, path, nil, nil, &is); err != nil {
+		c.log.WithError(err).Errorf("Get issue %s/%s failed", org, issueID)
+		return
+	}
+	for _, l := range is.Labels {
+		result = append(result, l.Name)
+	}
+	return result, true
+}
+
+// GetRepoIssueLabels lists the labels of a repository
+func (c *Client) GetRepoIssueLabels(org, repo string) (result []string, success bool) {
+	return c.listLabels(repoPath(org, repo) + "/labels")
+}
+
+func (c *Client) listLabels(path string) (result []string, success bool) {
+	var labels []label
+	if err := c.api.Do(http.MethodGet, path, nil, nil, &labels); err != nil {
+		c.log.WithError(err).Errorf("List labels of %s failed", path)
+		return
+	}
+	for _, l := range labels {
+		result = append(result, l.Name)
+	}
+	return result, true
+}
+
+// CheckPermissionWithBranch checks if the user can push to the repository
+func (c *Client) CheckPermissionWithBranch(org, repo, username, branch string) (pass, success bool) {
+	var perm struct {
+		Permission string `json:"permission"`
+	}
+	path := repoPath(org, repo) + "/collaborators/" + url.PathEscape(username) + "/permission"
+	if err := c.api.Do(http.MethodGet, path, nil, nil, &perm); err != nil {
+		c.log.WithError(err).Errorf("Get permission of %s on %s/%s failed", username, org, repo)
+		return false, false
+	}
+	switch perm.Permission {
+	case "admin", "write":
+		return true, true
+	default:
+		return false, true
+	}
+}
+
+// GetPullRequestChanges lists the files changed by a pull request
+func (c *Client) GetPullRequestChanges(org, repo, number string) (result []client.CommitFile, success bool) {
+	var files []struct {
+		Filename string `json:"filename"`
+	}
+	if err := c.api.Do(http.MethodGet, repoPath(org, repo)+"/pulls/"+number+"/files", nil, nil, &files); err != nil {
+		c.log.WithError(err).Errorf("List files of %s/%s#%s failed", org, repo, number)
+		return
+	}
+	for _, f := range files {
+		result = append(result, client.CommitFile{Filename: f.Filename})
+	}
+	return result, true
+}
+
+// CreatePR creates a pull request, the head of a fork is given as "owner:branch"
+func (c *Client) CreatePR(org, repo string, prContent client.PullRequest) (number string, success bool) {
+	body := map[string]interface{}{
+		"title":               utils.GetString(prContent.Title),
+		"body":                utils.GetString(prContent.Body),
+		"head":                utils.GetString(prContent.Head),
+		"base":                utils.GetString(prContent.Base),
+		"prune_source_branch": utils.GetBool(prContent.PruneSourceBranch),
+	}
+	var pr pullRequest
+	if err := c.api.Do(http.MethodPost, repoPath(org, repo)+"/pulls", nil, body, &pr); err != nil {
+		c.log.WithError(err).Errorf("Create pull request %v -> %v on %s/%s failed", body["head"], body["base"], org, repo)
+		return "", false
+	}
+	return strconv.Itoa(pr.Number), true
+}
+
+// CreateRepoBranch creates branch from createFrom
+func (c *Client) CreateRepoBranch(org, repo, createFrom, branch string) (success bool) {
+	body := map[string]string{"refs": createFrom, "branch_name": branch}
+	if err := c.api.Do(http.MethodPost, repoPath(org, repo)+"/branches", nil, body, nil); err != nil {
+		c.log.WithError(err).Errorf("Create branch %s of %s/%s failed", branch, org, repo)
+		return false
+	}
+	return true
+}
+
+// CheckIfPRCreateEvent checks if the event opens a pull request
+func (c *Client) CheckIfPRCreateEvent(evt *client.GenericEvent) (yes bool) {
+	return utils.GetString(evt.Action) == "open"
+}
+
+// CheckIfPRReopenEvent checks if the event reopens a pull request
+func (c *Client) CheckIfPRReopenEvent(evt *client.GenericEvent) (yes bool) {
+	return utils.GetString(evt.Action) == "reopen"
+}
+
+// CheckIfPRMergeEvent checks if the event merges a pull request
+func (c *Client) CheckIfPRMergeEvent(evt *client.GenericEvent) (yes bool) {
+	return utils.GetString(evt.Action) == "merge"
+}
+
+// CheckIfPRCloseEvent checks if the event closes a pull request without merging it
+func (c *Client) CheckIfPRCloseEvent(evt *client.GenericEvent) (yes bool) {
+	return utils.GetString(evt.Action) == "close"
+}
+
+// CheckIfPRSourceCodeUpdateEvent checks if the event pushes to the head of a pull request
+func (c *Client) CheckIfPRSourceCodeUpdateEvent(evt *client.GenericEvent) (yes bool) {
+	return utils.GetString(evt.Action) == "update"
+}
@@ -0,0 +1,177 @@
+package gitee
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"sync-bot/platform"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("access_token") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return NewClient([]byte("token"), srv.URL, logrus.NewEntry(logrus.New()))
+}
+
+func TestGetPullRequest(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/open-euler/syncbot-example/pulls/23" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(w, `{"number":23,"title":"fix","body":null,"state":"merged","mergeable":true,
+			"html_url":"https://gitee.com/open-euler/syncbot-example/pulls/23","head":{"ref":"bar"},"base":{"ref":"master"}}`)
+	})
+
+	pr, ok := c.GetPullRequest("open-euler", "syncbot-example", "23")
+	if !ok {
+		t.Fatalf("GetPullRequest() failed")
+	}
+	got := []string{utils.GetString(pr.Number), utils.GetString(pr.Title), utils.GetString(pr.Head),
+		utils.GetString(pr.Base), utils.GetString(pr.URL)}
+	want := []string{"23", "fix", "bar", "master", "https://gitee.com/open-euler/syncbot-example/pulls/23"}
+	if !reflect.DeepEqual(got, want) || !utils.GetBool(pr.MergeAble) {
+		t.Errorf("GetPullRequest() = %v, want %v", got, want)
+	}
+
+	if _, ok = c.GetPullRequest("open-euler", "syncbot-example", "24"); ok {
+		t.Errorf("GetPullRequest() of a missing pull request succeeded")
+	}
+}
+
+func TestListProtectedBranches(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/src-openeuler/kernel/branches":
+			_, _ = io.WriteString(w, `[{"name":"master","protected":true},{"name":"feature","protected":false}]`)
+		case "/orgs/src-openeuler/repos":
+			_, _ = io.WriteString(w, `[{"path":"kernel","name":"Kernel"}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	if branches, ok := c.ListProtectedBranches("src-openeuler", "kernel"); !ok || !reflect.DeepEqual(branches, []string{"master"}) {
+		t.Errorf("ListProtectedBranches() = %v, %v, want master", branches, ok)
+	}
+	if repos, ok := c.ListOrgRepos("src-openeuler"); !ok || !reflect.DeepEqual(repos, []string{"kernel"}) {
+		t.Errorf("ListOrgRepos() = %v, %v, want kernel", repos, ok)
+	}
+}
+
+func TestGetPullRequestCommits(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `[{"sha":"1111"},{"sha":"2222"},{"sha":"3333"}]`)
+	})
+
+	commits, ok := c.GetPullRequestCommits("open-euler", "syncbot-example", "23")
+	if !ok {
+		t.Fatalf("GetPullRequestCommits() failed")
+	}
+	var got []string
+	for _, commit := range commits {
+		got = append(got, commit.SHA)
+	}
+	// the latest commit first, like the other platforms
+	if want := []string{"3333", "2222", "1111"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetPullRequestCommits() = %v, want %v", got, want)
+	}
+}
+
+func TestCreatePR(t *testing.T) {
+	var body map[string]interface{}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/open-euler/syncbot-example/pulls" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{"number":24}`)
+	})
+
+	title, head, base, prune := "[sync] PR-23: fix", "bot:sync-pr23-bar-to-stable", "stable", true
+	number, ok := c.CreatePR("open-euler", "syncbot-example",
+		client.PullRequest{Title: &title, Head: &head, Base: &base, PruneSourceBranch: &prune})
+	if !ok || number != "24" {
+		t.Fatalf("CreatePR() = %v, %v", number, ok)
+	}
+	want := map[string]interface{}{"title": title, "body": "", "head": head, "base": base, "prune_source_branch": true}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("CreatePR() sent %v, want %v", body, want)
+	}
+}
+
+func TestEditPRComment(t *testing.T) {
+	var body map[string]interface{}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/open-euler/syncbot-example/pulls/23/comments":
+			_, _ = io.WriteString(w, `[{"id":7,"body":"old","user":{"login":"bot"}},{"id":9,"body":"new","user":{"login":"alice"}}]`)
+		case r.Method == http.MethodPatch && r.URL.Path == "/repos/open-euler/syncbot-example/pulls/comments/7":
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			_, _ = io.WriteString(w, `{"id":7}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	comments, ok := c.ListPullRequestCommentsWithID("open-euler", "syncbot-example", "23")
+	want := []platform.Comment{{ID: "9", Commenter: "alice", Body: "new"}, {ID: "7", Commenter: "bot", Body: "old"}}
+	if !ok || !reflect.DeepEqual(comments, want) {
+		t.Fatalf("ListPullRequestCommentsWithID() = %v, %v, want %v", comments, ok, want)
+	}
+	if !c.EditPRComment("open-euler", "syncbot-example", "23", "7", "edited") {
+		t.Fatalf("EditPRComment() failed")
+	}
+	if body["body"] != "edited" {
+		t.Errorf("EditPRComment() sent %v", body)
+	}
+	if c.EditPRComment("open-euler", "syncbot-example", "23", "8", "edited") {
+		t.Errorf("EditPRComment() of a missing comment succeeded")
+	}
+}
+
+func TestEditIssueComment(t *testing.T) {
+	var body map[string]interface{}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/open-euler/syncbot-example/issues/I8ABCD/comments":
+			_, _ = io.WriteString(w, `[{"id":7,"body":"old","user":{"login":"bot"}},{"id":9,"body":"new","user":{"login":"alice"}}]`)
+		case r.Method == http.MethodPatch && r.URL.Path == "/repos/open-euler/syncbot-example/issues/comments/7":
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			_, _ = io.WriteString(w, `{"id":7}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	comments, ok := c.ListIssueCommentsWithID("open-euler", "syncbot-example", "I8ABCD")
+	want := []platform.Comment{{ID: "9", Commenter: "alice", Body: "new"}, {ID: "7", Commenter: "bot", Body: "old"}}
+	if !ok || !reflect.DeepEqual(comments, want) {
+		t.Fatalf("ListIssueCommentsWithID() = %v, %v, want %v", comments, ok, want)
+	}
+	if !c.EditIssueComment("open-euler", "syncbot-example", "I8ABCD", "7", "edited") {
+		t.Fatalf("EditIssueComment() failed")
+	}
+	if body["body"] != "edited" {
+		t.Errorf("EditIssueComment() sent %v", body)
+	}
+	if c.EditIssueComment("open-euler", "syncbot-example", "I8ABCD", "8", "edited") {
+		t.Errorf("EditIssueComment() of a missing comment succeeded")
+	}
+}
@@ -0,0 +1,166 @@
+package gitee
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"sync-bot/platform"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+)
+
+// maxSignatureAge is how old the X-Gitee-Timestamp of a signed delivery may be.
+const maxSignatureAge = 5 * time.Minute
+
+// now returns the current time, replaced by tests.
+var now = time.Now
+
+type repository struct {
+	Path      string `json:"path"`
+	Namespace string `json:"namespace"`
+}
+
+type pullRequestPayload struct {
+	Action      string      `json:"action"`
+	ActionDesc  string      `json:"action_desc"`
+	PullRequest pullRequest `json:"pull_request"`
+	Repository  repository  `json:"repository"`
+}
+
+type notePayload struct {
+	Action       string      `json:"action"`
+	NoteableType string      `json:"noteable_type"`
+	Comment      comment     `json:"comment"`
+	PullRequest  pullRequest `json:"pull_request"`
+	Issue        struct {
+		// Number is the ident of the issue, like I8ABCD.
+		Number string `json:"number"`
+		Title  string `json:"title"`
+		State  string `json:"state"`
+	} `json:"issue"`
+	Repository repository `json:"repository"`
+}
+
+// prState maps the state of a Gitee pull request to the one the handlers expect.
+func prState(state string) string {
+	if state == "open" {
+		return "opened"
+	}
+	return state
+}
+
+// VerifySignature checks the X-Gitee-Token header of a delivery against secret.
+// The header holds either the secret itself or, for signed hooks, the base64
+// HMAC-SHA256 of the X-Gitee-Timestamp header and the secret. Gitee doesn't
+// sign the payload, so signed deliveries older than maxSignatureAge are
+// refused to keep a captured signature from being replayed.
+func VerifySignature(header http.Header, payload, secret []byte) error {
+	if len(secret) == 0 {
+		return errors.New("empty secret")
+	}
+	token := header.Get("X-Gitee-Token")
+	if token == "" {
+		return errors.New("missing X-Gitee-Token")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), secret) == 1 {
+		return nil
+	}
+	ts := header.Get("X-Gitee-Timestamp")
+	if ts == "" {
+		return errors.New("token mismatch")
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts + "\n" + string(secret)))
+	if !hmac.Equal([]byte(token), []byte(base64.StdEncoding.EncodeToString(mac.Sum(nil)))) {
+		return errors.New("token mismatch")
+	}
+	ms, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid X-Gitee-Timestamp %q", ts)
+	}
+	if age := now().Sub(time.UnixMilli(ms)); age > maxSignatureAge || age < -maxSignatureAge {
+		return fmt.Errorf("X-Gitee-Timestamp %s is %v off", ts, age.Round(time.Second))
+	}
+	return nil
+}
+
+// ParseWebhook maps a delivery to a generic event. It returns an empty kind for
+// deliveries sync-bot does not handle.
+func ParseWebhook(header http.Header, payload []byte) (string, *client.GenericEvent, error) {
+	switch header.Get("X-Gitee-Event") {
+	case "Merge Request Hook":
+		var p pullRequestPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return "", nil, err
+		}
+		// updates of the title or the body are not interesting
+		if p.Action == "update" && p.ActionDesc != "source_branch_changed" {
+			return "", nil, nil
+		}
+		pr := p.PullRequest
+		number := strconv.Itoa(pr.Number)
+		state := prState(pr.State)
+		return platform.PullRequestEvent, &client.GenericEvent{
+			Action:  &p.Action,
+			Org:     &p.Repository.Namespace,
+			Repo:    &p.Repository.Path,
+			Number:  &number,
+			Base:    &pr.Base.Ref,
+			Head:    &pr.Head.Ref,
+			Title:   &pr.Title,
+			HtmlURL: &pr.HTMLURL,
+			State:   &state,
+		}, nil
+	case "Note Hook":
+		var p notePayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return "", nil, err
+		}
+		if p.Action != "comment" {
+			return "", nil, nil
+		}
+		if p.NoteableType == "Issue" {
+			return platform.IssueCommentEvent, &client.GenericEvent{
+				Action:    &p.Action,
+				Org:       &p.Repository.Namespace,
+				Repo:      &p.Repository.Path,
+				Number:    &p.Issue.Number,
+				Title:     &p.Issue.Title,
+				Comment:   &p.Comment.Body,
+				Commenter: &p.Comment.User.Login,
+				HtmlURL:   &p.Comment.HTMLURL,
+				State:     &p.Issue.State,
+			}, nil
+		}
+		// comments on commits are not handled
+		if p.NoteableType != "PullRequest" {
+			return "", nil, nil
+		}
+		pr := p.PullRequest
+		number := strconv.Itoa(pr.Number)
+		state := prState(pr.State)
+		return platform.PullRequestCommentEvent, &client.GenericEvent{
+			Action:    &p.Action,
+			Org:       &p.Repository.Namespace,
+			Repo:      &p.Repository.Path,
+			Number:    &number,
+			Base:      &pr.Base.Ref,
+			Head:      &pr.Head.Ref,
+			Title:     &pr.Title,
+			Comment:   &p.Comment.Body,
+			Commenter: &p.Comment.User.Login,
+			HtmlURL:   &p.Comment.HTMLURL,
+			State:     &state,
+		}, nil
+	default:
+		return "", nil, nil
+	}
+}
@@ -0,0 +1,143 @@
+package gitee
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sync-bot/platform"
+
+	"github.com/opensourceways/robot-framework-lib/utils"
+)
+
+// readFixture reads a delivery recorded in the tests directory.
+func readFixture(t *testing.T, name string) (http.Header, []byte) {
+	f, err := os.Open(filepath.Join("..", "tests", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := platform.ReadHTTPDelivery(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d.Header, d.Payload
+}
+
+func TestVerifySignature(t *testing.T) {
+	header, payload := readFixture(t, "open_pr.http")
+	if err := VerifySignature(header, payload, []byte("Secret")); err != nil {
+		t.Errorf("VerifySignature() error = %v", err)
+	}
+	if err := VerifySignature(header, payload, []byte("other")); err == nil {
+		t.Errorf("VerifySignature() accepted a wrong token")
+	}
+
+	signed := time.UnixMilli(1609838877057)
+	defer func(f func() time.Time) { now = f }(now)
+	now = func() time.Time { return signed.Add(time.Minute) }
+	mac := hmac.New(sha256.New, []byte("Secret"))
+	mac.Write([]byte("1609838877057\nSecret"))
+	header.Set("X-Gitee-Token", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	header.Set("X-Gitee-Timestamp", "1609838877057")
+	if err := VerifySignature(header, payload, []byte("Secret")); err != nil {
+		t.Errorf("VerifySignature() of a signed delivery error = %v", err)
+	}
+	now = func() time.Time { return signed.Add(maxSignatureAge + time.Second) }
+	if err := VerifySignature(header, payload, []byte("Secret")); err == nil {
+		t.Errorf("VerifySignature() accepted a stale signature")
+	}
+	now = func() time.Time { return signed.Add(-maxSignatureAge - time.Second) }
+	if err := VerifySignature(header, payload, []byte("Secret")); err == nil {
+		t.Errorf("VerifySignature() accepted a signature from the future")
+	}
+	now = func() time.Time { return signed }
+	header.Set("X-Gitee-Timestamp", "1609838877058")
+	if err := VerifySignature(header, payload, []byte("Secret")); err == nil {
+		t.Errorf("VerifySignature() accepted a wrong timestamp")
+	}
+
+	mac = hmac.New(sha256.New, nil)
+	mac.Write([]byte("1609838877057\n"))
+	header.Set("X-Gitee-Token", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	header.Set("X-Gitee-Timestamp", "1609838877057")
+	if err := VerifySignature(header, payload, nil); err == nil {
+		t.Errorf("VerifySignature() accepted a delivery signed with an empty secret")
+	}
+}
+
+func TestParseWebhook(t *testing.T) {
+	tests := []struct {
+		fixture  string
+		wantKind string
+		want     map[string]string
+	}{
+		{
+			fixture:  "open_pr.http",
+			wantKind: platform.PullRequestEvent,
+			want:     map[string]string{"action": "open", "org": "open-euler", "repo": "syncbot-example", "state": "opened"},
+		},
+		{
+			fixture:  "merge_pr.http",
+			wantKind: platform.PullRequestEvent,
+			want: map[string]string{"action": "merge", "org": "open-euler", "repo": "syncbot-example", "number": "23",
+				"base": "master", "head": "bar", "state": "merged"},
+		},
+		{
+			fixture:  "close_pr.http",
+			wantKind: platform.PullRequestEvent,
+			want:     map[string]string{"action": "close", "number": "16", "state": "closed"},
+		},
+		{
+			fixture:  "sync.http",
+			wantKind: platform.PullRequestCommentEvent,
+			want: map[string]string{"action": "comment", "org": "open-euler", "repo": "syncbot-example", "number": "23",
+				"comment": "/sync branch1 branch2", "commenter": "chenyanpanHW", "state": "opened"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			header, payload := readFixture(t, tt.fixture)
+			kind, evt, err := ParseWebhook(header, payload)
+			if err != nil {
+				t.Fatalf("ParseWebhook() error = %v", err)
+			}
+			if kind != tt.wantKind {
+				t.Fatalf("ParseWebhook() kind = %v, want %v", kind, tt.wantKind)
+			}
+			got := map[string]string{
+				"action": utils.GetString(evt.Action), "org": utils.GetString(evt.Org),
+				"repo": utils.GetString(evt.Repo), "number": utils.GetString(evt.Number),
+				"base": utils.GetString(evt.Base), "head": utils.GetString(evt.Head),
+				"comment": utils.GetString(evt.Comment), "commenter": utils.GetString(evt.Commenter),
+				"state": utils.GetString(evt.State),
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("ParseWebhook() %s = %v, want %v", k, got[k], v)
+				}
+			}
+		})
+	}
+
+	header := http.Header{}
+	header.Set("X-Gitee-Event", "Note Hook")
+	kind, evt, err := ParseWebhook(header, []byte(`{"action":"comment","noteable_type":"Issue",
+		"comment":{"body":"/sync-branch master stable","user":{"login":"alice"}},
+		"issue":{"number":"I8ABCD","state":"open"},"repository":{"namespace":"o","path":"r"}}`))
+	if err != nil || kind != platform.IssueCommentEvent || utils.GetString(evt.Number) != "I8ABCD" {
+		t.Errorf("ParseWebhook() of an issue comment = %v, %v, %v", kind, evt, err)
+	}
+
+	header = http.Header{}
+	header.Set("X-Gitee-Event", "Merge Request Hook")
+	kind, _, err = ParseWebhook(header, []byte(`{"action":"update","action_desc":"title_changed"}`))
+	if err != nil || kind != "" {
+		t.Errorf("ParseWebhook() of a title update = %v, %v, want ignored", kind, err)
+	}
+}
@@ -0,0 +1,533 @@
+// Package github implements the platform client of sync-bot on the GitHub REST API.
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"sync-bot/platform"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// closingIssueRegex matches the keywords linking a pull request to the issues it closes, like "Fixes #12"
+var closingIssueRegex = regexp.MustCompile(`(?i)\b(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?)\s+#(\d+)\b`)
+
+type user struct {
+	Login string `json:"login"`
+}
+
+type branchRef struct {
+	Ref string `json:"ref"`
+	SHA string `json:"sha"`
+}
+
+type pullRequest struct {
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	HTMLURL   string    `json:"html_url"`
+	State     string    `json:"state"`
+	Merged    bool      `json:"merged"`
+	MergedAt  *string   `json:"merged_at"`
+	Mergeable *bool     `json:"mergeable"`
+	Head      branchRef `json:"head"`
+	Base      branchRef `json:"base"`
+	User      user      `json:"user"`
+}
+
+type issue struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+}
+
+type comment struct {
+	ID      int64  `json:"id"`
+	Body    string `json:"body"`
+	HTMLURL string `json:"html_url"`
+	User    user   `json:"user"`
+}
+
+type label struct {
+	Name string `json:"name"`
+}
+
+type commit struct {
+	SHA     string `json:"sha"`
+	HTMLURL string `json:"html_url"`
+	Commit  struct {
+		Message   string `json:"message"`
+		Committer struct {
+			Date string `json:"date"`
+		} `json:"committer"`
+	} `json:"commit"`
+}
+
+// Client is a platform client of GitHub. Create with NewClient.
+type Client struct {
+	api *platform.API
+	log *logrus.Entry
+}
+
+// NewClient returns a client of the GitHub REST API at apiBase authenticated with token.
+func NewClient(token []byte, apiBase string, logger *logrus.Entry) *Client {
+	t := string(token)
+	return &Client{
+		api: platform.NewAPI(apiBase, func(req *http.Request) {
+			req.Header.Set("Accept", "application/vnd.github+json")
+			req.Header.Set("Authorization", "Bearer "+t)
+		}),
+		log: logger,
+	}
+}
+
+func repoPath(org, repo string) string {
+	return fmt.Sprintf("/repos/%s/%s", url.PathEscape(org), url.PathEscape(repo))
+}
+
+// prState maps the state of a pull request to the one of the generic events.
+func prState(state string, merged bool) string {
+	switch {
+	case merged:
+		return "merged"
+	case state == "open":
+		return "opened"
+	default:
+		return state
+	}
+}
+
+// GetPullRequest gets a pull request in a specified organization and repository
+func (c *Client) GetPullRequest(org, repo, number string) (result client.PullRequest, success bool) {
+	var pr pullRequest
+	if err := c.api.Do(http.MethodGet, repoPath(org, repo)+"/pulls/"+number, nil, nil, &pr); err != nil {
+		c.log.WithError(err).Errorf("Get pull request %s/%s#%s failed", org, repo, number)
+		return
+	}
+	return toPullRequest(&pr), true
+}
+
+// ListOpenPullRequests lists the pull requests open on a repository
+func (c *Client) ListOpenPullRequests(org, repo string) (result []client.PullRequest, success bool) {
+	prs, err := platform.GetAll[pullRequest](c.api, repoPath(org, repo)+"/pulls", url.Values{"state": {"open"}})
+	if err != nil {
+		c.log.WithError(err).Errorf("List open pull requests of %s/%s failed", org, repo)
+		return
+	}
+	for i := range prs {
+		result = append(result, toPullRequest(&prs[i]))
+	}
+	return result, true
+}
+
+func toPullRequest(pr *pullRequest) client.PullRequest {
+	num := strconv.Itoa(pr.Number)
+	return client.PullRequest{
+		Number:    &num,
+		Title:     &pr.Title,
+		Body:      &pr.Body,
+		Head:      &pr.Head.Ref,
+		Base:      &pr.Base.Ref,
+		URL:       &pr.HTMLURL,
+		State:     &pr.State,
+		MergeAble: pr.Mergeable,
+	}
+}
+
+// GetAuthenticatedUser gets the login of the user the token belongs to
+func (c *Client) GetAuthenticatedUser() (login string, success bool) {
+	var u user
+	if err := c.api.Do(http.MethodGet, "/user", nil, nil, &u); err != nil {
+		c.log.WithError(err).Errorln("Get the authenticated user failed")
+		return
+	}
+	return u.Login, true
+}
+
+// GetPullRequestAuthor gets the login of the user who opened a pull request
+func (c *Client) GetPullRequestAuthor(org, repo, number string) (login string, success bool) {
+	var pr pullRequest
+	if err := c.api.Do(http.MethodGet, repoPath(org, repo)+"/pulls/"+number, nil, nil, &pr); err != nil {
+		c.log.WithError(err).Errorf("Get pull request %s/%s#%s failed", org, repo, number)
+		return
+	}
+	return pr.User.Login, true
+}
+
+// GetPathContent gets the base64 encoded content of a file on branch
+func (c *Client) GetPathContent(org, repo, path, branch string) (result client.RepoContent, success bool) {
+	var content struct {
+		Content string `json:"content"`
+	}
+	query := url.Values{"ref": {branch}}
+	if err := c.api.Do(http.MethodGet, repoPath(org, repo)+"/contents/"+path, query, nil, &content); err != nil {
+		c.log.WithError(err).Errorf("Get content of %s/%s:%s on %s failed", org, repo, path, branch)
+		return
+	}
+	// GitHub wraps the base64 content into lines
+	s := strings.ReplaceAll(content.Content, "\n", "")
+	return client.RepoContent{Content: &s}, true
+}
+
+// GetRepoAllBranch lists all branches of a repository
+func (c *Client) GetRepoAllBranch(org, repo string) (result []client.Branch, success bool) {
+	branches, err := platform.GetAll[struct {
+		Name string `json:"name"`
+	}](c.api, repoPath(org, repo)+"/branches", nil)
+	if err != nil {
+		c.log.WithError(err).Errorf("List branches of %s/%s failed", org, repo)
+		return
+	}
+	for _, b := range branches {
+		result = append(result, client.Branch{Name: b.Name})
+	}
+	return result, true
+}
+
+// ListProtectedBranches lists the protected branches of a repository
+func (c *Client) ListProtectedBranches(org, repo string) (result []string, success bool) {
+	branches, err := platform.GetAll[struct {
+		Name string `json:"name"`
+	}](c.api, repoPath(org, repo)+"/branches", url.Values{"protected": {"true"}})
+	if err != nil {
+		c.log.WithError(err).Errorf("List protected branches of %s/%s failed", org, repo)
+		return
+	}
+	for _, b := range branches {
+		result = append(result, b.Name)
+	}
+	return result, true
+}
+
+// ListOrgRepos lists the names of the repositories of an organization
+func (c *Client) ListOrgRepos(org string) (result []string, success bool) {
+	repos, err := platform.GetAll[struct {
+		Name string `json:"name"`
+	}](c.api, "/orgs/"+url.PathEscape(org)+"/repos", nil)
+	if err != nil {
+		c.log.WithError(err).Errorf("List repositories of %s failed", org)
+		return
+	}
+	for _, r := range repos {
+		result = append(result, r.Name)
+	}
+	return result, true
+}
+
+// ListPullRequestComments lists the comments of a pull request, the latest first
+func (c *Client) ListPullRequestComments(org, repo, number string) (result []client.PRComment, success bool) {
+	comments, ok := c.ListPullRequestCommentsWithID(org, repo, number)
+	for _, cm := range comments {
+		result = append(result, client.PRComment{Commenter: cm.Commenter, Body: cm.Body})
+	}
+	return result, ok
+}
+
+// ListPullRequestCommentsWithID lists the comments of a pull request with their ids, the latest first
+func (c *Client) ListPullRequestCommentsWithID(org, repo, number string) (result []platform.Comment, success bool) {
+	comments, err := platform.GetAll[comment](c.api, repoPath(org, repo)+"/issues/"+number+"/comments", nil)
+	if err != nil {
+		c.log.WithError(err).Errorf("List comments of %s/%s#%s failed", org, repo, number)
+		return
+	}
+	for i := len(comments) - 1; i >= 0; i-- {
+		result = append(result, platform.Comment{
+			ID:        strconv.FormatInt(comments[i].ID, 10),
+			Commenter: comments[i].User.Login,
+			Body:      comments[i].Body,
+		})
+	}
+	return result, true
+}
+
+// EditPRComment replaces the body of the comment commentID of a pull request
+func (c *Client) EditPRComment(org, repo, number, commentID, comment string) (success bool) {
+	body := map[string]string{"body": comment}
+	if err := c.api.Do(http.MethodPatch, repoPath(org, repo)+"/issues/comments/"+commentID, nil, body, nil); err != nil {
+		c.log.WithError(err).Errorf("Edit comment %s on %s/%s#%s failed", commentID, org, repo, number)
+		return false
+	}
+	return true
+}
+
+// ListIssueCommentsWithID lists the comments of an issue with their ids, the
+// latest first. The comments of pull requests are those of issues on GitHub.
+func (c *Client) ListIssueCommentsWithID(org, repo, number string) (result []platform.Comment, success bool) {
+	return c.ListPullRequestCommentsWithID(org, repo, number)
+}
+
+// EditIssueComment replaces the body of the comment commentID of an issue
+func (c *Client) EditIssueComment(org, repo, number, commentID, comment string) (success bool) {
+	return c.EditPRComment(org, repo, number, commentID, comment)
+}
+
+// RequestPRReviewers requests the reviews of the users on a pull request
+func (c *Client) RequestPRReviewers(org, repo, number string, reviewers []string) (success bool) {
+	body := map[string][]string{"reviewers": reviewers}
+	path := repoPath(org, repo) + "/pulls/" + number + "/requested_reviewers"
+	if err := c.api.Do(http.MethodPost, path, nil, body, nil); err != nil {
+		c.log.WithError(err).Errorf("Request reviewers of %s/%s#%s failed", org, repo, number)
+		return false
+	}
+	return true
+}
+
+// GetPullRequestReviewers gets the users who reviewed a pull request or whose
+// reviews are requested
+func (c *Client) GetPullRequestReviewers(org, repo, number string) (result []string, success bool) {
+	reviews, err := platform.GetAll[struct {
+		User user `json:"user"`
+	}](c.api, repoPath(org, repo)+"/pulls/"+number+"/reviews", nil)
+	if err != nil {
+		c.log.WithError(err).Errorf("List reviews of %s/%s#%s failed", org, repo, number)
+		return
+	}
+	var requested struct {
+		Users []user `json:"users"`
+	}
+	path := repoPath(org, repo) + "/pulls/" + number + "/requested_reviewers"
+	if err = c.api.Do(http.MethodGet, path, nil, nil, &requested); err != nil {
+		c.log.WithError(err).Errorf("List requested reviewers of %s/%s#%s failed", org, repo, number)
+		return
+	}
+	users := requested.Users
+	for _, r := range reviews {
+		users = append(users, r.User)
+	}
+	seen := make(map[string]bool)
+	for _, u := range users {
+		if !seen[u.Login] {
+			seen[u.Login] = true
+			result = append(result, u.Login)
+		}
+	}
+	return result, true
+}
+
+// AssignPR assigns the users to a pull request
+func (c *Client) AssignPR(org, repo, number string, assignees []string) (success bool) {
+	body := map[string][]string{"assignees": assignees}
+	if err := c.api.Do(http.MethodPost, repoPath(org, repo)+"/issues/"+number+"/assignees", nil, body, nil); err != nil {
+		c.log.WithError(err).Errorf("Assign %s/%s#%s failed", org, repo, number)
+		return false
+	}
+	return true
+}
+
+// GetPRLinkedIssue gets the issues closed by a pull request through the keywords in its body
+func (c *Client) GetPRLinkedIssue(org, repo, number string) (result []client.Issue, success bool) {
+	var pr pullRequest
+	if err := c.api.Do(http.MethodGet, repoPath(org, repo)+"/pulls/"+number, nil, nil, &pr); err != nil {
+		c.log.WithError(err).Errorf("Get pull request %s/%s#%s failed", org, repo, number)
+		return
+	}
+	seen := make(map[string]bool)
+	for _, match := range closingIssueRegex.FindAllStringSubmatch(pr.Body, -1) {
+		if seen[match[1]] {
+			continue
+		}
+		seen[match[1]] = true
+		var is issue
+		if err := c.api.Do(http.MethodGet, repoPath(org, repo)+"/issues/"+match[1], nil, nil, &is); err != nil {
+			c.log.WithError(err).Warnf("Get issue %s/%s#%s failed", org, repo, match[1])
+			continue
+		}
+		result = append(result, client.Issue{HtmlURL: is.HTMLURL})
+	}
+	return result, true
+}
+
+// CreatePRComment comments on a pull request
+func (c *Client) CreatePRComment(org, repo, number, comment string) (success bool) {
+	return c.CreateIssueComment(org, repo, number, comment)
+}
+
+// CreateIssueComment comments on an issue
+func (c *Client) CreateIssueComment(org, repo, number, comment string) (success bool) {
+	body := map[string]string{"body": comment}
+	if err := c.api.Do(http.MethodPost, repoPath(org, repo)+"/issues/"+number+"/comments", nil, body, nil); err != nil {
+		c.log.WithError(err).Errorf("Comment on %s/%s#%s failed", org, repo, number)
+		return false
+	}
+	return true
+}
+
+// AddIssueLabels adds labels to an issue
+func (c *Client) AddIssueLabels(org, repo, number string, labels []string) (success bool) {
+	body := map[string][]string{"labels": labels}
+	if err := c.api.Do(http.MethodPost, repoPath(org, repo)+"/issues/"+number+"/labels", nil, body, nil); err != nil {
+		c.log.WithError(err).Errorf("Add labels %v to %s/%s#%s failed", labels, org, repo, number)
+		return false
+	}
+	return true
+}
+
+// RemoveIssueLabels removes labels from an issue
+func (c *Client) RemoveIssueLabels(org, repo, number string, labels []string) (success bool) {
+	success = true
+	for _, l := range labels {
+		path := repoPath(org, repo) + "/issues/" + number + "/labels/" + url.PathEscape(l)
+		if err := c.api.Do(http.MethodDelete, path, nil, nil, nil); err != nil && !platform.IsNotFound(err) {
+			c.log.WithError(err).Errorf("Remove label %s from %s/%s#%s failed", l, org, repo, number)
+			success = false
+		}
+	}
+	return success
+}
+
+// AddPRLabels adds labels to a pull request
+func (c *Client) AddPRLabels(org, repo, number string, labels []string) (success bool) {
+	return c.AddIssueLabels(org, repo, number, labels)
+}
+
+// RemovePRLabels removes labels from a pull request
+func (c *Client) RemovePRLabels(org, repo, number string, labels []string) (success bool) {
+	return c.RemoveIssueLabels(org, repo, number, labels)
+}
+
+// GetPullRequestCommits lists the commits of a pull request, the latest first
+func (c *Client) GetPullRequestCommits(org, repo, number string) (result []client.PRCommit, success bool) {
+	commits, err := platform.GetAll[commit](c.api, repoPath(org, repo)+"/pulls/"+number+"/commits", nil)
+	if err != nil {
+		c.log.WithError(err).Errorf("List commits of %s/%s#%s failed", org, repo, number)
+		return
+	}
+	for i := len(commits) - 1; i >= 0; i-- {
+		result = append(result, client.PRCommit{
+			SHA:        commits[i].SHA,
+			HTMLURL:    commits[i].HTMLURL,
+			CommitTime: commits[i].Commit.Committer.Date,
+			Message:    commits[i].Commit.Message,
+		})
+	}
+	return result, true
+}
+
+// GetPullRequestLabels lists the labels of a pull request
+func (c *Client) GetPullRequestLabels(org, repo, number string) (result []string, success bool) {
+	return c.listLabels(repoPath(org, repo) + "/issues/" + number + "/labels")
+}
+
+// GetIssueLabels is not supported, issues of GitHub can't be found without their repository
+func (c *Client) GetIssueLabels(org, issueID string) (result []string, success bool) {
+	c.log.Errorf("Get labels of issue %s/%s failed: GitHub issues need a repository", org, issueID)
+	return nil, false
+}
+
+// GetRepoIssueLabels lists the labels of a repository
+func (c *Client) GetRepoIssueLabels(org, repo string) (result []string, success bool) {
+	return c.listLabels(repoPath(org, repo) + "/labels")
+}
+
+func (c *Client) listLabels(path string) (result []string, success bool) {
+	labels, err := platform.GetAll[label](c.api, path, nil)
+	if err != nil {
+		c.log.WithError(err).Errorf("List labels of %s failed", path)
+		return
+	}
+	for _, l := range labels {
+		result = append(result, l.Name)
+	}
+	return result, true
+}
+
+// CheckPermissionWithBranch checks if the user can push to the repository
+func (c *Client) CheckPermissionWithBranch(org, repo, username, branch string) (pass, success bool) {
+	var perm struct {
+		Permission string `json:"permission"`
+	}
+	path := repoPath(org, repo) + "/collaborators/" + url.PathEscape(username) + "/permission"
+	if err := c.api.Do(http.MethodGet, path, nil, nil, &perm); err != nil {
+		c.log.WithError(err).Errorf("Get permission of %s on %s/%s failed", username, org, repo)
+		return false, false
+	}
+	switch perm.Permission {
+	case "admin", "maintain", "write":
+		return true, true
+	default:
+		return false, true
+	}
+}
+
+// GetPullRequestChanges lists the files changed by a pull request
+func (c *Client) GetPullRequestChanges(org, repo, number string) (result []client.CommitFile, success bool) {
+	files, err := platform.GetAll[struct {
+		Filename string `json:"filename"`
+	}](c.api, repoPath(org, repo)+"/pulls/"+number+"/files", nil)
+	if err != nil {
+		c.log.WithError(err).Errorf("List files of %s/%s#%s failed", org, repo, number)
+		return
+	}
+	for _, f := range files {
+		result = append(result, client.CommitFile{Filename: f.Filename})
+	}
+	return result, true
+}
+
+// CreatePR creates a pull request, the head of a fork is given as "owner:branch".
+// GitHub can't prune the source branch of a single pull request, PruneSourceBranch
+// is ignored and the branch is left to the caller.
+func (c *Client) CreatePR(org, repo string, prContent client.PullRequest) (number string, success bool) {
+	body := map[string]string{
+		"title": utils.GetString(prContent.Title),
+		"body":  utils.GetString(prContent.Body),
+		"head":  utils.GetString(prContent.Head),
+		"base":  utils.GetString(prContent.Base),
+	}
+	var pr pullRequest
+	if err := c.api.Do(http.MethodPost, repoPath(org, repo)+"/pulls", nil, body, &pr); err != nil {
+		c.log.WithError(err).Errorf("Create pull request %s -> %s on %s/%s failed", body["head"], body["base"], org, repo)
+		return "", false
+	}
+	return strconv.Itoa(pr.Number), true
+}
+
+// CreateRepoBranch creates branch from the head of createFrom
+func (c *Client) CreateRepoBranch(org, repo, createFrom, branch string) (success bool) {
+	var from struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	if err := c.api.Do(http.MethodGet, repoPath(org, repo)+"/git/ref/heads/"+createFrom, nil, nil, &from); err != nil {
+		c.log.WithError(err).Errorf("Get branch %s of %s/%s failed", createFrom, org, repo)
+		return false
+	}
+	body := map[string]string{"ref": "refs/heads/" + branch, "sha": from.Object.SHA}
+	if err := c.api.Do(http.MethodPost, repoPath(org, repo)+"/git/refs", nil, body, nil); err != nil {
+		c.log.WithError(err).Errorf("Create branch %s of %s/%s failed", branch, org, repo)
+		return false
+	}
+	return true
+}
+
+// CheckIfPRCreateEvent checks if the event opens a pull request
+func (c *Client) CheckIfPRCreateEvent(evt *client.GenericEvent) (yes bool) {
+	return utils.GetString(evt.Action) == "opened"
+}
+
+// CheckIfPRReopenEvent checks if the event reopens a pull request
+func (c *Client) CheckIfPRReopenEvent(evt *client.GenericEvent) (yes bool) {
+	return utils.GetString(evt.Action) == "reopened"
+}
+
+// CheckIfPRMergeEvent checks if the event merges a pull request
+func (c *Client) CheckIfPRMergeEvent(evt *client.GenericEvent) (yes bool) {
+	return utils.GetString(evt.Action) == "closed" && utils.GetString(evt.State) == "merged"
+}
+
+// CheckIfPRCloseEvent checks if the event closes a pull request without merging it
+func (c *Client) CheckIfPRCloseEvent(evt *client.GenericEvent) (yes bool) {
+	return utils.GetString(evt.Action) == "closed" && utils.GetString(evt.State) != "merged"
+}
+
+// CheckIfPRSourceCodeUpdateEvent checks if the event pushes to the head of a pull request
+func (c *Client) CheckIfPRSourceCodeUpdateEvent(evt *client.GenericEvent) (yes bool) {
+	return utils.GetString(evt.Action) == "synchronize"
+}
@@ -0,0 +1,198 @@
+package github
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return NewClient([]byte("token"), srv.URL, logrus.NewEntry(logrus.New()))
+}
+
+func TestGetPullRequest(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/openEuler/kernel/pulls/7" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(w, `{"number":7,"title":"fix","body":"Fixes #3","html_url":"https://github.com/openEuler/kernel/pull/7",
+			"mergeable":true,"head":{"ref":"feature"},"base":{"ref":"master"}}`)
+	})
+
+	pr, ok := c.GetPullRequest("openEuler", "kernel", "7")
+	if !ok {
+		t.Fatalf("GetPullRequest() failed")
+	}
+	got := []string{utils.GetString(pr.Number), utils.GetString(pr.Title), utils.GetString(pr.Head),
+		utils.GetString(pr.Base), utils.GetString(pr.URL)}
+	want := []string{"7", "fix", "feature", "master", "https://github.com/openEuler/kernel/pull/7"}
+	if !reflect.DeepEqual(got, want) || !utils.GetBool(pr.MergeAble) {
+		t.Errorf("GetPullRequest() = %v, want %v", got, want)
+	}
+
+	if _, ok = c.GetPullRequest("openEuler", "kernel", "8"); ok {
+		t.Errorf("GetPullRequest() of a missing pull request succeeded")
+	}
+}
+
+func TestGetAuthenticatedUser(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(w, `{"login":"sync-bot"}`)
+	})
+	if login, ok := c.GetAuthenticatedUser(); !ok || login != "sync-bot" {
+		t.Errorf("GetAuthenticatedUser() = %v, %v, want sync-bot", login, ok)
+	}
+}
+
+func TestListOpenPullRequests(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/openEuler/kernel/pulls" || r.URL.Query().Get("state") != "open" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(w, `[{"number":9,"state":"open","head":{"ref":"sync-pr7-feature-to-stable"},"base":{"ref":"stable"}},
+			{"number":8,"state":"open","head":{"ref":"feature"},"base":{"ref":"master"}}]`)
+	})
+
+	prs, ok := c.ListOpenPullRequests("openEuler", "kernel")
+	if !ok {
+		t.Fatalf("ListOpenPullRequests() failed")
+	}
+	var got []string
+	for _, pr := range prs {
+		got = append(got, utils.GetString(pr.Number)+":"+utils.GetString(pr.Head)+":"+utils.GetString(pr.State))
+	}
+	want := []string{"9:sync-pr7-feature-to-stable:open", "8:feature:open"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListOpenPullRequests() = %v, want %v", got, want)
+	}
+}
+
+func TestListProtectedBranches(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/openEuler/kernel/branches" && r.URL.Query().Get("protected") == "true":
+			_, _ = io.WriteString(w, `[{"name":"master"},{"name":"stable"}]`)
+		case r.URL.Path == "/orgs/openEuler/repos":
+			_, _ = io.WriteString(w, `[{"name":"kernel"},{"name":"bash"}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	if branches, ok := c.ListProtectedBranches("openEuler", "kernel"); !ok || !reflect.DeepEqual(branches, []string{"master", "stable"}) {
+		t.Errorf("ListProtectedBranches() = %v, %v, want master and stable", branches, ok)
+	}
+	if repos, ok := c.ListOrgRepos("openEuler"); !ok || !reflect.DeepEqual(repos, []string{"kernel", "bash"}) {
+		t.Errorf("ListOrgRepos() = %v, %v, want kernel and bash", repos, ok)
+	}
+}
+
+func TestGetPullRequestCommits(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `[{"sha":"1111"},{"sha":"2222"},{"sha":"3333"}]`)
+	})
+
+	commits, ok := c.GetPullRequestCommits("openEuler", "kernel", "7")
+	if !ok {
+		t.Fatalf("GetPullRequestCommits() failed")
+	}
+	var got []string
+	for _, commit := range commits {
+		got = append(got, commit.SHA)
+	}
+	// the latest commit first, like the other platforms
+	if want := []string{"3333", "2222", "1111"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetPullRequestCommits() = %v, want %v", got, want)
+	}
+}
+
+func TestGetPRLinkedIssue(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/openEuler/kernel/pulls/7":
+			_, _ = io.WriteString(w, `{"number":7,"body":"Fixes #3, closes #4 and fixes #3 again. See #5."}`)
+		case "/repos/openEuler/kernel/issues/3", "/repos/openEuler/kernel/issues/4":
+			_, _ = io.WriteString(w, `{"html_url":"https://github.com`+r.URL.Path[len("/repos"):]+`"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	issues, ok := c.GetPRLinkedIssue("openEuler", "kernel", "7")
+	if !ok {
+		t.Fatalf("GetPRLinkedIssue() failed")
+	}
+	var got []string
+	for _, is := range issues {
+		got = append(got, is.HtmlURL)
+	}
+	want := []string{"https://github.com/openEuler/kernel/issues/3", "https://github.com/openEuler/kernel/issues/4"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetPRLinkedIssue() = %v, want %v", got, want)
+	}
+}
+
+func TestCreatePR(t *testing.T) {
+	var body map[string]string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/openEuler/kernel/pulls" {
+			t.Errorf("CreatePR() sent %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{"number":9}`)
+	})
+
+	title, head, base, prune := "[sync] PR-7: fix", "bot:sync-pr7-feature-to-stable", "stable", true
+	number, ok := c.CreatePR("openEuler", "kernel", client.PullRequest{Title: &title, Head: &head, Base: &base,
+		PruneSourceBranch: &prune})
+	if !ok || number != "9" {
+		t.Fatalf("CreatePR() = %v, %v", number, ok)
+	}
+	want := map[string]string{"title": title, "body": "", "head": head, "base": base}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("CreatePR() sent %v, want %v", body, want)
+	}
+}
+
+func TestRequestPRReviewers(t *testing.T) {
+	var body map[string][]string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/openeuler/kernel/pulls/24/requested_reviewers" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if !c.RequestPRReviewers("openeuler", "kernel", "24", []string{"alice", "bob"}) {
+		t.Fatalf("RequestPRReviewers() failed")
+	}
+	if want := map[string][]string{"reviewers": {"alice", "bob"}}; !reflect.DeepEqual(body, want) {
+		t.Errorf("RequestPRReviewers() sent %v, want %v", body, want)
+	}
+}
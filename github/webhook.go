@@ -0,0 +1,122 @@
+package github
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"sync-bot/platform"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+)
+
+type repository struct {
+	Name  string `json:"name"`
+	Owner user   `json:"owner"`
+}
+
+type pullRequestPayload struct {
+	Action      string      `json:"action"`
+	PullRequest pullRequest `json:"pull_request"`
+	Repository  repository  `json:"repository"`
+}
+
+type issueCommentPayload struct {
+	Action string `json:"action"`
+	Issue  struct {
+		Number      int    `json:"number"`
+		Title       string `json:"title"`
+		State       string `json:"state"`
+		HTMLURL     string `json:"html_url"`
+		PullRequest *struct {
+			MergedAt *string `json:"merged_at"`
+		} `json:"pull_request"`
+	} `json:"issue"`
+	Comment    comment    `json:"comment"`
+	Repository repository `json:"repository"`
+}
+
+// VerifySignature checks the X-Hub-Signature-256 header of a delivery against secret.
+func VerifySignature(header http.Header, payload, secret []byte) error {
+	if len(secret) == 0 {
+		return errors.New("empty secret")
+	}
+	sig := strings.TrimPrefix(header.Get("X-Hub-Signature-256"), "sha256=")
+	want, err := hex.DecodeString(sig)
+	if err != nil || sig == "" {
+		return errors.New("missing or malformed X-Hub-Signature-256")
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	if !hmac.Equal(mac.Sum(nil), want) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+// ParseWebhook maps a delivery to a generic event. It returns an empty kind for
+// deliveries sync-bot does not handle.
+func ParseWebhook(header http.Header, payload []byte) (string, *client.GenericEvent, error) {
+	switch header.Get("X-GitHub-Event") {
+	case "pull_request":
+		var p pullRequestPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return "", nil, err
+		}
+		pr := p.PullRequest
+		number := strconv.Itoa(pr.Number)
+		state := prState(pr.State, pr.Merged || pr.MergedAt != nil)
+		return platform.PullRequestEvent, &client.GenericEvent{
+			Action:  &p.Action,
+			Org:     &p.Repository.Owner.Login,
+			Repo:    &p.Repository.Name,
+			Number:  &number,
+			Base:    &pr.Base.Ref,
+			Head:    &pr.Head.Ref,
+			Title:   &pr.Title,
+			HtmlURL: &pr.HTMLURL,
+			State:   &state,
+		}, nil
+	case "issue_comment":
+		var p issueCommentPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return "", nil, err
+		}
+		if p.Action != "created" {
+			return "", nil, nil
+		}
+		number := strconv.Itoa(p.Issue.Number)
+		if p.Issue.PullRequest == nil {
+			return platform.IssueCommentEvent, &client.GenericEvent{
+				Action:    &p.Action,
+				Org:       &p.Repository.Owner.Login,
+				Repo:      &p.Repository.Name,
+				Number:    &number,
+				Title:     &p.Issue.Title,
+				Comment:   &p.Comment.Body,
+				Commenter: &p.Comment.User.Login,
+				HtmlURL:   &p.Comment.HTMLURL,
+				State:     &p.Issue.State,
+			}, nil
+		}
+		state := prState(p.Issue.State, p.Issue.PullRequest.MergedAt != nil)
+		return platform.PullRequestCommentEvent, &client.GenericEvent{
+			Action:    &p.Action,
+			Org:       &p.Repository.Owner.Login,
+			Repo:      &p.Repository.Name,
+			Number:    &number,
+			Title:     &p.Issue.Title,
+			Comment:   &p.Comment.Body,
+			Commenter: &p.Comment.User.Login,
+			HtmlURL:   &p.Comment.HTMLURL,
+			State:     &state,
+		}, nil
+	default:
+		return "", nil, nil
+	}
+}
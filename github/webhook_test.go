@@ -0,0 +1,121 @@
+package github
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"testing"
+
+	"sync-bot/platform"
+
+	"github.com/opensourceways/robot-framework-lib/utils"
+)
+
+func TestVerifySignature(t *testing.T) {
+	payload := []byte(`{"action":"opened"}`)
+	header := http.Header{}
+	header.Set("X-Hub-Signature-256", "sha256="+sign(payload, []byte("other")))
+	if err := VerifySignature(header, payload, []byte("secret")); err == nil {
+		t.Errorf("VerifySignature() accepted a wrong signature")
+	}
+	header.Set("X-Hub-Signature-256", "sha256="+sign(payload, []byte("secret")))
+	if err := VerifySignature(header, payload, []byte("secret")); err != nil {
+		t.Errorf("VerifySignature() error = %v", err)
+	}
+	header.Del("X-Hub-Signature-256")
+	if err := VerifySignature(header, payload, []byte("secret")); err == nil {
+		t.Errorf("VerifySignature() accepted a delivery without signature")
+	}
+	header.Set("X-Hub-Signature-256", "sha256="+sign(payload, nil))
+	if err := VerifySignature(header, payload, nil); err == nil {
+		t.Errorf("VerifySignature() accepted a delivery signed with an empty secret")
+	}
+}
+
+func TestParseWebhook(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    string
+		payload  string
+		wantKind string
+		want     map[string]string
+	}{
+		{
+			name:  "merged pull request",
+			event: "pull_request",
+			payload: `{"action":"closed","pull_request":{"number":7,"title":"fix","state":"closed","merged":true,
+				"html_url":"https://github.com/o/r/pull/7","head":{"ref":"feature"},"base":{"ref":"master"}},
+				"repository":{"name":"r","owner":{"login":"o"}}}`,
+			wantKind: platform.PullRequestEvent,
+			want: map[string]string{"action": "closed", "org": "o", "repo": "r", "number": "7",
+				"base": "master", "head": "feature", "state": "merged"},
+		},
+		{
+			name:  "comment on open pull request",
+			event: "issue_comment",
+			payload: `{"action":"created","issue":{"number":7,"state":"open","pull_request":{"merged_at":null}},
+				"comment":{"body":"/sync stable","user":{"login":"alice"}},
+				"repository":{"name":"r","owner":{"login":"o"}}}`,
+			wantKind: platform.PullRequestCommentEvent,
+			want: map[string]string{"action": "created", "org": "o", "repo": "r", "number": "7",
+				"comment": "/sync stable", "commenter": "alice", "state": "opened"},
+		},
+		{
+			name:  "comment on issue",
+			event: "issue_comment",
+			payload: `{"action":"created","issue":{"number":8,"state":"open"},
+				"comment":{"body":"/sync-branch master stable","user":{"login":"alice"}},
+				"repository":{"name":"r","owner":{"login":"o"}}}`,
+			wantKind: platform.IssueCommentEvent,
+			want: map[string]string{"org": "o", "repo": "r", "number": "8",
+				"comment": "/sync-branch master stable", "commenter": "alice"},
+		},
+		{
+			name:     "edited comment",
+			event:    "issue_comment",
+			payload:  `{"action":"edited","issue":{"number":8,"state":"open"}}`,
+			wantKind: "",
+		},
+		{
+			name:     "push",
+			event:    "push",
+			payload:  `{}`,
+			wantKind: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			header.Set("X-GitHub-Event", tt.event)
+			kind, evt, err := ParseWebhook(header, []byte(tt.payload))
+			if err != nil {
+				t.Fatalf("ParseWebhook() error = %v", err)
+			}
+			if kind != tt.wantKind {
+				t.Fatalf("ParseWebhook() kind = %v, want %v", kind, tt.wantKind)
+			}
+			if kind == "" {
+				return
+			}
+			got := map[string]string{
+				"action": utils.GetString(evt.Action), "org": utils.GetString(evt.Org),
+				"repo": utils.GetString(evt.Repo), "number": utils.GetString(evt.Number),
+				"base": utils.GetString(evt.Base), "head": utils.GetString(evt.Head),
+				"comment": utils.GetString(evt.Comment), "commenter": utils.GetString(evt.Commenter),
+				"state": utils.GetString(evt.State),
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("ParseWebhook() %s = %v, want %v", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func sign(payload, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
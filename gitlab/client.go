@@ -0,0 +1,578 @@
+// Package gitlab implements the platform client of sync-bot on the GitLab v4 API.
+package gitlab
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"sync-bot/platform"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// developerAccess is the lowest access level allowed to push to a project.
+const developerAccess = 30
+
+type user struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+}
+
+type mergeRequest struct {
+	IID          int      `json:"iid"`
+	Title        string   `json:"title"`
+	Description  string   `json:"description"`
+	WebURL       string   `json:"web_url"`
+	State        string   `json:"state"`
+	MergeStatus  string   `json:"merge_status"`
+	SourceBranch string   `json:"source_branch"`
+	TargetBranch string   `json:"target_branch"`
+	Labels       []string `json:"labels"`
+	Author       user     `json:"author"`
+	Reviewers    []user   `json:"reviewers"`
+}
+
+type note struct {
+	ID     int64  `json:"id"`
+	Body   string `json:"body"`
+	System bool   `json:"system"`
+	Author user   `json:"author"`
+}
+
+type commit struct {
+	ID            string `json:"id"`
+	Message       string `json:"message"`
+	WebURL        string `json:"web_url"`
+	CommittedDate string `json:"committed_date"`
+}
+
+// Client is a platform client of GitLab. Create with NewClient.
+type Client struct {
+	api *platform.API
+	log *logrus.Entry
+}
+
+// NewClient returns a client of the GitLab v4 API at apiBase authenticated with token.
+func NewClient(token []byte, apiBase string, logger *logrus.Entry) *Client {
+	t := string(token)
+	return &Client{
+		api: platform.NewAPI(apiBase, func(req *http.Request) {
+			req.Header.Set("PRIVATE-TOKEN", t)
+		}),
+		log: logger,
+	}
+}
+
+// projectPath returns the API path of a project, org may hold subgroups.
+func projectPath(org, repo string) string {
+	return "/projects/" + url.PathEscape(org+"/"+repo)
+}
+
+func mrPath(org, repo, number string) string {
+	return projectPath(org, repo) + "/merge_requests/" + number
+}
+
+func (c *Client) getMergeRequest(org, repo, number string) (*mergeRequest, error) {
+	var mr mergeRequest
+	if err := c.api.Do(http.MethodGet, mrPath(org, repo, number), nil, nil, &mr); err != nil {
+		return nil, err
+	}
+	return &mr, nil
+}
+
+// GetPullRequest gets a merge request in a specified organization and repository
+func (c *Client) GetPullRequest(org, repo, number string) (result client.PullRequest, success bool) {
+	mr, err := c.getMergeRequest(org, repo, number)
+	if err != nil {
+		c.log.WithError(err).Errorf("Get merge request %s/%s!%s failed", org, repo, number)
+		return
+	}
+	return toPullRequest(mr), true
+}
+
+// ListOpenPullRequests lists the merge requests open on a project
+func (c *Client) ListOpenPullRequests(org, repo string) (result []client.PullRequest, success bool) {
+	mrs, err := platform.GetAll[mergeRequest](c.api, projectPath(org, repo)+"/merge_requests",
+		url.Values{"state": {"opened"}})
+	if err != nil {
+		c.log.WithError(err).Errorf("List open merge requests of %s/%s failed", org, repo)
+		return
+	}
+	for i := range mrs {
+		result = append(result, toPullRequest(&mrs[i]))
+	}
+	return result, true
+}
+
+func toPullRequest(mr *mergeRequest) client.PullRequest {
+	num := strconv.Itoa(mr.IID)
+	mergeable := mr.MergeStatus == "can_be_merged"
+	return client.PullRequest{
+		Number:    &num,
+		Title:     &mr.Title,
+		Body:      &mr.Description,
+		Head:      &mr.SourceBranch,
+		Base:      &mr.TargetBranch,
+		URL:       &mr.WebURL,
+		State:     &mr.State,
+		MergeAble: &mergeable,
+	}
+}
+
+// GetAuthenticatedUser gets the username of the user the token belongs to
+func (c *Client) GetAuthenticatedUser() (login string, success bool) {
+	var u user
+	if err := c.api.Do(http.MethodGet, "/user", nil, nil, &u); err != nil {
+		c.log.WithError(err).Errorln("Get the authenticated user failed")
+		return
+	}
+	return u.Username, true
+}
+
+// GetPullRequestAuthor gets the username of the user who opened a merge request
+func (c *Client) GetPullRequestAuthor(org, repo, number string) (login string, success bool) {
+	mr, err := c.getMergeRequest(org, repo, number)
+	if err != nil {
+		c.log.WithError(err).Errorf("Get merge request %s/%s!%s failed", org, repo, number)
+		return
+	}
+	return mr.Author.Username, true
+}
+
+// GetPathContent gets the base64 encoded content of a file on branch
+func (c *Client) GetPathContent(org, repo, path, branch string) (result client.RepoContent, success bool) {
+	var file struct {
+		Content string `json:"content"`
+	}
+	query := url.Values{"ref": {branch}}
+	p := projectPath(org, repo) + "/repository/files/" + url.PathEscape(path)
+	if err := c.api.Do(http.MethodGet, p, query, nil, &file); err != nil {
+		c.log.WithError(err).Errorf("Get content of %s/%s:%s on %s failed", org, repo, path, branch)
+		return
+	}
+	return client.RepoContent{Content: &file.Content}, true
+}
+
+// GetRepoAllBranch lists all branches of a repository
+func (c *Client) GetRepoAllBranch(org, repo string) (result []client.Branch, success bool) {
+	branches, err := platform.GetAll[struct {
+		Name string `json:"name"`
+	}](c.api, projectPath(org, repo)+"/repository/branches", nil)
+	if err != nil {
+		c.log.WithError(err).Errorf("List branches of %s/%s failed", org, repo)
+		return
+	}
+	for _, b := range branches {
+		result = append(result, client.Branch{Name: b.Name})
+	}
+	return result, true
+}
+
+// ListProtectedBranches lists the protected branches of a project
+func (c *Client) ListProtectedBranches(org, repo string) (result []string, success bool) {
+	branches, err := platform.GetAll[struct {
+		Name      string `json:"name"`
+		Protected bool   `json:"protected"`
+	}](c.api, projectPath(org, repo)+"/repository/branches", nil)
+	if err != nil {
+		c.log.WithError(err).Errorf("List branches of %s/%s failed", org, repo)
+		return
+	}
+	for _, b := range branches {
+		if b.Protected {
+			result = append(result, b.Name)
+		}
+	}
+	return result, true
+}
+
+// ListOrgRepos lists the paths of the projects of a group, those of its
+// subgroups are left out
+func (c *Client) ListOrgRepos(org string) (result []string, success bool) {
+	projects, err := platform.GetAll[struct {
+		Path string `json:"path"`
+	}](c.api, "/groups/"+url.PathEscape(org)+"/projects", nil)
+	if err != nil {
+		c.log.WithError(err).Errorf("List projects of %s failed", org)
+		return
+	}
+	for _, p := range projects {
+		result = append(result, p.Path)
+	}
+	return result, true
+}
+
+// ListPullRequestComments lists the comments of a merge request, the latest first
+func (c *Client) ListPullRequestComments(org, repo, number string) (result []client.PRComment, success bool) {
+	comments, ok := c.ListPullRequestCommentsWithID(org, repo, number)
+	for _, cm := range comments {
+		result = append(result, client.PRComment{Commenter: cm.Commenter, Body: cm.Body})
+	}
+	return result, ok
+}
+
+// ListPullRequestCommentsWithID lists the comments of a merge request with their ids, the latest first
+func (c *Client) ListPullRequestCommentsWithID(org, repo, number string) (result []platform.Comment, success bool) {
+	return c.listNotes(mrPath(org, repo, number))
+}
+
+// ListIssueCommentsWithID lists the comments of an issue with their ids, the latest first
+func (c *Client) ListIssueCommentsWithID(org, repo, number string) (result []platform.Comment, success bool) {
+	return c.listNotes(projectPath(org, repo) + "/issues/" + number)
+}
+
+func (c *Client) listNotes(path string) (result []platform.Comment, success bool) {
+	query := url.Values{"sort": {"desc"}, "order_by": {"created_at"}}
+	notes, err := platform.GetAll[note](c.api, path+"/notes", query)
+	if err != nil {
+		c.log.WithError(err).Errorf("List notes of %s failed", path)
+		return
+	}
+	for _, n := range notes {
+		// notes of the system record events like pushes, not comments
+		if n.System {
+			continue
+		}
+		result = append(result, platform.Comment{ID: strconv.FormatInt(n.ID, 10), Commenter: n.Author.Username, Body: n.Body})
+	}
+	return result, true
+}
+
+// EditPRComment replaces the body of the note commentID of a merge request
+func (c *Client) EditPRComment(org, repo, number, commentID, comment string) (success bool) {
+	body := map[string]string{"body": comment}
+	if err := c.api.Do(http.MethodPut, mrPath(org, repo, number)+"/notes/"+commentID, nil, body, nil); err != nil {
+		c.log.WithError(err).Errorf("Edit note %s on %s/%s!%s failed", commentID, org, repo, number)
+		return false
+	}
+	return true
+}
+
+// EditIssueComment replaces the body of the note commentID of an issue
+func (c *Client) EditIssueComment(org, repo, number, commentID, comment string) (success bool) {
+	body := map[string]string{"body": comment}
+	path := projectPath(org, repo) + "/issues/" + number + "/notes/" + commentID
+	if err := c.api.Do(http.MethodPut, path, nil, body, nil); err != nil {
+		c.log.WithError(err).Errorf("Edit note %s on %s/%s#%s failed", commentID, org, repo, number)
+		return false
+	}
+	return true
+}
+
+// RequestPRReviewers adds the users as the reviewers of a merge request, the
+// unknown ones are left out
+func (c *Client) RequestPRReviewers(org, repo, number string, reviewers []string) (success bool) {
+	mr, err := c.getMergeRequest(org, repo, number)
+	if err != nil {
+		c.log.WithError(err).Errorf("Get merge request %s/%s!%s failed", org, repo, number)
+		return false
+	}
+	ids, err := c.userIDs(reviewers)
+	if err != nil {
+		c.log.WithError(err).Errorf("Get users %v failed", reviewers)
+		return false
+	}
+	for _, r := range mr.Reviewers {
+		ids = append(ids, r.ID)
+	}
+	body := map[string][]int{"reviewer_ids": ids}
+	if err = c.api.Do(http.MethodPut, mrPath(org, repo, number), nil, body, nil); err != nil {
+		c.log.WithError(err).Errorf("Request reviewers of %s/%s!%s failed", org, repo, number)
+		return false
+	}
+	return true
+}
+
+// GetPullRequestReviewers gets the reviewers and the approvers of a merge request
+func (c *Client) GetPullRequestReviewers(org, repo, number string) (result []string, success bool) {
+	mr, err := c.getMergeRequest(org, repo, number)
+	if err != nil {
+		c.log.WithError(err).Errorf("Get merge request %s/%s!%s failed", org, repo, number)
+		return
+	}
+	var approvals struct {
+		ApprovedBy []struct {
+			User user `json:"user"`
+		} `json:"approved_by"`
+	}
+	if err = c.api.Do(http.MethodGet, mrPath(org, repo, number)+"/approvals", nil, nil, &approvals); err != nil {
+		c.log.WithError(err).Errorf("Get approvals of %s/%s!%s failed", org, repo, number)
+		return
+	}
+	users := mr.Reviewers
+	for _, a := range approvals.ApprovedBy {
+		users = append(users, a.User)
+	}
+	seen := make(map[string]bool)
+	for _, u := range users {
+		if !seen[u.Username] {
+			seen[u.Username] = true
+			result = append(result, u.Username)
+		}
+	}
+	return result, true
+}
+
+// AssignPR assigns the users to a merge request, the unknown ones are left out
+func (c *Client) AssignPR(org, repo, number string, assignees []string) (success bool) {
+	ids, err := c.userIDs(assignees)
+	if err != nil {
+		c.log.WithError(err).Errorf("Get users %v failed", assignees)
+		return false
+	}
+	body := map[string][]int{"assignee_ids": ids}
+	if err = c.api.Do(http.MethodPut, mrPath(org, repo, number), nil, body, nil); err != nil {
+		c.log.WithError(err).Errorf("Assign %s/%s!%s failed", org, repo, number)
+		return false
+	}
+	return true
+}
+
+// userIDs returns the ids of the users with usernames, the unknown ones are left out.
+func (c *Client) userIDs(usernames []string) ([]int, error) {
+	var ids []int
+	for _, username := range usernames {
+		id, err := c.userID(username)
+		if err != nil {
+			return nil, err
+		}
+		if id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// userID returns the id of the user with username, 0 if there is none.
+func (c *Client) userID(username string) (int, error) {
+	var users []user
+	if err := c.api.Do(http.MethodGet, "/users", url.Values{"username": {username}}, nil, &users); err != nil {
+		return 0, err
+	}
+	if len(users) == 0 {
+		return 0, nil
+	}
+	return users[0].ID, nil
+}
+
+// GetPRLinkedIssue gets the issues a merge request closes
+func (c *Client) GetPRLinkedIssue(org, repo, number string) (result []client.Issue, success bool) {
+	issues, err := platform.GetAll[struct {
+		WebURL string `json:"web_url"`
+	}](c.api, mrPath(org, repo, number)+"/closes_issues", nil)
+	if err != nil {
+		c.log.WithError(err).Errorf("List issues closed by %s/%s!%s failed", org, repo, number)
+		return
+	}
+	for _, is := range issues {
+		result = append(result, client.Issue{HtmlURL: is.WebURL})
+	}
+	return result, true
+}
+
+// CreatePRComment comments on a merge request
+func (c *Client) CreatePRComment(org, repo, number, comment string) (success bool) {
+	return c.createNote(mrPath(org, repo, number), comment)
+}
+
+// CreateIssueComment comments on an issue
+func (c *Client) CreateIssueComment(org, repo, number, comment string) (success bool) {
+	return c.createNote(projectPath(org, repo)+"/issues/"+number, comment)
+}
+
+func (c *Client) createNote(path, comment string) bool {
+	body := map[string]string{"body": comment}
+	if err := c.api.Do(http.MethodPost, path+"/notes", nil, body, nil); err != nil {
+		c.log.WithError(err).Errorf("Comment on %s failed", path)
+		return false
+	}
+	return true
+}
+
+// AddIssueLabels adds labels to an issue
+func (c *Client) AddIssueLabels(org, repo, number string, labels []string) (success bool) {
+	return c.updateLabels(projectPath(org, repo)+"/issues/"+number, "add_labels", labels)
+}
+
+// RemoveIssueLabels removes labels from an issue
+func (c *Client) RemoveIssueLabels(org, repo, number string, labels []string) (success bool) {
+	return c.updateLabels(projectPath(org, repo)+"/issues/"+number, "remove_labels", labels)
+}
+
+// AddPRLabels adds labels to a merge request
+func (c *Client) AddPRLabels(org, repo, number string, labels []string) (success bool) {
+	return c.updateLabels(mrPath(org, repo, number), "add_labels", labels)
+}
+
+// RemovePRLabels removes labels from a merge request
+func (c *Client) RemovePRLabels(org, repo, number string, labels []string) (success bool) {
+	return c.updateLabels(mrPath(org, repo, number), "remove_labels", labels)
+}
+
+func (c *Client) updateLabels(path, field string, labels []string) bool {
+	body := map[string]string{field: strings.Join(labels, ",")}
+	if err := c.api.Do(http.MethodPut, path, nil, body, nil); err != nil {
+		c.log.WithError(err).Errorf("Update %s %v of %s failed", field, labels, path)
+		return false
+	}
+	return true
+}
+
+// GetPullRequestCommits lists the commits of a merge request, the latest first
+func (c *Client) GetPullRequestCommits(org, repo, number string) (result []client.PRCommit, success bool) {
+	commits, err := platform.GetAll[commit](c.api, mrPath(org, repo, number)+"/commits", nil)
+	if err != nil {
+		c.log.WithError(err).Errorf("List commits of %s/%s!%s failed", org, repo, number)
+		return
+	}
+	for _, co := range commits {
+		result = append(result, client.PRCommit{
+			SHA:        co.ID,
+			HTMLURL:    co.WebURL,
+			CommitTime: co.CommittedDate,
+			Message:    co.Message,
+		})
+	}
+	return result, true
+}
+
+// GetPullRequestLabels lists the labels of a merge request
+func (c *Client) GetPullRequestLabels(org, repo, number string) (result []string, success bool) {
+	mr, err := c.getMergeRequest(org, repo, number)
+	if err != nil {
+		c.log.WithError(err).Errorf("Get merge request %s/%s!%s failed", org, repo, number)
+		return
+	}
+	return mr.Labels, true
+}
+
+// GetIssueLabels is not supported, issues of GitLab can't be found without their project
+func (c *Client) GetIssueLabels(org, issueID string) (result []string, success bool) {
+	c.log.Errorf("Get labels of issue %s/%s failed: GitLab issues need a project", org, issueID)
+	return nil, false
+}
+
+// GetRepoIssueLabels lists the labels of a repository
+func (c *Client) GetRepoIssueLabels(org, repo string) (result []string, success bool) {
+	labels, err := platform.GetAll[struct {
+		Name string `json:"name"`
+	}](c.api, projectPath(org, repo)+"/labels", nil)
+	if err != nil {
+		c.log.WithError(err).Errorf("List labels of %s/%s failed", org, repo)
+		return
+	}
+	for _, l := range labels {
+		result = append(result, l.Name)
+	}
+	return result, true
+}
+
+// CheckPermissionWithBranch checks if the user is at least a developer of the project
+func (c *Client) CheckPermissionWithBranch(org, repo, username, branch string) (pass, success bool) {
+	id, err := c.userID(username)
+	if err != nil {
+		c.log.WithError(err).Errorf("Get user %s failed", username)
+		return false, false
+	}
+	if id == 0 {
+		return false, true
+	}
+	var member struct {
+		AccessLevel int `json:"access_level"`
+	}
+	path := projectPath(org, repo) + "/members/all/" + strconv.Itoa(id)
+	if err := c.api.Do(http.MethodGet, path, nil, nil, &member); err != nil {
+		if platform.IsNotFound(err) {
+			return false, true
+		}
+		c.log.WithError(err).Errorf("Get permission of %s on %s/%s failed", username, org, repo)
+		return false, false
+	}
+	return member.AccessLevel >= developerAccess, true
+}
+
+// GetPullRequestChanges lists the files changed by a merge request
+func (c *Client) GetPullRequestChanges(org, repo, number string) (result []client.CommitFile, success bool) {
+	diffs, err := platform.GetAll[struct {
+		NewPath string `json:"new_path"`
+	}](c.api, mrPath(org, repo, number)+"/diffs", nil)
+	if err != nil {
+		c.log.WithError(err).Errorf("List files of %s/%s!%s failed", org, repo, number)
+		return
+	}
+	for _, d := range diffs {
+		result = append(result, client.CommitFile{Filename: d.NewPath})
+	}
+	return result, true
+}
+
+// CreatePR creates a merge request, the head of a fork is given as "owner:branch"
+func (c *Client) CreatePR(org, repo string, prContent client.PullRequest) (number string, success bool) {
+	head, base := utils.GetString(prContent.Head), utils.GetString(prContent.Base)
+	body := map[string]interface{}{
+		"title":                utils.GetString(prContent.Title),
+		"description":          utils.GetString(prContent.Body),
+		"source_branch":        head,
+		"target_branch":        base,
+		"remove_source_branch": utils.GetBool(prContent.PruneSourceBranch),
+	}
+	source := projectPath(org, repo)
+	if owner, branch, ok := strings.Cut(head, ":"); ok {
+		// merge requests from a fork are created on the fork against the target project
+		var target struct {
+			ID int `json:"id"`
+		}
+		if err := c.api.Do(http.MethodGet, source, nil, nil, &target); err != nil {
+			c.log.WithError(err).Errorf("Get project %s/%s failed", org, repo)
+			return "", false
+		}
+		body["source_branch"] = branch
+		body["target_project_id"] = target.ID
+		source = projectPath(owner, repo)
+	}
+	var mr mergeRequest
+	if err := c.api.Do(http.MethodPost, source+"/merge_requests", nil, body, &mr); err != nil {
+		c.log.WithError(err).Errorf("Create merge request %s -> %s on %s/%s failed", head, base, org, repo)
+		return "", false
+	}
+	return strconv.Itoa(mr.IID), true
+}
+
+// CreateRepoBranch creates branch from createFrom
+func (c *Client) CreateRepoBranch(org, repo, createFrom, branch string) (success bool) {
+	query := url.Values{"branch": {branch}, "ref": {createFrom}}
+	if err := c.api.Do(http.MethodPost, projectPath(org, repo)+"/repository/branches", query, nil, nil); err != nil {
+		c.log.WithError(err).Errorf("Create branch %s of %s/%s failed", branch, org, repo)
+		return false
+	}
+	return true
+}
+
+// CheckIfPRCreateEvent checks if the event opens a merge request
+func (c *Client) CheckIfPRCreateEvent(evt *client.GenericEvent) (yes bool) {
+	return utils.GetString(evt.Action) == "open"
+}
+
+// CheckIfPRReopenEvent checks if the event reopens a merge request
+func (c *Client) CheckIfPRReopenEvent(evt *client.GenericEvent) (yes bool) {
+	return utils.GetString(evt.Action) == "reopen"
+}
+
+// CheckIfPRMergeEvent checks if the event merges a merge request
+func (c *Client) CheckIfPRMergeEvent(evt *client.GenericEvent) (yes bool) {
+	return utils.GetString(evt.Action) == "merge"
+}
+
+// CheckIfPRCloseEvent checks if the event closes a merge request without merging it
+func (c *Client) CheckIfPRCloseEvent(evt *client.GenericEvent) (yes bool) {
+	return utils.GetString(evt.Action) == "close"
+}
+
+// CheckIfPRSourceCodeUpdateEvent checks if the event pushes to the source branch of a merge request
+func (c *Client) CheckIfPRSourceCodeUpdateEvent(evt *client.GenericEvent) (yes bool) {
+	return utils.GetString(evt.Action) == "update"
+}
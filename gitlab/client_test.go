@@ -0,0 +1,176 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return NewClient([]byte("token"), srv.URL, logrus.NewEntry(logrus.New()))
+}
+
+func TestListPullRequestComments(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/projects/openeuler%2Fsrc%2Fkernel/merge_requests/7/notes" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(w, `[{"body":"/sync stable","author":{"username":"alice"}},
+			{"body":"added 1 commit","system":true,"author":{"username":"bob"}},
+			{"body":"looks good","author":{"username":"bob"}}]`)
+	})
+
+	comments, ok := c.ListPullRequestComments("openeuler/src", "kernel", "7")
+	if !ok {
+		t.Fatalf("ListPullRequestComments() failed")
+	}
+	want := []client.PRComment{{Commenter: "alice", Body: "/sync stable"}, {Commenter: "bob", Body: "looks good"}}
+	if !reflect.DeepEqual(comments, want) {
+		t.Errorf("ListPullRequestComments() = %v, want %v", comments, want)
+	}
+}
+
+func TestListOpenPullRequests(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/projects/openeuler%2Fsrc%2Fkernel/merge_requests" || r.URL.Query().Get("state") != "opened" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(w, `[{"iid":9,"state":"opened","source_branch":"sync-pr7-feature-to-stable","target_branch":"stable"}]`)
+	})
+
+	prs, ok := c.ListOpenPullRequests("openeuler/src", "kernel")
+	if !ok || len(prs) != 1 {
+		t.Fatalf("ListOpenPullRequests() = %v, %v", prs, ok)
+	}
+	got := []string{utils.GetString(prs[0].Number), utils.GetString(prs[0].Head), utils.GetString(prs[0].Base)}
+	if want := []string{"9", "sync-pr7-feature-to-stable", "stable"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListOpenPullRequests() = %v, want %v", got, want)
+	}
+}
+
+func TestListProtectedBranches(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/projects/openeuler%2Fsrc%2Fkernel/repository/branches":
+			_, _ = io.WriteString(w, `[{"name":"master","protected":true},{"name":"feature","protected":false}]`)
+		case "/groups/openeuler%2Fsrc/projects":
+			_, _ = io.WriteString(w, `[{"path":"kernel","name":"Kernel"}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	if branches, ok := c.ListProtectedBranches("openeuler/src", "kernel"); !ok || !reflect.DeepEqual(branches, []string{"master"}) {
+		t.Errorf("ListProtectedBranches() = %v, %v, want master", branches, ok)
+	}
+	if repos, ok := c.ListOrgRepos("openeuler/src"); !ok || !reflect.DeepEqual(repos, []string{"kernel"}) {
+		t.Errorf("ListOrgRepos() = %v, %v, want kernel", repos, ok)
+	}
+}
+
+func TestCheckPermissionWithBranch(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users":
+			switch r.URL.Query().Get("username") {
+			case "alice":
+				_, _ = io.WriteString(w, `[{"id":1,"username":"alice"}]`)
+			case "bob":
+				_, _ = io.WriteString(w, `[{"id":2,"username":"bob"}]`)
+			default:
+				_, _ = io.WriteString(w, `[]`)
+			}
+		case "/projects/openeuler/kernel/members/all/1":
+			_, _ = io.WriteString(w, `{"access_level":30}`)
+		case "/projects/openeuler/kernel/members/all/2":
+			_, _ = io.WriteString(w, `{"access_level":20}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	tests := []struct {
+		user string
+		pass bool
+	}{
+		{user: "alice", pass: true},
+		{user: "bob", pass: false},
+		{user: "carol", pass: false},
+	}
+	for _, tt := range tests {
+		pass, ok := c.CheckPermissionWithBranch("openeuler", "kernel", tt.user, "master")
+		if !ok || pass != tt.pass {
+			t.Errorf("CheckPermissionWithBranch(%s) = %v, %v, want %v, true", tt.user, pass, ok, tt.pass)
+		}
+	}
+}
+
+func TestCreatePRFromFork(t *testing.T) {
+	var body map[string]interface{}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/projects/openeuler/kernel":
+			_, _ = io.WriteString(w, `{"id":42}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/projects/bot/kernel/merge_requests":
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = io.WriteString(w, `{"iid":9}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	title, head, base := "[sync] PR-7: fix", "bot:sync-pr7-feature-to-stable", "stable"
+	number, ok := c.CreatePR("openeuler", "kernel", client.PullRequest{Title: &title, Head: &head, Base: &base})
+	if !ok || number != "9" {
+		t.Fatalf("CreatePR() = %v, %v", number, ok)
+	}
+	want := map[string]interface{}{"title": title, "description": "", "source_branch": "sync-pr7-feature-to-stable",
+		"target_branch": base, "remove_source_branch": false, "target_project_id": float64(42)}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("CreatePR() sent %v, want %v", body, want)
+	}
+}
+
+func TestAssignPR(t *testing.T) {
+	var body map[string][]int
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/users":
+			if r.URL.Query().Get("username") == "alice" {
+				_, _ = io.WriteString(w, `[{"id":3,"username":"alice"}]`)
+				return
+			}
+			_, _ = io.WriteString(w, `[]`)
+		case r.Method == http.MethodPut && r.URL.EscapedPath() == "/projects/openeuler%2Fkernel/merge_requests/7":
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			_, _ = io.WriteString(w, `{"iid":7}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	if !c.AssignPR("openeuler", "kernel", "7", []string{"alice", "nobody"}) {
+		t.Fatalf("AssignPR() failed")
+	}
+	if want := map[string][]int{"assignee_ids": {3}}; !reflect.DeepEqual(body, want) {
+		t.Errorf("AssignPR() sent %v, want %v", body, want)
+	}
+}
@@ -0,0 +1,149 @@
+package gitlab
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"sync-bot/platform"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+)
+
+type project struct {
+	PathWithNamespace string `json:"path_with_namespace"`
+}
+
+// split returns the namespace and the path of the project.
+func (p project) split() (org, repo string) {
+	i := strings.LastIndex(p.PathWithNamespace, "/")
+	if i < 0 {
+		return "", p.PathWithNamespace
+	}
+	return p.PathWithNamespace[:i], p.PathWithNamespace[i+1:]
+}
+
+type hookMergeRequest struct {
+	IID          int    `json:"iid"`
+	Title        string `json:"title"`
+	State        string `json:"state"`
+	URL          string `json:"url"`
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+}
+
+type mergeRequestPayload struct {
+	ObjectAttributes struct {
+		hookMergeRequest
+		Action string `json:"action"`
+		// OldRev is only set on updates pushing new commits.
+		OldRev string `json:"oldrev"`
+	} `json:"object_attributes"`
+	Project project `json:"project"`
+}
+
+type notePayload struct {
+	User             user `json:"user"`
+	ObjectAttributes struct {
+		Note         string `json:"note"`
+		NoteableType string `json:"noteable_type"`
+		URL          string `json:"url"`
+	} `json:"object_attributes"`
+	MergeRequest hookMergeRequest `json:"merge_request"`
+	Issue        struct {
+		IID   int    `json:"iid"`
+		Title string `json:"title"`
+		State string `json:"state"`
+	} `json:"issue"`
+	Project project `json:"project"`
+}
+
+// VerifySignature checks the X-Gitlab-Token header of a delivery against secret.
+func VerifySignature(header http.Header, payload, secret []byte) error {
+	if len(secret) == 0 {
+		return errors.New("empty secret")
+	}
+	token := header.Get("X-Gitlab-Token")
+	if token == "" {
+		return errors.New("missing X-Gitlab-Token")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), secret) != 1 {
+		return errors.New("token mismatch")
+	}
+	return nil
+}
+
+// ParseWebhook maps a delivery to a generic event. It returns an empty kind for
+// deliveries sync-bot does not handle.
+func ParseWebhook(header http.Header, payload []byte) (string, *client.GenericEvent, error) {
+	switch header.Get("X-Gitlab-Event") {
+	case "Merge Request Hook":
+		var p mergeRequestPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return "", nil, err
+		}
+		mr := p.ObjectAttributes
+		// updates of the title or the description are not interesting
+		if mr.Action == "update" && mr.OldRev == "" {
+			return "", nil, nil
+		}
+		org, repo := p.Project.split()
+		number := strconv.Itoa(mr.IID)
+		return platform.PullRequestEvent, &client.GenericEvent{
+			Action:  &mr.Action,
+			Org:     &org,
+			Repo:    &repo,
+			Number:  &number,
+			Base:    &mr.TargetBranch,
+			Head:    &mr.SourceBranch,
+			Title:   &mr.Title,
+			HtmlURL: &mr.URL,
+			State:   &mr.State,
+		}, nil
+	case "Note Hook":
+		var p notePayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return "", nil, err
+		}
+		org, repo := p.Project.split()
+		action := "comment"
+		if p.ObjectAttributes.NoteableType == "Issue" {
+			number := strconv.Itoa(p.Issue.IID)
+			return platform.IssueCommentEvent, &client.GenericEvent{
+				Action:    &action,
+				Org:       &org,
+				Repo:      &repo,
+				Number:    &number,
+				Title:     &p.Issue.Title,
+				Comment:   &p.ObjectAttributes.Note,
+				Commenter: &p.User.Username,
+				HtmlURL:   &p.ObjectAttributes.URL,
+				State:     &p.Issue.State,
+			}, nil
+		}
+		// comments on commits and snippets are not handled
+		if p.ObjectAttributes.NoteableType != "MergeRequest" {
+			return "", nil, nil
+		}
+		mr := p.MergeRequest
+		number := strconv.Itoa(mr.IID)
+		return platform.PullRequestCommentEvent, &client.GenericEvent{
+			Action:    &action,
+			Org:       &org,
+			Repo:      &repo,
+			Number:    &number,
+			Base:      &mr.TargetBranch,
+			Head:      &mr.SourceBranch,
+			Title:     &mr.Title,
+			Comment:   &p.ObjectAttributes.Note,
+			Commenter: &p.User.Username,
+			HtmlURL:   &p.ObjectAttributes.URL,
+			State:     &mr.State,
+		}, nil
+	default:
+		return "", nil, nil
+	}
+}
@@ -0,0 +1,111 @@
+package gitlab
+
+import (
+	"net/http"
+	"testing"
+
+	"sync-bot/platform"
+
+	"github.com/opensourceways/robot-framework-lib/utils"
+)
+
+func TestVerifySignature(t *testing.T) {
+	header := http.Header{}
+	if err := VerifySignature(header, nil, []byte("secret")); err == nil {
+		t.Errorf("VerifySignature() accepted a delivery without token")
+	}
+	header.Set("X-Gitlab-Token", "other")
+	if err := VerifySignature(header, nil, []byte("secret")); err == nil {
+		t.Errorf("VerifySignature() accepted a wrong token")
+	}
+	header.Set("X-Gitlab-Token", "secret")
+	if err := VerifySignature(header, nil, []byte("secret")); err != nil {
+		t.Errorf("VerifySignature() error = %v", err)
+	}
+}
+
+func TestParseWebhook(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    string
+		payload  string
+		wantKind string
+		want     map[string]string
+	}{
+		{
+			name:  "merged merge request",
+			event: "Merge Request Hook",
+			payload: `{"object_attributes":{"iid":7,"title":"fix","state":"merged","action":"merge",
+				"source_branch":"feature","target_branch":"master"},"project":{"path_with_namespace":"openeuler/src/kernel"}}`,
+			wantKind: platform.PullRequestEvent,
+			want: map[string]string{"action": "merge", "org": "openeuler/src", "repo": "kernel", "number": "7",
+				"base": "master", "head": "feature", "state": "merged"},
+		},
+		{
+			name:  "push to merge request",
+			event: "Merge Request Hook",
+			payload: `{"object_attributes":{"iid":7,"state":"opened","action":"update","oldrev":"1111"},
+				"project":{"path_with_namespace":"o/r"}}`,
+			wantKind: platform.PullRequestEvent,
+			want:     map[string]string{"action": "update", "state": "opened"},
+		},
+		{
+			name:     "title update",
+			event:    "Merge Request Hook",
+			payload:  `{"object_attributes":{"iid":7,"state":"opened","action":"update"}}`,
+			wantKind: "",
+		},
+		{
+			name:  "comment on merge request",
+			event: "Note Hook",
+			payload: `{"user":{"username":"alice"},"object_attributes":{"note":"/sync stable","noteable_type":"MergeRequest"},
+				"merge_request":{"iid":7,"state":"opened","target_branch":"master"},"project":{"path_with_namespace":"o/r"}}`,
+			wantKind: platform.PullRequestCommentEvent,
+			want: map[string]string{"org": "o", "repo": "r", "number": "7", "base": "master",
+				"comment": "/sync stable", "commenter": "alice", "state": "opened"},
+		},
+		{
+			name:  "comment on issue",
+			event: "Note Hook",
+			payload: `{"user":{"username":"alice"},"object_attributes":{"note":"/sync-branch master stable","noteable_type":"Issue"},
+				"issue":{"iid":8,"state":"opened"},"project":{"path_with_namespace":"o/r"}}`,
+			wantKind: platform.IssueCommentEvent,
+			want: map[string]string{"org": "o", "repo": "r", "number": "8",
+				"comment": "/sync-branch master stable", "commenter": "alice"},
+		},
+		{
+			name:     "comment on commit",
+			event:    "Note Hook",
+			payload:  `{"object_attributes":{"note":"/sync stable","noteable_type":"Commit"}}`,
+			wantKind: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			header.Set("X-Gitlab-Event", tt.event)
+			kind, evt, err := ParseWebhook(header, []byte(tt.payload))
+			if err != nil {
+				t.Fatalf("ParseWebhook() error = %v", err)
+			}
+			if kind != tt.wantKind {
+				t.Fatalf("ParseWebhook() kind = %v, want %v", kind, tt.wantKind)
+			}
+			if kind == "" {
+				return
+			}
+			got := map[string]string{
+				"action": utils.GetString(evt.Action), "org": utils.GetString(evt.Org),
+				"repo": utils.GetString(evt.Repo), "number": utils.GetString(evt.Number),
+				"base": utils.GetString(evt.Base), "head": utils.GetString(evt.Head),
+				"comment": utils.GetString(evt.Comment), "commenter": utils.GetString(evt.Commenter),
+				"state": utils.GetString(evt.State),
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("ParseWebhook() %s = %v, want %v", k, got[k], v)
+				}
+			}
+		})
+	}
+}
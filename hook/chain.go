@@ -0,0 +1,77 @@
+package hook
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// syncChainRegex matches the marker of the sync chain in the body of a sync pull request.
+var syncChainRegex = regexp.MustCompile(`<!-- sync-chain: ([^>]*) -->`)
+
+// parseSyncChain returns the pull requests a sync pull request descends from,
+// the original one first, as marked in its body.
+func parseSyncChain(body string) []string {
+	match := syncChainRegex.FindStringSubmatch(body)
+	if match == nil {
+		return nil
+	}
+	return strings.Fields(match[1])
+}
+
+// continueSyncChain syncs a merged sync pull request to the branches following
+// its target branch in the lineages of the repository, and replies the result
+// on it. The new sync pull requests link every hop from the original one.
+func (bot *robot) continueSyncChain(evt *client.GenericEvent, logger *logrus.Entry) {
+	org := utils.GetString(evt.Org)
+	repo := utils.GetString(evt.Repo)
+	number := utils.GetString(evt.Number)
+	base := utils.GetString(evt.Base)
+
+	// the pull requests of /sync-branch are of no pull request to sync on
+	if !syncHeadRegex.MatchString(utils.GetString(evt.Head)) {
+		logger.Infoln("Merge Pull Request which is not of a pull request, no sync chain to continue.")
+		return
+	}
+	next := bot.cnf.nextBranches(org, repo, base)
+	if len(next) == 0 {
+		logger.Infoln("Merge Pull Request which created by sync-bot, ignore it.")
+		return
+	}
+	if err := bot.syncChainHop(org, repo, number, base, next, logger); err != nil {
+		logger.WithError(err).Errorln("Continue sync chain failed")
+	}
+}
+
+func (bot *robot) syncChainHop(org, repo, number, base string, next []string, logger *logrus.Entry) error {
+	pr, ok := bot.cli.GetPullRequest(org, repo, number)
+	if !ok {
+		return errors.New("get pull request failed")
+	}
+	chain := parseSyncChain(utils.GetString(pr.Body))
+	logger.Infof("Continue sync chain from %s to %v", base, next)
+
+	opt := &SyncCmdOption{strategy: Pick, branches: next}
+	status, err := bot.syncPullRequest(org, repo, number, opt, chain, logger)
+	if err != nil {
+		return err
+	}
+	comment, err := executeTemplate(syncChainResultTmpl, struct {
+		Branch     string
+		SyncStatus []syncStatus
+	}{
+		Branch:     base,
+		SyncStatus: status,
+	})
+	if err != nil {
+		return err
+	}
+	if !bot.cli.CreatePRComment(org, repo, number, comment) {
+		return errors.New("create comment failed")
+	}
+	return nil
+}
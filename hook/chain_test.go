@@ -0,0 +1,94 @@
+package hook
+
+import (
+	"strings"
+	"testing"
+
+	"sync-bot/fake"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+func mergeEvent(number, title, base string) *client.GenericEvent {
+	org, repo, action, state := "o", "r", "merge", "merged"
+	return &client.GenericEvent{Org: &org, Repo: &repo, Number: &number, Base: &base, Title: &title,
+		Action: &action, State: &state}
+}
+
+func TestSyncChain(t *testing.T) {
+	bot, cli, f := newSyncScenario(t)
+	f.git(f.base+"/o/r.git", "branch", "next", "master")
+	key := fake.RepoKey("o", "r")
+	cli.Branches[key] = append(cli.Branches[key], "next")
+	bot.cnf = &Configuration{ConfigItems: []repoConfig{{
+		Repos:    []string{"o/r"},
+		Lineages: [][]string{{"master", "stable", "next"}},
+	}}}
+	logger := logrus.NewEntry(logrus.StandardLogger())
+
+	// the first hop comes from /sync
+	bot.handlePullRequestCommentEvent(commentEvent("/sync stable", "merged"), nil, logger)
+	if len(cli.CreatedPRs) != 1 {
+		t.Fatalf("created %v, want the first hop", cli.CreatedPRs)
+	}
+	first := cli.CreatedPRs[0]
+	firstNumber := utils.GetString(first.Number)
+	cli.Commits[fake.Key("o", "r", firstNumber)] = f.mergePullRequest("o", "r", firstNumber, utils.GetString(first.Head), "stable")
+	cli.SetPullRequestState("o", "r", firstNumber, "merged")
+
+	evt := mergeEvent(firstNumber, utils.GetString(first.Title), "stable")
+	evt.Head = first.Head
+	bot.handlePREvent(evt, nil, logger)
+
+	if len(cli.CreatedPRs) != 2 {
+		t.Fatalf("created %d pull requests, want the second hop", len(cli.CreatedPRs))
+	}
+	second := cli.CreatedPRs[1]
+	if base := utils.GetString(second.Base); base != "next" {
+		t.Errorf("second hop goes to %s, want next", base)
+	}
+	if title := utils.GetString(second.Title); title != "[sync] PR-1: fix a" {
+		t.Errorf("second hop title = %q", title)
+	}
+	body := utils.GetString(second.Body)
+	for _, want := range []string{
+		"### 1. Origin pull request:\nhttps://fake/o/r/pulls/1\n",
+		"1. https://fake/o/r/pulls/1\n2. " + utils.GetString(first.URL),
+		"<!-- sync-chain: https://fake/o/r/pulls/1 " + utils.GetString(first.URL) + " -->",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("second hop body %q doesn't hold %q", body, want)
+		}
+	}
+	if got := f.show("o", "r", utils.GetString(second.Head), "a.txt"); got != "2" {
+		t.Errorf("second hop a.txt = %q, want 2", got)
+	}
+	reply := cli.PRComments[len(cli.PRComments)-1]
+	if reply.Number != firstNumber || !strings.Contains(reply.Body, "|next|"+createdPR+"|") {
+		t.Errorf("reply %+v, want the second hop on the first one", reply)
+	}
+
+	// the chain ends at next
+	secondNumber := utils.GetString(second.Number)
+	cli.Commits[fake.Key("o", "r", secondNumber)] = f.mergePullRequest("o", "r", secondNumber, utils.GetString(second.Head), "next")
+	cli.SetPullRequestState("o", "r", secondNumber, "merged")
+	evt = mergeEvent(secondNumber, utils.GetString(second.Title), "next")
+	evt.Head = second.Head
+	bot.handlePREvent(evt, nil, logger)
+	if len(cli.CreatedPRs) != 2 {
+		t.Errorf("created %v after the end of the chain", cli.CreatedPRs[2:])
+	}
+}
+
+func TestParseSyncChain(t *testing.T) {
+	body := "### 1. Origin pull request:\nhttps://fake/o/r/pulls/1\n<!-- sync-chain: https://fake/o/r/pulls/1 https://fake/o/r/pulls/7 -->\n"
+	got := parseSyncChain(body)
+	if len(got) != 2 || got[0] != "https://fake/o/r/pulls/1" || got[1] != "https://fake/o/r/pulls/7" {
+		t.Errorf("parseSyncChain() = %v", got)
+	}
+	if got := parseSyncChain("no marker"); got != nil {
+		t.Errorf("parseSyncChain() without marker = %v", got)
+	}
+}
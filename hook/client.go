@@ -0,0 +1,399 @@
+package hook
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"sync-bot/gitee"
+	"sync-bot/github"
+	"sync-bot/gitlab"
+	"sync-bot/platform"
+	"sync-bot/secret"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/sirupsen/logrus"
+)
+
+// clientFactory builds a platform client authenticated with token.
+type clientFactory func(token []byte) iClient
+
+// newClientFactory returns the factory of the platform clients of p.
+func newClientFactory(p *platform.Platform, logger *logrus.Entry) clientFactory {
+	switch p.Name() {
+	case platform.GitHub:
+		return func(token []byte) iClient {
+			return github.NewClient(token, p.APIBase(), logger)
+		}
+	case platform.Gitee:
+		return func(token []byte) iClient {
+			return gitee.NewClient(token, p.APIBase(), logger)
+		}
+	case platform.GitLab:
+		return func(token []byte) iClient {
+			return gitlab.NewClient(token, p.APIBase(), logger)
+		}
+	default:
+		return func(token []byte) iClient {
+			if cli := client.NewClient(token, logger); cli != nil {
+				return &gitcodeClient{frameworkClient: cli, v5: gitee.NewClient(token, p.APIBase(), logger)}
+			}
+			return nil
+		}
+	}
+}
+
+// gitcodeClient is the GitCode client of the robot framework. What it lacks is
+// done through the v5 API of GitCode, which is compatible with the Gitee one.
+type gitcodeClient struct {
+	frameworkClient
+	v5 *gitee.Client
+}
+
+func (c *gitcodeClient) ListPullRequestCommentsWithID(org, repo, number string) ([]platform.Comment, bool) {
+	return c.v5.ListPullRequestCommentsWithID(org, repo, number)
+}
+
+func (c *gitcodeClient) EditPRComment(org, repo, number, commentID, comment string) bool {
+	return c.v5.EditPRComment(org, repo, number, commentID, comment)
+}
+
+func (c *gitcodeClient) ListIssueCommentsWithID(org, repo, number string) ([]platform.Comment, bool) {
+	return c.v5.ListIssueCommentsWithID(org, repo, number)
+}
+
+func (c *gitcodeClient) EditIssueComment(org, repo, number, commentID, comment string) bool {
+	return c.v5.EditIssueComment(org, repo, number, commentID, comment)
+}
+
+func (c *gitcodeClient) GetAuthenticatedUser() (string, bool) {
+	return c.v5.GetAuthenticatedUser()
+}
+
+func (c *gitcodeClient) ListOpenPullRequests(org, repo string) ([]client.PullRequest, bool) {
+	return c.v5.ListOpenPullRequests(org, repo)
+}
+
+func (c *gitcodeClient) ListOrgRepos(org string) ([]string, bool) {
+	return c.v5.ListOrgRepos(org)
+}
+
+func (c *gitcodeClient) ListProtectedBranches(org, repo string) ([]string, bool) {
+	return c.v5.ListProtectedBranches(org, repo)
+}
+
+func (c *gitcodeClient) GetPullRequestAuthor(org, repo, number string) (string, bool) {
+	return c.v5.GetPullRequestAuthor(org, repo, number)
+}
+
+func (c *gitcodeClient) RequestPRReviewers(org, repo, number string, reviewers []string) bool {
+	return c.v5.RequestPRReviewers(org, repo, number, reviewers)
+}
+
+func (c *gitcodeClient) GetPullRequestReviewers(org, repo, number string) ([]string, bool) {
+	return c.v5.GetPullRequestReviewers(org, repo, number)
+}
+
+func (c *gitcodeClient) AssignPR(org, repo, number string, assignees []string) bool {
+	return c.v5.AssignPR(org, repo, number, assignees)
+}
+
+// tokenClient holds a platform client which is rebuilt whenever the token
+// returned by its generator changes.
+type tokenClient struct {
+	lock    sync.RWMutex
+	cli     iClient
+	current []byte
+
+	token     func() []byte
+	newClient clientFactory
+	log       *logrus.Entry
+}
+
+func newTokenClient(token func() []byte, newClient clientFactory, logger *logrus.Entry) *tokenClient {
+	return &tokenClient{token: token, newClient: newClient, log: logger}
+}
+
+// get returns the platform client for the current token, it returns nil only
+// if no client could ever be built.
+func (c *tokenClient) get() iClient {
+	token := c.token()
+	c.lock.RLock()
+	cli, current := c.cli, c.current
+	c.lock.RUnlock()
+	if cli != nil && bytes.Equal(token, current) {
+		return cli
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.cli != nil && bytes.Equal(token, c.current) {
+		return c.cli
+	}
+	cli = c.newClient(token)
+	if cli == nil {
+		c.log.Errorln("Build platform client with the rotated token failed, keep the previous one")
+		return c.cli
+	}
+	if c.cli != nil {
+		c.log.Infoln("Platform token rotated, rebuild platform client")
+	}
+	c.cli, c.current = cli, token
+	return c.cli
+}
+
+// roleClient is an iClient which sends every operation with the token of the
+// identity allowed to do it: pushing branches and creating pull requests use
+// the push token, everything else uses the api token. Organizations may have
+// their own tokens.
+type roleClient struct {
+	tokens    *secret.Tokens
+	newClient clientFactory
+	log       *logrus.Entry
+
+	lock    sync.Mutex
+	clients map[string]*tokenClient
+}
+
+func newRoleClient(tokens *secret.Tokens, newClient clientFactory, logger *logrus.Entry) *roleClient {
+	return &roleClient{
+		tokens:    tokens,
+		newClient: newClient,
+		log:       logger,
+		clients:   make(map[string]*tokenClient),
+	}
+}
+
+// client returns the platform client of role for the repositories of org, it
+// returns nil if none could be built.
+func (c *roleClient) client(org string, role secret.Role) iClient {
+	key := org + "/" + string(role)
+	c.lock.Lock()
+	tc, ok := c.clients[key]
+	if !ok {
+		tc = newTokenClient(c.tokens.Generator(org, role), c.newClient, c.log)
+		c.clients[key] = tc
+	}
+	c.lock.Unlock()
+	cli := tc.get()
+	if cli == nil {
+		c.log.Errorf("No platform client of the %s token for %q, skip the operation", role, org)
+	}
+	return cli
+}
+
+// with calls op with the platform client of role for org. op is skipped when
+// there is no client, the results of the caller are left zero then.
+func (c *roleClient) with(org string, role secret.Role, op func(cli iClient)) {
+	if cli := c.client(org, role); cli != nil {
+		op(cli)
+	}
+}
+
+// check builds the platform clients of every role, for all organizations and
+// for those with their own tokens, and asks the platform whose token each one
+// holds, so that a bad token is found at startup.
+func (c *roleClient) check() error {
+	for _, org := range append([]string{""}, c.tokens.Orgs()...) {
+		for _, role := range []secret.Role{secret.RoleAPI, secret.RolePush} {
+			cli := c.client(org, role)
+			if cli == nil {
+				return fmt.Errorf("build the platform client of the %s token for %q failed", role, org)
+			}
+			if _, ok := cli.GetAuthenticatedUser(); !ok {
+				return fmt.Errorf("the platform refused the %s token for %q", role, org)
+			}
+		}
+	}
+	return nil
+}
+
+// pushUser returns the user the push token of org belongs to, the one set with
+// the tokens or else the one the platform tells.
+func (c *roleClient) pushUser(org string) (user string) {
+	if user = c.tokens.User(org); user != "" {
+		return user
+	}
+	c.with(org, secret.RolePush, func(cli iClient) { user, _ = cli.GetAuthenticatedUser() })
+	return user
+}
+
+func (c *roleClient) GetPullRequest(org, repo, number string) (result client.PullRequest, success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { result, success = cli.GetPullRequest(org, repo, number) })
+	return
+}
+
+func (c *roleClient) GetPathContent(org, repo, path, branch string) (result client.RepoContent, success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { result, success = cli.GetPathContent(org, repo, path, branch) })
+	return
+}
+
+func (c *roleClient) GetRepoAllBranch(org, repo string) (result []client.Branch, success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { result, success = cli.GetRepoAllBranch(org, repo) })
+	return
+}
+
+func (c *roleClient) ListPullRequestComments(org, repo, number string) (result []client.PRComment, success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { result, success = cli.ListPullRequestComments(org, repo, number) })
+	return
+}
+
+func (c *roleClient) ListPullRequestCommentsWithID(org, repo, number string) (result []platform.Comment, success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { result, success = cli.ListPullRequestCommentsWithID(org, repo, number) })
+	return
+}
+
+func (c *roleClient) EditPRComment(org, repo, number, commentID, comment string) (success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { success = cli.EditPRComment(org, repo, number, commentID, comment) })
+	return
+}
+
+func (c *roleClient) ListIssueCommentsWithID(org, repo, number string) (result []platform.Comment, success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { result, success = cli.ListIssueCommentsWithID(org, repo, number) })
+	return
+}
+
+func (c *roleClient) EditIssueComment(org, repo, number, commentID, comment string) (success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { success = cli.EditIssueComment(org, repo, number, commentID, comment) })
+	return
+}
+
+func (c *roleClient) GetAuthenticatedUser() (login string, success bool) {
+	c.with("", secret.RoleAPI, func(cli iClient) { login, success = cli.GetAuthenticatedUser() })
+	return
+}
+
+func (c *roleClient) ListOpenPullRequests(org, repo string) (result []client.PullRequest, success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { result, success = cli.ListOpenPullRequests(org, repo) })
+	return
+}
+
+func (c *roleClient) ListOrgRepos(org string) (result []string, success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { result, success = cli.ListOrgRepos(org) })
+	return
+}
+
+func (c *roleClient) ListProtectedBranches(org, repo string) (result []string, success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { result, success = cli.ListProtectedBranches(org, repo) })
+	return
+}
+
+func (c *roleClient) GetPullRequestAuthor(org, repo, number string) (login string, success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { login, success = cli.GetPullRequestAuthor(org, repo, number) })
+	return
+}
+
+func (c *roleClient) RequestPRReviewers(org, repo, number string, reviewers []string) (success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { success = cli.RequestPRReviewers(org, repo, number, reviewers) })
+	return
+}
+
+func (c *roleClient) GetPullRequestReviewers(org, repo, number string) (result []string, success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { result, success = cli.GetPullRequestReviewers(org, repo, number) })
+	return
+}
+
+func (c *roleClient) AssignPR(org, repo, number string, assignees []string) (success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { success = cli.AssignPR(org, repo, number, assignees) })
+	return
+}
+
+func (c *roleClient) GetPRLinkedIssue(org, repo, number string) (result []client.Issue, success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { result, success = cli.GetPRLinkedIssue(org, repo, number) })
+	return
+}
+
+func (c *roleClient) CreatePRComment(org, repo, number, comment string) (success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { success = cli.CreatePRComment(org, repo, number, comment) })
+	return
+}
+
+func (c *roleClient) CreateIssueComment(org, repo, number, comment string) (success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { success = cli.CreateIssueComment(org, repo, number, comment) })
+	return
+}
+
+func (c *roleClient) AddIssueLabels(org, repo, number string, labels []string) (success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { success = cli.AddIssueLabels(org, repo, number, labels) })
+	return
+}
+
+func (c *roleClient) RemoveIssueLabels(org, repo, number string, labels []string) (success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { success = cli.RemoveIssueLabels(org, repo, number, labels) })
+	return
+}
+
+func (c *roleClient) AddPRLabels(org, repo, number string, labels []string) (success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { success = cli.AddPRLabels(org, repo, number, labels) })
+	return
+}
+
+func (c *roleClient) RemovePRLabels(org, repo, number string, labels []string) (success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { success = cli.RemovePRLabels(org, repo, number, labels) })
+	return
+}
+
+func (c *roleClient) GetPullRequestCommits(org, repo, number string) (result []client.PRCommit, success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { result, success = cli.GetPullRequestCommits(org, repo, number) })
+	return
+}
+
+func (c *roleClient) GetPullRequestLabels(org, repo, number string) (result []string, success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { result, success = cli.GetPullRequestLabels(org, repo, number) })
+	return
+}
+
+func (c *roleClient) GetIssueLabels(org, issueID string) (result []string, success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { result, success = cli.GetIssueLabels(org, issueID) })
+	return
+}
+
+func (c *roleClient) GetRepoIssueLabels(org, repo string) (result []string, success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { result, success = cli.GetRepoIssueLabels(org, repo) })
+	return
+}
+
+func (c *roleClient) CheckPermissionWithBranch(org, repo, username, branch string) (pass, success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { pass, success = cli.CheckPermissionWithBranch(org, repo, username, branch) })
+	return
+}
+
+func (c *roleClient) GetPullRequestChanges(org, repo, number string) (result []client.CommitFile, success bool) {
+	c.with(org, secret.RoleAPI, func(cli iClient) { result, success = cli.GetPullRequestChanges(org, repo, number) })
+	return
+}
+
+func (c *roleClient) CreatePR(org, repo string, prContent client.PullRequest) (number string, success bool) {
+	c.with(org, secret.RolePush, func(cli iClient) { number, success = cli.CreatePR(org, repo, prContent) })
+	return
+}
+
+func (c *roleClient) CreateRepoBranch(org, repo, createFrom, branch string) (success bool) {
+	c.with(org, secret.RolePush, func(cli iClient) { success = cli.CreateRepoBranch(org, repo, createFrom, branch) })
+	return
+}
+
+func (c *roleClient) CheckIfPRCreateEvent(evt *client.GenericEvent) (yes bool) {
+	c.with("", secret.RoleAPI, func(cli iClient) { yes = cli.CheckIfPRCreateEvent(evt) })
+	return
+}
+
+func (c *roleClient) CheckIfPRReopenEvent(evt *client.GenericEvent) (yes bool) {
+	c.with("", secret.RoleAPI, func(cli iClient) { yes = cli.CheckIfPRReopenEvent(evt) })
+	return
+}
+
+func (c *roleClient) CheckIfPRMergeEvent(evt *client.GenericEvent) (yes bool) {
+	c.with("", secret.RoleAPI, func(cli iClient) { yes = cli.CheckIfPRMergeEvent(evt) })
+	return
+}
+
+func (c *roleClient) CheckIfPRCloseEvent(evt *client.GenericEvent) (yes bool) {
+	c.with("", secret.RoleAPI, func(cli iClient) { yes = cli.CheckIfPRCloseEvent(evt) })
+	return
+}
+
+func (c *roleClient) CheckIfPRSourceCodeUpdateEvent(evt *client.GenericEvent) (yes bool) {
+	c.with("", secret.RoleAPI, func(cli iClient) { yes = cli.CheckIfPRSourceCodeUpdateEvent(evt) })
+	return
+}
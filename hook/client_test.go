@@ -0,0 +1,76 @@
+package hook
+
+import (
+	"testing"
+
+	"sync-bot/fake"
+	"sync-bot/secret"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/sirupsen/logrus"
+)
+
+func TestRoleClientBadToken(t *testing.T) {
+	token := func(v string) func() []byte {
+		return func() []byte { return []byte(v) }
+	}
+	// the factory fails on a bad token like the one of GitCode does, the
+	// platform refuses a revoked one
+	newClient := func(token []byte) iClient {
+		if string(token) == "bad" {
+			return nil
+		}
+		cli := fake.NewClient()
+		cli.Fail["GetAuthenticatedUser"] = string(token) == "revoked"
+		return cli
+	}
+	logger := logrus.NewEntry(logrus.StandardLogger())
+
+	tokens := secret.NewTokens(token("good"))
+	if err := newRoleClient(tokens, newClient, logger).check(); err != nil {
+		t.Fatalf("check() error = %v", err)
+	}
+
+	tokens.SetOrg("p", secret.RoleAPI, token("revoked"))
+	if err := newRoleClient(tokens, newClient, logger).check(); err == nil {
+		t.Errorf("check() of a revoked token of p succeeded")
+	}
+
+	tokens = secret.NewTokens(token("good"))
+	tokens.SetOrg("o", secret.RolePush, token("bad"))
+	cli := newRoleClient(tokens, newClient, logger)
+	if err := cli.check(); err == nil {
+		t.Errorf("check() of a bad token of o succeeded")
+	}
+	// at runtime the operations of the bad token fail instead of panicking
+	if _, ok := cli.CreatePR("o", "r", client.PullRequest{}); ok {
+		t.Errorf("CreatePR() with a bad token succeeded")
+	}
+	if cli.CreateRepoBranch("o", "r", "master", "next") {
+		t.Errorf("CreateRepoBranch() with a bad token succeeded")
+	}
+	if _, ok := cli.GetRepoAllBranch("o", "r"); !ok {
+		t.Errorf("GetRepoAllBranch() with the good api token failed")
+	}
+}
+
+func TestRoleClientPushUser(t *testing.T) {
+	// the platform tells the token as the user
+	newClient := func(token []byte) iClient {
+		cli := fake.NewClient()
+		cli.User = string(token)
+		return cli
+	}
+	tokens := secret.NewTokens(func() []byte { return []byte("api") })
+	tokens.Set(secret.RolePush, func() []byte { return []byte("pusher") })
+	tokens.SetOrg("o", secret.RolePush, func() []byte { return []byte("o-pusher") })
+	tokens.SetOrg("p", secret.RolePush, func() []byte { return []byte("p-token") })
+	tokens.SetUser("p", "p-pusher")
+	cli := newRoleClient(tokens, newClient, logrus.NewEntry(logrus.StandardLogger()))
+
+	for org, want := range map[string]string{"": "pusher", "o": "o-pusher", "p": "p-pusher", "q": "pusher"} {
+		if got := cli.pushUser(org); got != want {
+			t.Errorf("pushUser(%q) = %q, want %q", org, got, want)
+		}
+	}
+}
@@ -1,9 +1,13 @@
 package hook
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"regexp"
 	"strings"
+
+	"sync-bot/util"
 )
 
 // Strategy strategy of sync
@@ -16,14 +20,72 @@ const (
 	Overwrite
 )
 
+// parseStrategy parses the name of a strategy, overwrite is not supported yet.
+func parseStrategy(name string) (Strategy, error) {
+	switch name {
+	case "pick":
+		return Pick, nil
+	case "merge":
+		return Merge, nil
+	}
+	return Pick, fmt.Errorf("unsupported strategy %q, use pick or merge", name)
+}
+
+// parseSyncBranchCommand parses "/sync-branch <from> <to>".
+func parseSyncBranchCommand(command string) (from, to string, err error) {
+	fields := strings.Fields(command)
+	if len(fields) != 3 {
+		return "", "", fmt.Errorf("want /sync-branch <from> <to>, got %q", strings.TrimSpace(command))
+	}
+	return fields[1], fields[2], nil
+}
+
 // SyncCmdOption /sync command option
 type SyncCmdOption struct {
 	strategy Strategy
 	branches []string
+	// dryRun previews the sync without pushing or creating pull requests.
+	dryRun bool
+	// squash syncs the commits of the pull request as one.
+	squash bool
+	// commits are the SHAs, possibly abbreviated, of the commits to sync. All
+	// the commits of the pull request are synced if empty.
+	commits []string
+	// paths are the glob patterns of the files whose changes are synced. The
+	// changes to all files are synced if empty.
+	paths []string
+}
+
+// partial tells if only some commits or files of the pull request are synced.
+func (o *SyncCmdOption) partial() bool {
+	return len(o.commits) > 0 || len(o.paths) > 0
 }
 
+// recordOptions returns the options recorded with the sync pull requests in the
+// result of /sync, for /sync-update to rebuild them alike. See parseSyncOptions.
+func (o *SyncCmdOption) recordOptions() string {
+	var options []string
+	if o.squash {
+		options = append(options, "-squash")
+	}
+	if len(o.commits) > 0 {
+		options = append(options, "-commits="+strings.Join(o.commits, ","))
+	}
+	if len(o.paths) > 0 {
+		options = append(options, "-paths="+strings.Join(o.paths, ","))
+	}
+	return strings.Join(options, " ")
+}
+
+// shaRegex matches a SHA, possibly abbreviated.
+var shaRegex = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+
 func parseSyncCommand(command string) (*SyncCmdOption, error) {
 	f := flag.NewFlagSet("/sync", flag.ContinueOnError)
+	dryRun := f.Bool("dry-run", false, "preview the sync without pushing or creating pull requests")
+	squash := f.Bool("squash", false, "sync the commits of the pull request as one")
+	commits := f.String("commits", "", "comma separated SHAs of the commits to sync")
+	paths := f.String("paths", "", "comma separated glob patterns of the files to sync")
 	sep := regexp.MustCompile(`[ \t]+`)
 	command = strings.TrimSpace(command)
 	str := sep.Split(command, -1)
@@ -31,10 +93,24 @@ func parseSyncCommand(command string) (*SyncCmdOption, error) {
 	if err != nil {
 		return nil, err
 	}
+	shas := util.SplitList(*commits)
+	for _, sha := range shas {
+		if !shaRegex.MatchString(sha) {
+			return nil, fmt.Errorf("invalid commit %q, want a SHA of at least 7 characters", sha)
+		}
+	}
+	globs := util.SplitList(*paths)
+	if *squash && (len(shas) > 0 || len(globs) > 0) {
+		return nil, errors.New("-squash can't be used with -commits or -paths")
+	}
 	// Todo: default is Merge now, will change to Pick
 	branches := f.Args()
 	return &SyncCmdOption{
 		strategy: Pick,
 		branches: branches,
+		dryRun:   *dryRun,
+		squash:   *squash,
+		commits:  shas,
+		paths:    globs,
 	}, nil
 }
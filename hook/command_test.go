@@ -67,6 +67,59 @@ func Test_parse(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "dry run",
+			args: args{
+				"/sync -dry-run branch1 branch2",
+			},
+			want: &SyncCmdOption{
+				strategy: Pick,
+				branches: []string{"branch1", "branch2"},
+				dryRun:   true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "squash",
+			args: args{
+				"/sync -squash branch1",
+			},
+			want: &SyncCmdOption{
+				strategy: Pick,
+				branches: []string{"branch1"},
+				squash:   true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "commits and paths",
+			args: args{
+				"/sync -commits=1a2b3c4d,5E6F7A8B9C -paths=*.patch, branch1",
+			},
+			want: &SyncCmdOption{
+				strategy: Pick,
+				branches: []string{"branch1"},
+				commits:  []string{"1a2b3c4d", "5E6F7A8B9C"},
+				paths:    []string{"*.patch"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid commit",
+			args: args{
+				"/sync -commits=HEAD branch1",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "squash with paths",
+			args: args{
+				"/sync -squash -paths=*.patch branch1",
+			},
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			name: "prefix blank line",
 			args: args{
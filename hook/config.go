@@ -14,13 +14,18 @@
 package hook
 
 import (
+	"errors"
+
+	"sync-bot/platform"
+	"sync-bot/util"
+
 	"github.com/opensourceways/robot-framework-lib/config"
 )
 
 // Configuration holds a list of repoConfig configurations.
 type Configuration struct {
 	ConfigItems []repoConfig `json:"config_items,omitempty"`
-	// Community name used as a request parameter to getRepoConfig sig information.
+	// LabelUsageDescriptionMap holds the descriptions of the labels of sync pull requests by name.
 	LabelUsageDescriptionMap map[string]*LabelUsageDescription `json:"-"`
 	DropBrancher             []string                          `json:"drop_brancher"`
 	// Sig information url.
@@ -28,6 +33,25 @@ type Configuration struct {
 	// Community name used as a request parameter to getRepoConfig sig information.
 	CommunityName    string `json:"community_name" required:"true"`
 	CommunityRobotID string `json:"community_robot_id"`
+	// Platform is the code hosting platform, gitcode, github, gitee or gitlab. It defaults to gitcode.
+	Platform string `json:"platform,omitempty"`
+	// PlatformHost is the host of a self-hosted platform instance.
+	PlatformHost string `json:"platform_host,omitempty"`
+	// DivergenceReport files the divergence report of protected branches on a tracking issue periodically.
+	DivergenceReport *divergenceReportConfig `json:"divergence_report,omitempty"`
+	// LabelPropagation configures the labels copied from the original pull request to the sync
+	// pull requests. It copies kind/bug, CVE/* and sig/*, drops lgtm and approved and marks them
+	// sync-bot/synced if empty.
+	LabelPropagation *labelPropagationConfig `json:"label_propagation,omitempty"`
+	// SyncReviewers selects the assignees and the reviewers of the sync pull requests. The author
+	// of the original pull request is assigned and its reviewers and the SIG maintainers are
+	// requested to review if empty.
+	SyncReviewers *reviewerSelectionConfig `json:"sync_reviewers,omitempty"`
+	// Committer is the identity of the committer of the commits synced, the authors of the
+	// original commits are kept. It is sync-bot <infra@openeuler.sh> if empty.
+	Committer *committerConfig `json:"committer,omitempty"`
+	// StaleSyncPR reminds of the sync pull requests left unmerged and closes them at last.
+	StaleSyncPR *staleSyncPRConfig `json:"stale_sync_pr,omitempty"`
 }
 
 type LabelUsageDescription struct {
@@ -45,6 +69,52 @@ func (c *Configuration) Validate() error {
 	if err != nil {
 		return err
 	}
+	_, err = platform.New(c.Platform, c.PlatformHost)
+	if err != nil {
+		return err
+	}
+	if c.DivergenceReport != nil {
+		if err = c.DivergenceReport.validate(); err != nil {
+			return err
+		}
+	}
+	if c.Committer != nil {
+		if err = c.Committer.validate(); err != nil {
+			return err
+		}
+	}
+	if c.SyncReviewers != nil {
+		if err = c.SyncReviewers.validate(); err != nil {
+			return err
+		}
+	}
+	if c.StaleSyncPR != nil {
+		if err = c.StaleSyncPR.validate(); err != nil {
+			return err
+		}
+	}
+	if c.LabelPropagation != nil {
+		if err = c.LabelPropagation.validate(); err != nil {
+			return err
+		}
+	}
+	c.LabelUsageDescriptionMap = make(map[string]*LabelUsageDescription)
+	for _, d := range c.labelPropagation().Descriptions {
+		c.LabelUsageDescriptionMap[d.LabelName] = &d
+	}
+	return nil
+}
+
+// committerConfig is the identity of a git committer.
+type committerConfig struct {
+	Name  string `json:"name" required:"true"`
+	Email string `json:"email" required:"true"`
+}
+
+func (c *committerConfig) validate() error {
+	if c.Name == "" || c.Email == "" {
+		return errors.New("committer: name and email are required")
+	}
 	return nil
 }
 
@@ -57,6 +127,44 @@ type repoConfig struct {
 	ExcludedRepos []string `json:"excluded_repos,omitempty"`
 	// LegalOperator means who can add or remove labels legally
 	LegalOperator string `json:"legal_operator"  required:"true"`
+	// Lineages are chains of branches fixes flow along, like master, openEuler-24.03-LTS-Next and
+	// openEuler-24.03-LTS-SP1. A sync pull request merged into one of them is synced to the next.
+	Lineages [][]string `json:"lineages,omitempty"`
+}
+
+// getRepoConfig returns the configuration of org/repo, or nil if it is not configured.
+func (c *Configuration) getRepoConfig(org, repo string) *repoConfig {
+	if c == nil {
+		return nil
+	}
+	fullName := org + "/" + repo
+	for i := range c.ConfigItems {
+		item := &c.ConfigItems[i]
+		if util.ContainsString(item.ExcludedRepos, fullName) {
+			continue
+		}
+		if util.ContainsString(item.Repos, fullName) || util.ContainsString(item.Repos, org) {
+			return item
+		}
+	}
+	return nil
+}
+
+// nextBranches returns the branches following branch in the lineages of org/repo.
+func (c *Configuration) nextBranches(org, repo, branch string) []string {
+	repoCnf := c.getRepoConfig(org, repo)
+	if repoCnf == nil {
+		return nil
+	}
+	var next []string
+	for _, lineage := range repoCnf.Lineages {
+		for i, b := range lineage {
+			if b == branch && i+1 < len(lineage) && !util.ContainsString(next, lineage[i+1]) {
+				next = append(next, lineage[i+1])
+			}
+		}
+	}
+	return next
 }
 
 type freezeFile struct {
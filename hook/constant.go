@@ -15,4 +15,22 @@ const (
 	createPRFailed     = "创建 PR 失败"
 	emptyCherry        = "空提交，忽略创建 PR"
 	lfsSkipped         = "包含 LFS 内容，跳过同步"
+	dryRunClean        = "可以无冲突同步"
+	dryRunConflict     = "存在冲突"
+	dryRunApplied      = "目标分支已包含当前 PR 的修改"
+	dryRunFailed       = "预检失败：%v"
+	branchInSync       = "目标分支已包含源分支的全部修改，无需同步"
+	commitPicked       = "同步"
+	commitSkipped      = "目标分支已包含该修改，跳过"
+	noPermission       = "没有目标分支的写权限，忽略处理"
+	syncUpdated        = "更新同步 PR"
+	syncPRExists       = "同步 PR 已存在"
+	noSyncRecord       = "未找到当前 PR 的同步 PR，忽略处理"
+	syncPRClosed       = "同步 PR 已合入或关闭，忽略处理"
+	syncPRPending      = "待合入"
+	syncPRMerged       = "已合入"
+	syncPRAbandoned    = "已关闭"
+	syncConflict       = "存在冲突，需手动同步"
+	commitNotFound     = "当前 PR 中没有与 %s 唯一对应的提交，忽略处理"
+	notSigMember       = "仅当前 PR 的作者、仓库所属 SIG 的 maintainer、committer 及有目标分支写权限的用户可以执行同步，忽略处理"
 )
@@ -0,0 +1,151 @@
+package hook
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"sync-bot/report"
+	"sync-bot/util"
+)
+
+// divergenceReportMarker marks the comment of the divergence report on its
+// tracking issue, which is edited for the later reports.
+const divergenceReportMarker = "<!-- sync-divergence-report -->"
+
+// divergenceReportConfig configures the divergence report filed periodically.
+type divergenceReportConfig struct {
+	Org string `json:"org"`
+	// Repos are the repositories compared, every one of Org if empty.
+	Repos []string `json:"repos,omitempty"`
+	// References are the patterns of the reference branches, master and *-LTS-Next if empty.
+	References []string `json:"references,omitempty"`
+	// Interval is the interval between two reports, like 24h.
+	Interval string `json:"interval"`
+	// Issue is the tracking issue in the form of org/repo#number.
+	Issue string `json:"issue"`
+}
+
+func (c *divergenceReportConfig) validate() error {
+	if c.Org == "" {
+		return errors.New("divergence_report: org is required")
+	}
+	if _, err := c.interval(); err != nil {
+		return err
+	}
+	_, _, _, err := c.issue()
+	return err
+}
+
+func (c *divergenceReportConfig) interval() (time.Duration, error) {
+	d, err := time.ParseDuration(c.Interval)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("divergence_report: invalid interval %q", c.Interval)
+	}
+	return d, nil
+}
+
+func (c *divergenceReportConfig) issue() (org, repo, number string, err error) {
+	fullName, number, ok := strings.Cut(c.Issue, "#")
+	org, repo, ok2 := strings.Cut(fullName, "/")
+	if !ok || !ok2 || org == "" || repo == "" || number == "" {
+		return "", "", "", fmt.Errorf("divergence_report: issue %q is not in the form of org/repo#number", c.Issue)
+	}
+	return org, repo, number, nil
+}
+
+// DivergenceReport compares the protected branches of the repositories of org,
+// every one of org if repos is empty, with the reference branches matching the
+// patterns of references.
+func (bot *robot) DivergenceReport(org string, repos []string, references []string) (*report.Report, error) {
+	if len(repos) == 0 {
+		var ok bool
+		if repos, ok = bot.cli.ListOrgRepos(org); !ok {
+			return nil, fmt.Errorf("list the repositories of %s failed", org)
+		}
+	}
+	g := report.NewGenerator(bot.GitClient, bot.protectedBranches, references, bot.log)
+	return g.Generate(org, repos), nil
+}
+
+// protectedBranches lists the protected branches of a repository but those
+// dropped by the configuration.
+func (bot *robot) protectedBranches(org, repo string) ([]string, error) {
+	branches, ok := bot.cli.ListProtectedBranches(org, repo)
+	if !ok {
+		return nil, errors.New("list protected branches failed")
+	}
+	var names []string
+	for _, b := range branches {
+		if !util.ContainsString(bot.cnf.DropBrancher, b) {
+			names = append(names, b)
+		}
+	}
+	return names, nil
+}
+
+// StartDivergenceReport files the configured divergence report on its tracking
+// issue every interval until stop is called. It does nothing if no report is
+// configured.
+func (bot *robot) StartDivergenceReport() (stop func()) {
+	c := bot.cnf.DivergenceReport
+	if c == nil {
+		return func() {}
+	}
+	interval, err := c.interval()
+	if err != nil {
+		bot.log.WithError(err).Errorln("Schedule divergence report failed")
+		return func() {}
+	}
+	return every(interval, func() {
+		bot.fileDivergenceReport(c)
+	})
+}
+
+// fileDivergenceReport edits the comment of the report on the tracking issue
+// when the report changed, it comments one if there is none yet.
+func (bot *robot) fileDivergenceReport(c *divergenceReportConfig) {
+	logger := bot.log.WithField("issue", c.Issue)
+	org, repo, number, err := c.issue()
+	if err != nil {
+		logger.WithError(err).Errorln("File divergence report failed")
+		return
+	}
+	r, err := bot.DivergenceReport(c.Org, c.Repos, c.References)
+	if err != nil {
+		logger.WithError(err).Errorln("Generate divergence report failed")
+		return
+	}
+	body, err := r.Markdown()
+	if err != nil {
+		logger.WithError(err).Errorln("Render divergence report failed")
+		return
+	}
+	body += "\n" + divergenceReportMarker + "\n"
+
+	comments, ok := bot.cli.ListIssueCommentsWithID(org, repo, number)
+	if !ok {
+		logger.Errorln("List issue comments failed")
+		return
+	}
+	for _, comment := range comments {
+		if !strings.Contains(comment.Body, divergenceReportMarker) {
+			continue
+		}
+		switch {
+		case comment.Body == body:
+			logger.Infoln("Divergence report not changed.")
+		case bot.cli.EditIssueComment(org, repo, number, comment.ID, body):
+			logger.Infoln("Update divergence report.")
+		default:
+			logger.Errorln("Edit comment failed")
+		}
+		return
+	}
+	if !bot.cli.CreateIssueComment(org, repo, number, body) {
+		logger.Errorln("Create comment failed")
+		return
+	}
+	logger.Infoln("File divergence report.")
+}
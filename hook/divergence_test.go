@@ -0,0 +1,91 @@
+package hook
+
+import (
+	"strings"
+	"testing"
+
+	"sync-bot/fake"
+)
+
+func TestDivergenceReportConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       divergenceReportConfig
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			c:    divergenceReportConfig{Org: "o", Repos: []string{"r"}, Interval: "24h", Issue: "o/tracking#5"},
+		},
+		{
+			name: "every repo",
+			c:    divergenceReportConfig{Org: "o", Interval: "24h", Issue: "o/tracking#5"},
+		},
+		{
+			name:    "no org",
+			c:       divergenceReportConfig{Repos: []string{"r"}, Interval: "24h", Issue: "o/tracking#5"},
+			wantErr: true,
+		},
+		{
+			name:    "bad interval",
+			c:       divergenceReportConfig{Org: "o", Repos: []string{"r"}, Interval: "daily", Issue: "o/tracking#5"},
+			wantErr: true,
+		},
+		{
+			name:    "bad issue",
+			c:       divergenceReportConfig{Org: "o", Repos: []string{"r"}, Interval: "24h", Issue: "tracking#5"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.c.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFileDivergenceReport(t *testing.T) {
+	bot, cli, f := newSyncScenario(t)
+	// old isn't protected, it is left out
+	cli.Protected[fake.Key("o", "r", "master")] = true
+	cli.Protected[fake.Key("o", "r", "stable")] = true
+	c := &divergenceReportConfig{Org: "o", Interval: "1h", Issue: "o/tracking#5"}
+
+	bot.fileDivergenceReport(c)
+	if len(cli.IssueComments) != 1 || cli.IssueComments[0].Repo != "tracking" || cli.IssueComments[0].Number != "5" {
+		t.Fatalf("issue comments %v, want the report on o/tracking#5", cli.IssueComments)
+	}
+	report := cli.IssueComments[0].Body
+	if !strings.Contains(report, "## r\n\nAll branches are in sync") || !strings.Contains(report, divergenceReportMarker) {
+		t.Errorf("report %q, want r in sync and marked", report)
+	}
+
+	if bot.fileDivergenceReport(c); len(cli.IssueComments) != 1 || len(cli.EditedComments) != 0 {
+		t.Errorf("filed the same report again: %v %v", cli.IssueComments, cli.EditedComments)
+	}
+
+	dir := f.work + "/o/r"
+	f.git(dir, "checkout", "master")
+	f.commit(dir, "fix b", map[string]string{"b.txt": "fixed\n"})
+	f.git(dir, "push", "origin", "master")
+	bot.fileDivergenceReport(c)
+	if len(cli.IssueComments) != 1 || len(cli.EditedComments) != 1 {
+		t.Fatalf("issue comments %v, edited %v, want the report edited in place", cli.IssueComments, cli.EditedComments)
+	}
+	report = cli.Comments[fake.Key("o", "tracking", "5")][0].Body
+	for _, want := range []string{"|stable|master|", "fix b", divergenceReportMarker} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report %q doesn't hold %q", report, want)
+		}
+	}
+	if strings.Contains(report, "|old|") {
+		t.Errorf("report %q holds the unprotected branch old", report)
+	}
+
+	cli.Fail["ListOrgRepos"] = true
+	if bot.fileDivergenceReport(c); len(cli.IssueComments) != 1 || len(cli.EditedComments) != 1 {
+		t.Errorf("filed a report without the repositories: %v %v", cli.IssueComments, cli.EditedComments)
+	}
+}
@@ -0,0 +1,158 @@
+package hook
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"sync-bot/git"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// dryRun previews a sync: the commits of the pull request are picked onto each
+// branch in a throwaway worktree, nothing is pushed and no pull request is
+// created. It works on open pull requests as well.
+func (bot *robot) dryRun(evt *client.GenericEvent, user string, command string, opt *SyncCmdOption, logger *logrus.Entry) error {
+	org := utils.GetString(evt.Org)
+	repo := utils.GetString(evt.Repo)
+	number := utils.GetString(evt.Number)
+
+	status, err := bot.dryRunPullRequest(org, repo, number, opt, logger)
+	if err != nil {
+		return err
+	}
+
+	comment, err := executeTemplate(syncDryRunResultTmpl, struct {
+		URL        string
+		User       string
+		Command    string
+		SyncStatus []dryRunStatus
+	}{
+		URL:        utils.GetString(evt.HtmlURL),
+		User:       user,
+		Command:    strings.TrimSpace(command),
+		SyncStatus: status,
+	})
+	if err != nil {
+		logger.Errorln("Execute template failed:", err)
+		return err
+	}
+	if !bot.cli.CreatePRComment(org, repo, number, comment) {
+		logger.Errorln("Create comment failed")
+		return errors.New("create comment failed")
+	}
+	logger.Infoln("Reply sync dry run.")
+	return nil
+}
+
+// dryRunPullRequest picks the commits of the pull request onto every branch of
+// opt and returns how it went.
+func (bot *robot) dryRunPullRequest(org, repo, number string, opt *SyncCmdOption, logger *logrus.Entry) ([]dryRunStatus, error) {
+	prNumber, err := strconv.Atoi(number)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pull request number: %s", number)
+	}
+
+	commits, ok := bot.cli.GetPullRequestCommits(org, repo, number)
+	if !ok {
+		logger.Errorln("List commits failed")
+		return nil, errors.New("list commits failed")
+	}
+	if len(commits) == 0 {
+		logger.Errorln("Pull request has no commits")
+		return nil, errors.New("pull request has no commits")
+	}
+	branches, ok := bot.cli.GetRepoAllBranch(org, repo)
+	if !ok {
+		logger.Errorln("List branches failed")
+		return nil, errors.New("list branches failed")
+	}
+	branchSet := make(map[string]bool)
+	for _, b := range branches {
+		branchSet[b.Name] = true
+	}
+
+	r, err := bot.GitClient.Clone(org, repo)
+	if err != nil {
+		logger.Errorf("Clone %s/%s failed: %v", org, repo, err)
+		return nil, err
+	}
+	if err = r.FetchPullRequest(prNumber); err != nil {
+		logger.Errorf("Fetch pull request failed: %v", err)
+		return nil, err
+	}
+
+	commits, unknown := selectCommits(commits, opt.commits)
+	shas := commitSHAs(commits)
+	var status []dryRunStatus
+	for _, branch := range opt.branches {
+		if !branchSet[branch] {
+			status = append(status, dryRunStatus{Name: branch, Status: branchNonExist})
+			continue
+		}
+		if unknown != "" {
+			status = append(status, dryRunStatus{Name: branch, Status: fmt.Sprintf(commitNotFound, unknown)})
+			continue
+		}
+		st := dryRunPick(r, "origin/"+branch, shas, opt.paths)
+		st.Name = branch
+		status = append(status, st)
+	}
+	return status, nil
+}
+
+// dryRunPick picks the commits shas, oldest first, onto target in a throwaway
+// worktree and tells how it went. Only the changes to the files matching paths
+// are picked unless paths is empty.
+func dryRunPick(r *git.Repo, target string, shas, paths []string) dryRunStatus {
+	first, last := shas[0], shas[len(shas)-1]
+	// shas may leave out some commits between first and last, those applied
+	// on target as well tell nothing is left to pick
+	pending, _, err := r.Cherry(target, first, last)
+	if err != nil {
+		return dryRunStatus{Status: syncFailed, Detail: err.Error()}
+	}
+	if len(pending) == 0 {
+		return dryRunStatus{Status: dryRunApplied}
+	}
+
+	dir, err := os.MkdirTemp("", "sync-bot-dry-run-*")
+	if err != nil {
+		return dryRunStatus{Status: syncFailed, Detail: err.Error()}
+	}
+	defer os.RemoveAll(dir)
+	wt, err := r.AddWorktree(dir, target)
+	if err != nil {
+		return dryRunStatus{Status: syncFailed, Detail: err.Error()}
+	}
+	defer func() {
+		if err := r.RemoveWorktree(dir); err != nil {
+			logrus.WithError(err).Warnln("Remove dry run worktree failed")
+		}
+	}()
+
+	if err = wt.CherryPickPaths(shas, paths); err != nil {
+		files, _ := wt.ConflictFiles()
+		if len(files) > 0 {
+			return dryRunStatus{Status: dryRunConflict, Detail: strings.Join(files, "<br>")}
+		}
+		// a commit already applied on target leaves nothing to pick
+		if strings.Contains(err.Error(), "empty") {
+			return dryRunStatus{Status: emptyCherry}
+		}
+		return dryRunStatus{Status: syncFailed, Detail: err.Error()}
+	}
+	changed, err := wt.HasDiff(target, "HEAD")
+	if err != nil {
+		return dryRunStatus{Status: syncFailed, Detail: err.Error()}
+	}
+	if !changed {
+		return dryRunStatus{Status: emptyCherry}
+	}
+	return dryRunStatus{Status: dryRunClean}
+}
@@ -0,0 +1,136 @@
+package hook
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sync-bot/fake"
+	"sync-bot/git"
+	"sync-bot/platform"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/sirupsen/logrus"
+)
+
+// gitFixture serves bare repositories from a local directory, so the git
+// client clones, fetches and pushes through file URLs.
+type gitFixture struct {
+	t *testing.T
+	// base holds the bare repositories as <org>/<repo>.git.
+	base string
+	// work holds the working copies the fixture commits with.
+	work string
+}
+
+func newGitFixture(t *testing.T) *gitFixture {
+	return &gitFixture{t: t, base: t.TempDir(), work: t.TempDir()}
+}
+
+// git runs git in dir and returns its trimmed output.
+func (f *gitFixture) git(dir string, arg ...string) string {
+	f.t.Helper()
+	cmd := exec.Command("git", arg...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=alice", "GIT_AUTHOR_EMAIL=alice@example.com",
+		"GIT_COMMITTER_NAME=alice", "GIT_COMMITTER_EMAIL=alice@example.com",
+		"GIT_CONFIG_NOSYSTEM=1", "HOME="+f.work)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		f.t.Fatalf("git %v failed, output: %q, error: %v", arg, string(out), err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// repo creates the bare repository org/repo with an initial commit on master
+// and returns a working copy of it.
+func (f *gitFixture) repo(org, repo string, files map[string]string) string {
+	f.t.Helper()
+	bare := filepath.Join(f.base, org, repo+".git")
+	f.git("", "init", "--bare", "--initial-branch=master", bare)
+	dir := filepath.Join(f.work, org, repo)
+	f.git("", "clone", bare, dir)
+	f.git(dir, "symbolic-ref", "HEAD", "refs/heads/master")
+	f.commit(dir, "init", files)
+	f.git(dir, "push", "origin", "master")
+	return dir
+}
+
+// commit writes files in the working copy dir and commits them, it returns the sha.
+func (f *gitFixture) commit(dir, message string, files map[string]string) string {
+	f.t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			f.t.Fatal(err)
+		}
+	}
+	f.git(dir, "add", "-A")
+	f.git(dir, "commit", "-m", message)
+	return f.git(dir, "rev-parse", "HEAD")
+}
+
+// pullRequest pushes the head of the working copy as the head ref of pull request number.
+func (f *gitFixture) pullRequest(dir string, number string) {
+	f.git(dir, "push", "--force", "origin", "HEAD:refs/merge-requests/"+number+"/head")
+}
+
+// rejectPushes makes the bare repository org/repo refuse every push.
+func (f *gitFixture) rejectPushes(org, repo string) {
+	hook := filepath.Join(f.base, org, repo+".git", "hooks", "pre-receive")
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\necho rejected by fixture\nexit 1\n"), 0755); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+// show returns the content of a file on a branch of the bare repository org/repo.
+func (f *gitFixture) show(org, repo, branch, name string) string {
+	return f.git(filepath.Join(f.base, org, repo+".git"), "show", branch+":"+name)
+}
+
+// mergePullRequest records head as the ref of pull request number in the bare
+// repository org/repo and fast-forwards base to it, like merging a sync pull
+// request does. It returns the commits of the pull request as the platform
+// lists them.
+func (f *gitFixture) mergePullRequest(org, repo, number, head, base string) []client.PRCommit {
+	bare := filepath.Join(f.base, org, repo+".git")
+	var commits []client.PRCommit
+	for _, line := range strings.Split(f.git(bare, "log", "--format=%H %s", base+".."+head), "\n") {
+		if sha, subject, ok := strings.Cut(line, " "); ok {
+			commits = append(commits, commitOf(sha, subject))
+		}
+	}
+	f.git(bare, "update-ref", "refs/merge-requests/"+number+"/head", "refs/heads/"+head)
+	f.git(bare, "update-ref", "refs/heads/"+base, "refs/heads/"+head)
+	return commits
+}
+
+// newTestBot returns a robot talking to a fake platform and the repositories of f.
+func newTestBot(t *testing.T, f *gitFixture) (*robot, *fake.Client) {
+	gitClient, err := git.NewClientWithBase("file://"+f.base, t.TempDir())
+	if err != nil {
+		t.Fatalf("New git client failed: %v", err)
+	}
+	t.Cleanup(func() { _ = gitClient.Clean() })
+	gitClient.SetCredentials("sync-bot", func() []byte { return []byte("token") })
+
+	p, err := platform.New(platform.GitCode, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli := fake.NewClient()
+	return &robot{
+		cli:       cli,
+		cnf:       &Configuration{},
+		log:       logrus.NewEntry(logrus.StandardLogger()),
+		GitClient: gitClient,
+		platform:  p,
+	}, cli
+}
+
+// commitOf returns a pull request commit as the platform lists it.
+func commitOf(sha, message string) client.PRCommit {
+	return client.PRCommit{SHA: sha, Message: message, CommitTime: "2024-01-02T03:04:05+08:00"}
+}
@@ -0,0 +1,73 @@
+package hook
+
+import (
+	"errors"
+	"regexp"
+
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// syncIssueLinksRegex matches the marker of the comment listing the sync pull
+// requests of a pull request on an issue.
+var syncIssueLinksRegex = regexp.MustCompile(`<!-- sync-issue-links: (\S+) -->`)
+
+// linkSyncIssues shows the entries of the status board of the pull request
+// number on every issue linked to it.
+func (bot *robot) linkSyncIssues(org, repo, number string, entries []boardEntry, logger *logrus.Entry) error {
+	issues, ok := bot.cli.GetPRLinkedIssue(org, repo, number)
+	if !ok {
+		return errors.New("list linked issues failed")
+	}
+	if len(issues) == 0 {
+		return nil
+	}
+	pr, ok := bot.cli.GetPullRequest(org, repo, number)
+	if !ok {
+		return errors.New("get pull request failed")
+	}
+
+	comment, err := executeTemplate(syncIssueLinksTmpl, struct {
+		PR      string
+		Entries []boardEntry
+	}{
+		PR:      utils.GetString(pr.URL),
+		Entries: entries,
+	})
+	if err != nil {
+		return err
+	}
+	for _, issue := range issues {
+		owner, issueRepo, issueNumber, ok := bot.platform.ParseIssueURL(issue.HtmlURL)
+		if !ok {
+			logger.Warnf("Unknown issue URL %s", issue.HtmlURL)
+			continue
+		}
+		if err := bot.commentSyncIssue(owner, issueRepo, issueNumber, utils.GetString(pr.URL), comment); err != nil {
+			logger.WithError(err).Errorf("Comment on issue %s failed", issue.HtmlURL)
+		}
+	}
+	return nil
+}
+
+// commentSyncIssue edits the comment listing the sync pull requests of the pull
+// request prURL on an issue, it is created if there is none yet.
+func (bot *robot) commentSyncIssue(org, repo, number, prURL, comment string) error {
+	comments, ok := bot.cli.ListIssueCommentsWithID(org, repo, number)
+	if !ok {
+		return errors.New("list issue comments failed")
+	}
+	for _, c := range comments {
+		if m := syncIssueLinksRegex.FindStringSubmatch(c.Body); m != nil && m[1] == prURL {
+			if bot.cli.EditIssueComment(org, repo, number, c.ID, comment) {
+				return nil
+			}
+			logrus.Warnf("Edit the sync pull requests on %s/%s#%s failed, comment a new one", org, repo, number)
+			break
+		}
+	}
+	if !bot.cli.CreateIssueComment(org, repo, number, comment) {
+		return errors.New("create comment failed")
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package hook
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sync-bot/fake"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLinkSyncIssues(t *testing.T) {
+	bot, cli, f := newSyncScenario(t)
+	logger := logrus.NewEntry(logrus.StandardLogger())
+	bare := filepath.Join(f.base, "o", "r.git")
+	f.git(bare, "branch", "next", "master")
+	cli.Branches[fake.RepoKey("o", "r")] = append(cli.Branches[fake.RepoKey("o", "r")], "next")
+	cli.Issues[fake.Key("o", "r", "1")] = []client.Issue{{HtmlURL: "https://gitcode.com/o/r/issues/7"}}
+	stablePR, nextPR := bot.platform.PullRequestURL("o", "r", "1000"), bot.platform.PullRequestURL("o", "r", "1001")
+
+	bot.handlePullRequestCommentEvent(commentEvent("/sync stable next", "merged"), nil, logger)
+
+	// the comment is created once and edited in place after
+	assertIssueComment := func(edits int, want ...string) {
+		t.Helper()
+		edited := 0
+		for _, e := range cli.EditedComments {
+			if e.Number == "7" {
+				edited++
+			}
+		}
+		if len(cli.IssueComments) != 1 || edited != edits {
+			t.Fatalf("got %d issue comments and %d edits, want 1 and %d", len(cli.IssueComments), edited, edits)
+		}
+		if c := cli.IssueComments[0]; c.Org != "o" || c.Repo != "r" || c.Number != "7" {
+			t.Errorf("commented on %s/%s#%s, want o/r#7", c.Org, c.Repo, c.Number)
+		}
+		comments := cli.Comments[fake.Key("o", "r", "7")]
+		if len(comments) != 1 {
+			t.Fatalf("issue o/r#7 has %d comments, want 1", len(comments))
+		}
+		for _, w := range want {
+			if !strings.Contains(comments[0].Body, w) {
+				t.Errorf("issue comment %q doesn't hold %q", comments[0].Body, w)
+			}
+		}
+	}
+	assertIssueComment(0, "|stable|"+stablePR+"|"+syncPRPending+"|", "|next|"+nextPR+"|"+syncPRPending+"|")
+
+	// the sync pull request to stable is merged, sync-bot prunes its branch
+	stable := cli.CreatedPRs[0]
+	f.mergePullRequest("o", "r", "1000", utils.GetString(stable.Head), "stable")
+	cli.SetPullRequestState("o", "r", "1000", "merged")
+	evt := mergeEvent("1000", utils.GetString(stable.Title), "stable")
+	evt.Head = stable.Head
+	bot.handlePREvent(evt, nil, logger)
+	assertIssueComment(1, "|stable|"+stablePR+"|"+syncPRMerged+"|", "|next|"+nextPR+"|"+syncPRPending+"|")
+
+	// the one to next is closed, stable stays merged on the status board
+	if branches := f.git(bare, "branch", "--list", utils.GetString(stable.Head)); branches != "" {
+		t.Errorf("branch %s is not pruned after the merge", branches)
+	}
+	next := cli.CreatedPRs[1]
+	cli.SetPullRequestState("o", "r", "1001", "closed")
+	org, repo, number, base, title, action, state := "o", "r", "1001", "next", utils.GetString(next.Title), "close", "closed"
+	bot.handlePREvent(&client.GenericEvent{Org: &org, Repo: &repo, Number: &number, Base: &base, Head: next.Head,
+		Title: &title, Action: &action, State: &state}, nil, logger)
+	assertIssueComment(2, "|stable|"+stablePR+"|"+syncPRMerged+"|", "|next|"+nextPR+"|"+syncPRAbandoned+"|")
+}
@@ -0,0 +1,101 @@
+package hook
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/sirupsen/logrus"
+)
+
+// labelPropagationConfig configures the labels of the sync pull requests.
+type labelPropagationConfig struct {
+	// Copy are the patterns of the labels of the original pull request copied
+	// to its sync pull requests, like kind/bug, CVE/* and sig/*. They are
+	// matched as by path.Match, a * doesn't match a slash.
+	Copy []string `json:"copy,omitempty"`
+	// Drop are the patterns of the labels never copied even if they match Copy,
+	// like the workflow labels lgtm and approved.
+	Drop []string `json:"drop,omitempty"`
+	// Marker is added to every sync pull request, like sync-bot/synced.
+	Marker string `json:"marker,omitempty"`
+	// Descriptions describe the labels in the body of the sync pull requests.
+	Descriptions []LabelUsageDescription `json:"descriptions,omitempty"`
+}
+
+// defaultLabelPropagation is used when no label propagation is configured.
+var defaultLabelPropagation = labelPropagationConfig{
+	Copy:   []string{"kind/bug", "CVE/*", "sig/*"},
+	Drop:   []string{"lgtm", "approved"},
+	Marker: "sync-bot/synced",
+}
+
+func (c *labelPropagationConfig) validate() error {
+	for _, pattern := range append(append([]string{}, c.Copy...), c.Drop...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("label_propagation: invalid pattern %q", pattern)
+		}
+	}
+	for _, d := range c.Descriptions {
+		if d.LabelName == "" {
+			return fmt.Errorf("label_propagation: label_name of the description %q is required", d.Description)
+		}
+	}
+	return nil
+}
+
+// syncLabels returns the labels of a sync pull request of a pull request
+// labeled with labels.
+func (c *labelPropagationConfig) syncLabels(labels []string) []string {
+	var result []string
+	for _, l := range labels {
+		if matchAny(c.Copy, l) && !matchAny(c.Drop, l) {
+			result = append(result, l)
+		}
+	}
+	if c.Marker != "" {
+		result = append(result, c.Marker)
+	}
+	return result
+}
+
+func matchAny(patterns []string, label string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, label); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// labelPropagation returns the label propagation configured, the default one
+// if none is.
+func (c *Configuration) labelPropagation() *labelPropagationConfig {
+	if c == nil || c.LabelPropagation == nil {
+		return &defaultLabelPropagation
+	}
+	return c.LabelPropagation
+}
+
+// describeLabels returns the labels with their descriptions, if any.
+func (c *Configuration) describeLabels(labels []string) []LabelUsageDescription {
+	result := make([]LabelUsageDescription, 0, len(labels))
+	for _, l := range labels {
+		d := LabelUsageDescription{LabelName: l}
+		if c != nil && c.LabelUsageDescriptionMap[l] != nil {
+			d.Description = c.LabelUsageDescriptionMap[l].Description
+		}
+		result = append(result, d)
+	}
+	return result
+}
+
+// labelSyncPR adds labels to the sync pull request number, a failure leaves
+// it unlabeled but created.
+func (bot *robot) labelSyncPR(org, repo, number string, labels []string) {
+	if len(labels) == 0 {
+		return
+	}
+	if !bot.cli.AddPRLabels(org, repo, number, labels) {
+		logrus.Errorf("Add labels %v to %s/%s#%s failed", labels, org, repo, number)
+	}
+}
@@ -0,0 +1,85 @@
+package hook
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"sync-bot/fake"
+
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSyncLabels(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *labelPropagationConfig
+		labels []string
+		want   []string
+	}{
+		{
+			name:   "default",
+			labels: []string{"kind/bug", "lgtm", "sig/Kernel", "CVE/CVE-2024-1", "approved", "priority/high"},
+			want:   []string{"kind/bug", "sig/Kernel", "CVE/CVE-2024-1", "sync-bot/synced"},
+		},
+		{
+			name:   "drop wins over copy",
+			config: &labelPropagationConfig{Copy: []string{"*", "*/*"}, Drop: []string{"lgtm", "approved", "ci_*"}},
+			labels: []string{"kind/bug", "lgtm", "ci_failed", "priority/high"},
+			want:   []string{"kind/bug", "priority/high"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Configuration{LabelPropagation: tt.config}
+			if got := c.labelPropagation().syncLabels(tt.labels); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("syncLabels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateLabelPropagation(t *testing.T) {
+	c := &Configuration{
+		ConfigItems:   []repoConfig{{Repos: []string{"o/r"}, LegalOperator: "bot"}},
+		SigInfoURL:    "https://sig.example.com",
+		CommunityName: "openeuler",
+		LabelPropagation: &labelPropagationConfig{
+			Copy:         []string{"kind/bug"},
+			Descriptions: []LabelUsageDescription{{LabelName: "kind/bug", Description: "a bug fix"}},
+		},
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if d := c.LabelUsageDescriptionMap["kind/bug"]; d == nil || d.Description != "a bug fix" {
+		t.Errorf("description of kind/bug = %v", d)
+	}
+
+	c.LabelPropagation.Copy = []string{"kind/["}
+	if err := c.Validate(); err == nil {
+		t.Error("Validate() of a bad pattern succeeded")
+	}
+}
+
+func TestSyncPropagatesLabels(t *testing.T) {
+	bot, cli, _ := newSyncScenario(t)
+	bot.cnf.LabelUsageDescriptionMap = map[string]*LabelUsageDescription{
+		"kind/bug": {LabelName: "kind/bug", Description: "a bug fix"},
+	}
+	cli.Labels[fake.Key("o", "r", "1")] = []string{"kind/bug", "lgtm", "approved"}
+
+	bot.handlePullRequestCommentEvent(commentEvent("/sync stable", "merged"), nil, logrus.NewEntry(logrus.StandardLogger()))
+
+	if len(cli.CreatedPRs) != 1 {
+		t.Fatalf("created %v, want the sync pull request to stable", cli.CreatedPRs)
+	}
+	number := utils.GetString(cli.CreatedPRs[0].Number)
+	if got, want := cli.Labels[fake.Key("o", "r", number)], []string{"kind/bug", "sync-bot/synced"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("labels of the sync pull request = %v, want %v", got, want)
+	}
+	if body := utils.GetString(cli.CreatedPRs[0].Body); !strings.Contains(body, "|kind/bug|a bug fix|") {
+		t.Errorf("body %q doesn't describe kind/bug", body)
+	}
+}
@@ -0,0 +1,106 @@
+package hook
+
+import (
+	"errors"
+	"path"
+	"strings"
+
+	"sync-bot/git"
+
+	"github.com/sirupsen/logrus"
+)
+
+// lfsRule is a line of .gitattributes setting or unsetting the filter of the
+// files matching pattern.
+type lfsRule struct {
+	pattern string
+	lfs     bool
+}
+
+// parseLFSRules returns the rules of .gitattributes deciding which files are
+// stored in Git LFS, in the order they are given.
+func parseLFSRules(attributes string) []lfsRule {
+	var rules []lfsRule
+	for _, line := range strings.Split(attributes, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		for _, attr := range fields[1:] {
+			if attr == "filter" || attr == "-filter" || attr == "!filter" || strings.HasPrefix(attr, "filter=") {
+				rules = append(rules, lfsRule{pattern: fields[0], lfs: attr == "filter=lfs"})
+			}
+		}
+	}
+	return rules
+}
+
+// lfsTracked tells if the file at name is stored in Git LFS by rules, the last
+// rule matching it wins.
+func lfsTracked(rules []lfsRule, name string) bool {
+	tracked := false
+	for _, rule := range rules {
+		if matchAttributePattern(rule.pattern, name) {
+			tracked = rule.lfs
+		}
+	}
+	return tracked
+}
+
+// matchAttributePattern matches name, a path relative to the top of the
+// repository, against a pattern of .gitattributes. A pattern without a slash
+// matches the base name at any depth, "dir/**" everything under dir.
+func matchAttributePattern(pattern, name string) bool {
+	pattern = strings.TrimPrefix(pattern, "**/")
+	if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+		return strings.HasPrefix(name, strings.TrimPrefix(prefix, "/")+"/")
+	}
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	ok, _ := path.Match(strings.TrimPrefix(pattern, "/"), name)
+	return ok
+}
+
+// lfsFiles returns the files changed by the pull request that the
+// .gitattributes at commitLike, the head of the pull request, stores in Git
+// LFS. Only the .gitattributes at the top of the repository is read.
+func (bot *robot) lfsFiles(r *git.Repo, org, repo, number, commitLike string) ([]string, error) {
+	attributes, err := r.ReadFile(commitLike, ".gitattributes")
+	if err != nil {
+		// nothing is stored in Git LFS without .gitattributes
+		return nil, nil
+	}
+	rules := parseLFSRules(string(attributes))
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	changes, ok := bot.cli.GetPullRequestChanges(org, repo, number)
+	if !ok {
+		return nil, errors.New("list changed files failed")
+	}
+	var files []string
+	for _, c := range changes {
+		if lfsTracked(rules, c.Filename) {
+			files = append(files, c.Filename)
+		}
+	}
+	return files, nil
+}
+
+// prepareLFS fetches the Git LFS objects of the pull request whose last commit
+// is head, so that they are pushed with the sync branches. It returns false if
+// git lfs isn't installed or the objects can't be fetched, the pull request
+// can't be synced then.
+func prepareLFS(r *git.Repo, head string) bool {
+	if !r.LFSAvailable() {
+		logrus.Warnln("The pull request changes files stored in Git LFS, but git lfs is not installed")
+		return false
+	}
+	if err := r.LFSFetch(head); err != nil {
+		logrus.WithError(err).Warnln("Fetch LFS objects failed")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+package hook
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sync-bot/fake"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLFSTracked(t *testing.T) {
+	rules := parseLFSRules(`# large files
+*.tar.gz filter=lfs diff=lfs merge=lfs -text
+/top.bin filter=lfs
+assets/** filter=lfs
+keep.tar.gz -filter
+docs/*.pdf filter=lfs
+`)
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"src.tar.gz", true},
+		{"sub/src.tar.gz", true},
+		{"keep.tar.gz", false},
+		{"top.bin", true},
+		{"sub/top.bin", false},
+		{"assets/a/b.png", true},
+		{"docs/a.pdf", true},
+		{"docs/sub/a.pdf", false},
+		{"a.spec", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lfsTracked(rules, tt.name); got != tt.want {
+				t.Errorf("lfsTracked(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeLFS puts a git-lfs on PATH recording its arguments in the returned file,
+// it fails unless installed.
+func fakeLFS(t *testing.T, installed bool) string {
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls")
+	code := "0"
+	if !installed {
+		code = "1"
+	}
+	script := "#!/bin/sh\necho \"$@\" >> " + calls + "\nexit " + code + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "git-lfs"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return calls
+}
+
+func TestSyncLFS(t *testing.T) {
+	tests := []struct {
+		name      string
+		installed bool
+		status    string
+		// calls are the git lfs commands expected, <head> stands for the last
+		// commit of the pull request.
+		calls []string
+	}{
+		{
+			name:      "git lfs installed",
+			installed: true,
+			status:    createdPR,
+			calls:     []string{"version", "fetch origin <head>", "push origin sync-pr1-feature-to-stable"},
+		},
+		{
+			name:   "git lfs missing",
+			status: lfsSkipped,
+			calls:  []string{"version"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bot, cli, f := newSyncScenario(t)
+			dir := filepath.Join(f.work, "o", "r")
+			head := f.commit(dir, "add tarball", map[string]string{
+				".gitattributes": "*.tar.gz filter=lfs diff=lfs merge=lfs -text\n",
+				"src.tar.gz":     "version https://git-lfs.github.com/spec/v1\noid sha256:0123\nsize 4\n",
+			})
+			f.pullRequest(dir, "1")
+			key := fake.Key("o", "r", "1")
+			cli.Commits[key] = append([]client.PRCommit{commitOf(head, "add tarball")}, cli.Commits[key]...)
+			cli.Changes[key] = []client.CommitFile{{Filename: "a.txt"}, {Filename: "c.txt"},
+				{Filename: ".gitattributes"}, {Filename: "src.tar.gz"}}
+			calls := fakeLFS(t, tt.installed)
+
+			bot.handlePullRequestCommentEvent(commentEvent("/sync stable", "merged"), nil,
+				logrus.NewEntry(logrus.StandardLogger()))
+
+			if len(cli.PRComments) == 0 || !strings.Contains(cli.PRComments[0].Body, "|stable|"+tt.status+"|") {
+				t.Errorf("result comments %v, want stable %s", cli.PRComments, tt.status)
+			}
+			created := 0
+			if tt.status == createdPR {
+				created = 1
+			}
+			if len(cli.CreatedPRs) != created {
+				t.Errorf("created %d pull requests, want %d", len(cli.CreatedPRs), created)
+			}
+			out, err := os.ReadFile(calls)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := strings.ReplaceAll(strings.Join(tt.calls, "\n"), "<head>", head)
+			if got := strings.TrimSpace(string(out)); got != want {
+				t.Errorf("git lfs calls = %q, want %q", got, want)
+			}
+			if tt.status == createdPR && f.show("o", "r", utils.GetString(cli.CreatedPRs[0].Head), "src.tar.gz") == "" {
+				t.Error("the LFS pointer is not synced")
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package hook
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SyncResult is the result of syncing a pull request to a branch.
+type SyncResult struct {
+	Branch string
+	Status string
+	// PullRequest is the URL of the sync pull request, if one was created.
+	PullRequest string
+	// Detail tells more about the status, like the conflicting files of a dry run.
+	Detail string
+}
+
+// SyncPullRequest syncs pull request number of org/repo to branches like the
+// /sync command does after the merge, for re-running a sync whose webhook was
+// lost. strategy is pick or merge. With dryRun the sync is only previewed in
+// throwaway worktrees. The results are returned, nothing is commented.
+func (bot *robot) SyncPullRequest(org, repo, number string, branches []string, strategy string, dryRun bool) ([]SyncResult, error) {
+	st, err := parseStrategy(strategy)
+	if err != nil {
+		return nil, err
+	}
+	if len(branches) == 0 {
+		return nil, fmt.Errorf("no branches to sync %s/%s#%s to", org, repo, number)
+	}
+	opt := &SyncCmdOption{strategy: st, branches: branches, dryRun: dryRun}
+	logger := bot.log.WithFields(logrus.Fields{
+		"org":    org,
+		"repo":   repo,
+		"number": number,
+	})
+
+	var results []SyncResult
+	if dryRun {
+		status, err := bot.dryRunPullRequest(org, repo, number, opt, logger)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range status {
+			results = append(results, SyncResult{Branch: s.Name, Status: s.Status, Detail: s.Detail})
+		}
+		return results, nil
+	}
+
+	status, err := bot.syncPullRequest(org, repo, number, opt, nil, logger)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range status {
+		results = append(results, SyncResult{Branch: s.Name, Status: s.Status, PullRequest: s.PR})
+	}
+	return results, nil
+}
+
+// SyncBranch opens one pull request carrying the commits of from missing from
+// to, like the /sync-branch command does. The result is returned, nothing is
+// commented.
+func (bot *robot) SyncBranch(org, repo, from, to string) (*BranchSyncResult, error) {
+	logger := bot.log.WithFields(logrus.Fields{
+		"org":  org,
+		"repo": repo,
+	})
+	return bot.syncBranch(org, repo, from, to, logger)
+}
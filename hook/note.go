@@ -31,12 +31,10 @@ func (bot *robot) greeting(org string, repo string, number string, targetBranch
 	}
 	branchesExt := make([]branchExt, len(branches))
 	for i, branch := range branches {
-		if branchesExt[i].Name == targetBranch {
-			branches[i].Name = fmt.Sprintf("__*__ [%s](https://gitcode.com/%s/%s/tree/%s)",
-				branch.Name, org, repo, branch.Name)
+		if branch.Name == targetBranch {
+			branchesExt[i].Name = fmt.Sprintf("__*__ [%s](%s)", branch.Name, bot.platform.TreeURL(org, repo, branch.Name))
 		} else {
-			branchesExt[i].Name = fmt.Sprintf("[%s](https://gitcode.com/%s/%s/tree/%s)",
-				branch.Name, org, repo, branch.Name)
+			branchesExt[i].Name = fmt.Sprintf("[%s](%s)", branch.Name, bot.platform.TreeURL(org, repo, branch.Name))
 		}
 		// extract Version and Release from spec file
 		spec, ok := bot.cli.GetPathContent(org, repo, repo+".spec", branch.Name)
@@ -150,6 +148,18 @@ func (bot *robot) NotePullRequest(evt *client.GenericEvent, logger *logrus.Entry
 
 	if util.MatchSync(comment) {
 		logger.Infoln("Receive /sync command")
+		if !bot.canSync(org, repo, number, user, logger) {
+			logger.Infof("%s may not sync the pull request.", user)
+			bot.replySyncStatus(evt, user, comment, notSigMember, logger)
+			return
+		}
+		if opt, err := parseSyncCommand(comment); err == nil && opt.dryRun {
+			if err = bot.dryRun(evt, user, comment, opt, logger); err != nil {
+				logger.WithError(err).Errorln("Dry run failed")
+				bot.replySyncStatus(evt, user, comment, fmt.Sprintf(dryRunFailed, err), logger)
+			}
+			return
+		}
 		switch state {
 		case "opened":
 			logger.Infoln("Pull request is open, just replay sync.")
@@ -163,6 +173,17 @@ func (bot *robot) NotePullRequest(evt *client.GenericEvent, logger *logrus.Entry
 		return
 	}
 
+	if util.MatchSyncUpdate(comment) {
+		logger.Infoln("Receive /sync-update command")
+		if !bot.canSync(org, repo, number, user, logger) {
+			logger.Infof("%s may not update the sync pull requests.", user)
+			bot.replySyncStatus(evt, user, comment, notSigMember, logger)
+			return
+		}
+		_ = bot.syncUpdate(evt, logger)
+		return
+	}
+
 	if util.MatchClose(comment) {
 		logger.Infoln("Receive /close command")
 		if util.MatchTitle(title) {
@@ -32,7 +32,15 @@ func (bot *robot) MergePullRequest(evt *client.GenericEvent, logger *logrus.Entr
 		user := comment.Commenter
 		body := comment.Body
 		if util.MatchSync(body) {
-			logger.Infoln("match /sync command, user: %s, body: %s", user, body)
+			// a preview doesn't ask for syncing on merge
+			if opt, err := parseSyncCommand(body); err == nil && opt.dryRun {
+				continue
+			}
+			// the command of a user not allowed to sync was refused when commented
+			if !bot.canSync(org, repo, number, user, logger) {
+				continue
+			}
+			logger.Infof("match /sync command, user: %s, body: %s", user, body)
 			_ = bot.sync(evt, user, body, logger)
 			return
 		}
@@ -100,7 +108,8 @@ func (bot *robot) AutoMerge(evt *client.GenericEvent, org, repo, number string,
 }
 
 func (bot *robot) pick(org string, repo string, opt *SyncCmdOption, branchSet map[string]bool, pr client.PullRequest,
-	title string, body string, firstSha string, lastSha string) ([]syncStatus, error) {
+	title string, body string, labels []string, shas []string) ([]syncStatus, error) {
+	firstSha, lastSha := shas[0], shas[len(shas)-1]
 	number := utils.GetString(pr.Number)
 	sourceBranch := utils.GetString(pr.Head)
 	prNumber, err := strconv.Atoi(number)
@@ -114,6 +123,36 @@ func (bot *robot) pick(org string, repo string, opt *SyncCmdOption, branchSet ma
 		logrus.Errorf("Clone %s/%s failed: %v", org, repo, err)
 		return nil, err
 	}
+	// sync pull requests still open from an earlier /sync are reused
+	heads, err := bot.openSyncHeads(org, repo)
+	if err != nil {
+		logrus.WithError(err).Warnln("List open sync pull requests failed")
+	}
+	records, err := bot.syncRecords(org, repo, number)
+	if err != nil {
+		logrus.WithError(err).Warnln("Find sync pull requests failed")
+	}
+	merged := &git.Merged{}
+	var lfs []string
+	lfsReady := true
+	if !(org == "openEuler" && repo == "kernel") {
+		if err = r.FetchPullRequest(prNumber); err != nil {
+			logrus.WithError(err).Warnln("Fetch pull request failed")
+		} else {
+			if !opt.partial() {
+				merged = findMerged(r, utils.GetString(pr.Base), lastSha)
+			}
+			if lfs, err = bot.lfsFiles(r, org, repo, number, lastSha); err != nil {
+				logrus.WithError(err).Warnln("Find files stored in Git LFS failed")
+			}
+		}
+	}
+	if len(lfs) > 0 {
+		lfsReady = prepareLFS(r, lastSha)
+	}
+	if len(merged.Commits) > 0 {
+		firstSha, lastSha = merged.Commits[0], merged.Commits[len(merged.Commits)-1]
+	}
 
 	var status []syncStatus
 	for _, branch := range opt.branches {
@@ -125,10 +164,17 @@ func (bot *robot) pick(org string, repo string, opt *SyncCmdOption, branchSet ma
 			})
 			continue
 		}
+		if !lfsReady {
+			status = append(status, syncStatus{
+				Name:   branch,
+				Status: lfsSkipped,
+			})
+			continue
+		}
 
 		// pull for big repos by using upstream repos
 		if org == "openEuler" && repo == "kernel" {
-			bigRemote := fmt.Sprintf("%s/%s.git", "https://gitcode.com", org+"/"+repo)
+			bigRemote := bot.platform.RepoURL(org, repo)
 
 			// check remote
 			if hasUpstream, _ := r.ListRemote(); !hasUpstream {
@@ -250,7 +296,16 @@ func (bot *robot) pick(org string, repo string, opt *SyncCmdOption, branchSet ma
 			})
 			continue
 		}
-		err = r.CherryPick(firstSha, lastSha, git.Theirs)
+		switch {
+		case opt.squash:
+			err = r.CherryPickSquash(firstSha, lastSha, bot.squashMessage(org, repo, pr))
+		case opt.partial():
+			err = r.CherryPickPaths(shas, opt.paths)
+		case merged.Method == git.MergeCommit:
+			err = r.CherryPickMerge(lastSha)
+		default:
+			err = r.CherryPick(firstSha, lastSha, git.Theirs)
+		}
 		if err != nil {
 			logrus.Errorln("Cherry pick failed:", err.Error())
 			status = append(status, syncStatus{
@@ -259,6 +314,35 @@ func (bot *robot) pick(org string, repo string, opt *SyncCmdOption, branchSet ma
 			})
 			continue
 		}
+		// the commits picked may change none of the files of opt.paths
+		if changed, err := r.HasDiff("origin/"+branch, "HEAD"); opt.partial() && err == nil && !changed {
+			status = append(status, syncStatus{
+				Name:   branch,
+				Status: emptyCherry,
+			})
+			continue
+		}
+		err = r.AddTrailer("origin/"+branch, syncedFrom(org, repo, number))
+		if err != nil {
+			status = append(status, syncStatus{
+				Name:   branch,
+				Status: err.Error(),
+			})
+			continue
+		}
+		if len(lfs) > 0 {
+			if err = r.LFSPush(tempBranch); err != nil {
+				status = append(status, syncStatus{
+					Name:   branch,
+					Status: err.Error(),
+				})
+				continue
+			}
+		}
+		if heads[tempBranch] {
+			status = append(status, bot.reuseSyncBranch(r, org, repo, branch, tempBranch, records))
+			continue
+		}
 		err = r.Push(tempBranch, true)
 		if err != nil {
 			status = append(status, syncStatus{
@@ -268,8 +352,10 @@ func (bot *robot) pick(org string, repo string, opt *SyncCmdOption, branchSet ma
 			continue
 		}
 		var num string
+		var created bool
 		sleepyTime := time.Second
 
+		head := tempBranch
 		if org == "openEuler" && repo == "kernel" {
 			tempBranch = "LiYanghang00:" + tempBranch
 			forkPath = fmt.Sprintf("%s/%s", "LiYanghang00", repo)
@@ -288,9 +374,8 @@ func (bot *robot) pick(org string, repo string, opt *SyncCmdOption, branchSet ma
 				PruneSourceBranch: &prune,
 				ForkPath:          &forkPath,
 			}
-			var ok bool
-			num, ok = bot.cli.CreatePR(org, repo, newPR)
-			if !ok {
+			num, created = bot.cli.CreatePR(org, repo, newPR)
+			if !created {
 				logrus.WithError(err).Infof("Create pull request: retrying %d times", i+1)
 				time.Sleep(sleepyTime)
 				sleepyTime *= 2
@@ -300,20 +385,95 @@ func (bot *robot) pick(org string, repo string, opt *SyncCmdOption, branchSet ma
 		}
 		var url string
 		var st string
-		if err != nil {
-			logrus.Errorln("Create PullRequest failed:", err)
-			st = err.Error()
+		if !created {
+			logrus.Errorln("Create PullRequest failed")
+			st = createPRFailed
+			head = ""
 		} else {
 			logrus.Infoln("Create PullRequest:", num)
 			st = createdPR
-			url = fmt.Sprintf("https://gitcode.com/%v/%v/merge_requests/%v", org, repo, num)
+			url = bot.platform.PullRequestURL(org, repo, num)
+			bot.labelSyncPR(org, repo, num, labels)
+			bot.assignSyncPR(org, repo, number, num)
 		}
-		status = append(status, syncStatus{Name: branch, Status: st, PR: url})
+		status = append(status, syncStatus{Name: branch, Status: st, PR: url, Head: head})
 	}
 	return status, nil
 }
 
-func (bot *robot) merge(org string, repo string, opt *SyncCmdOption, branchSet map[string]bool, pr client.PullRequest, title string, body string) ([]syncStatus, error) {
+// findMerged tells how the pull request whose last commit is head was merged
+// into base, so that the commits it left on base are picked. A fast-forward, a
+// pull request not merged yet or one whose changes can't be found on base are
+// picked from its own commits. The pull request must have been fetched.
+func findMerged(r *git.Repo, base, head string) *git.Merged {
+	merged, err := r.FindMerged("origin/"+base, head)
+	if err != nil {
+		logrus.WithError(err).Warnln("Find how the pull request was merged failed, pick its commits")
+		return &git.Merged{}
+	}
+	logrus.Infof("Pull request merged by %q, pick %v", merged.Method, merged.Commits)
+	return merged
+}
+
+// selectCommits returns the commits of the pull request whose SHAs start with
+// one of shas, in the order of commits, or all of them if shas is empty. A sha
+// matching no commit or more than one is returned as unknown.
+func selectCommits(commits []client.PRCommit, shas []string) (selected []client.PRCommit, unknown string) {
+	if len(shas) == 0 {
+		return commits, ""
+	}
+	picked := make(map[int]bool)
+	for _, sha := range shas {
+		match := -1
+		for i, c := range commits {
+			if strings.HasPrefix(strings.ToLower(c.SHA), strings.ToLower(sha)) {
+				if match >= 0 {
+					return nil, sha
+				}
+				match = i
+			}
+		}
+		if match < 0 {
+			return nil, sha
+		}
+		picked[match] = true
+	}
+	for i, c := range commits {
+		if picked[i] {
+			selected = append(selected, c)
+		}
+	}
+	return selected, ""
+}
+
+// commitSHAs returns the SHAs of the commits of a pull request, oldest first.
+func commitSHAs(commits []client.PRCommit) []string {
+	shas := make([]string, 0, len(commits))
+	for i := len(commits) - 1; i >= 0; i-- {
+		shas = append(shas, commits[i].SHA)
+	}
+	return shas
+}
+
+// squashMessage returns the message of the commit squashing the commits of the
+// pull request.
+func (bot *robot) squashMessage(org, repo string, pr client.PullRequest) string {
+	number := utils.GetString(pr.Number)
+	message := fmt.Sprintf("%s\n\nSquashed from %s/%s!%s", utils.GetString(pr.Title), org, repo, number)
+	if author, ok := bot.cli.GetPullRequestAuthor(org, repo, number); ok && author != "" {
+		message += " of @" + author
+	}
+	return message + "."
+}
+
+// syncedFrom returns the trailer tracing the commits synced back to the pull
+// request they come from.
+func syncedFrom(org, repo, number string) string {
+	return fmt.Sprintf("Synced-from: %s/%s!%s", org, repo, number)
+}
+
+func (bot *robot) merge(org string, repo string, opt *SyncCmdOption, branchSet map[string]bool, pr client.PullRequest, title string, body string,
+	labels []string) ([]syncStatus, error) {
 	number := utils.GetString(pr.Number)
 	ref := utils.GetString(pr.Head)
 
@@ -329,15 +489,14 @@ func (bot *robot) merge(org string, repo string, opt *SyncCmdOption, branchSet m
 		}
 		// create temp branch
 		tempBranch := fmt.Sprintf("sync-pr%v-to-%v", number, branch)
-		ok := bot.cli.CreateRepoBranch(org, repo, tempBranch, ref)
-		if !ok {
+		if !bot.cli.CreateRepoBranch(org, repo, ref, tempBranch) {
 			logrus.WithFields(logrus.Fields{
 				"tempBranch": tempBranch,
 			}).Errorln("Create temp branch failed:")
-			// TODO: check if branch exist
-		} else {
-			logrus.Infoln("Create temp branch:", branch)
+			status = append(status, syncStatus{Name: branch, Status: createBranchFailed})
+			continue
 		}
+		logrus.Infoln("Create temp branch:", tempBranch)
 		var url string
 		var st string
 		var forkPath string
@@ -357,7 +516,9 @@ func (bot *robot) merge(org string, repo string, opt *SyncCmdOption, branchSet m
 		} else {
 			logrus.Infoln("Create PullRequest:", num)
 			st = createdPR
-			url = fmt.Sprintf("https://gitcode.com/%v/%v/merge_requests/%v", org, repo, num)
+			url = bot.platform.PullRequestURL(org, repo, num)
+			bot.labelSyncPR(org, repo, num, labels)
+			bot.assignSyncPR(org, repo, number, num)
 		}
 		status = append(status, syncStatus{Name: branch, Status: st, PR: url})
 	}
@@ -381,17 +542,72 @@ func (bot *robot) sync(evt *client.GenericEvent, user string, command string, lo
 		return err
 	}
 
+	status, err := bot.syncPullRequest(org, repo, number, opt, nil, logger)
+	if err != nil {
+		return err
+	}
+
+	comment, err := executeTemplate(syncResultTmpl, struct {
+		URL        string
+		User       string
+		Command    string
+		SyncStatus []syncStatus
+		Options    string
+		Mentions   []string
+	}{
+		URL:        utils.GetString(evt.HtmlURL),
+		User:       user,
+		Command:    strings.TrimSpace(command),
+		SyncStatus: status,
+		Options:    opt.recordOptions(),
+		Mentions:   bot.failureMentions(org, repo, status, logger),
+	})
+	if err != nil {
+		logger.Errorln("Execute template failed:", err)
+		return err
+	}
+
+	ok := bot.cli.CreatePRComment(org, repo, number, comment)
+	if !ok {
+		logger.Errorln("Create comment failed:", err)
+		return err
+	} else {
+		logger.Infoln("Reply sync.")
+	}
+	bot.boardSync(org, repo, number, status, logger)
+	return err
+}
+
+// syncPullRequest syncs the pull request to the branches of opt and returns the
+// status of every branch. chain are the pull requests the one synced descends
+// from along a lineage, the original one first, it is empty unless the pull
+// request is itself a sync pull request.
+func (bot *robot) syncPullRequest(org, repo, number string, opt *SyncCmdOption, chain []string,
+	logger *logrus.Entry) ([]syncStatus, error) {
 	pr, ok := bot.cli.GetPullRequest(org, repo, number)
 	if !ok {
 		logger.Errorln("Get pull request failed")
-		return errors.New("get pull request failed")
+		return nil, errors.New("get pull request failed")
 	}
 
 	issues, ok := bot.cli.GetPRLinkedIssue(org, repo, number)
 	commits, ok := bot.cli.GetPullRequestCommits(org, repo, number)
 	if !ok {
 		logger.Errorln("List commits failed")
-		return errors.New("list commits failed")
+		return nil, errors.New("list commits failed")
+	}
+	if len(commits) == 0 {
+		logger.Errorln("Pull request has no commits")
+		return nil, errors.New("pull request has no commits")
+	}
+	commits, unknown := selectCommits(commits, opt.commits)
+	if unknown != "" {
+		logger.Warnf("Commit %s not found in pull request", unknown)
+		var status []syncStatus
+		for _, branch := range opt.branches {
+			status = append(status, syncStatus{Name: branch, Status: fmt.Sprintf(commitNotFound, unknown)})
+		}
+		return status, nil
 	}
 	for i := range commits {
 		commits[i].Message = strings.ReplaceAll(commits[i].Message, "\n", "<br>")
@@ -401,7 +617,7 @@ func (bot *robot) sync(evt *client.GenericEvent, user string, command string, lo
 	branches, ok := bot.cli.GetRepoAllBranch(org, repo)
 	if !ok {
 		logger.Errorln("List branches failed")
-		return errors.New("list branches failed")
+		return nil, errors.New("list branches failed")
 	}
 	branchSet := make(map[string]bool)
 	for _, b := range branches {
@@ -409,32 +625,54 @@ func (bot *robot) sync(evt *client.GenericEvent, user string, command string, lo
 	}
 
 	title := fmt.Sprintf("[sync] PR-%v: %v", number, utils.GetString(pr.Title))
+	if util.MatchTitle(utils.GetString(pr.Title)) {
+		// the title of a sync pull request already refers to the original one
+		title = utils.GetString(pr.Title)
+	}
+	chain = append(append([]string{}, chain...), utils.GetString(pr.URL))
+
+	sourceLabels, ok := bot.cli.GetPullRequestLabels(org, repo, number)
+	if !ok {
+		logger.Warnln("List labels failed, sync pull requests are not labeled after them")
+	}
+	labels := bot.cnf.labelPropagation().syncLabels(sourceLabels)
 
 	var body string
 	var data interface{}
+	var err error
 	if org == "openEuler" && repo == "kernel" {
 		data = struct {
-			PR   string
-			Body string
+			PR     string
+			Body   string
+			Labels []LabelUsageDescription
+			Chain  []string
 		}{
-			PR:   utils.GetString(pr.URL),
-			Body: utils.GetString(pr.Body),
+			PR:     chain[0],
+			Body:   utils.GetString(pr.Body),
+			Labels: bot.cnf.describeLabels(labels),
+			Chain:  chain,
 		}
 
 		body, err = executeTemplate(syncPRBodyTmplKernel, data)
 		if err != nil {
 			logger.Errorln("Execute template failed:", err)
-			return err
+			return nil, err
 		}
 	} else {
 		data = struct {
 			PR      string
 			Issues  []client.Issue
 			Commits []client.PRCommit
+			Paths   []string
+			Labels  []LabelUsageDescription
+			Chain   []string
 		}{
-			PR:      utils.GetString(pr.URL),
+			PR:      chain[0],
 			Issues:  issues,
 			Commits: commits,
+			Paths:   opt.paths,
+			Labels:  bot.cnf.describeLabels(labels),
+			Chain:   chain,
 		}
 
 		body, err = executeTemplate(syncPRBodyTmpl, data)
@@ -443,54 +681,33 @@ func (bot *robot) sync(evt *client.GenericEvent, user string, command string, lo
 				"tmpl": syncPRBodyTmpl,
 				"data": data,
 			}).Errorln("Execute template failed:", err)
-			return err
+			return nil, err
 		}
 	}
 
 	var status []syncStatus
 	switch opt.strategy {
 	case Pick:
-		firstSha := commits[len(commits)-1].SHA
-		lastSha := commits[0].SHA
-		status, _ = bot.pick(org, repo, opt, branchSet, pr, title, body, firstSha, lastSha)
+		status, _ = bot.pick(org, repo, opt, branchSet, pr, title, body, labels, commitSHAs(commits))
 	case Merge:
-		status, _ = bot.merge(org, repo, opt, branchSet, pr, title, body)
+		status, _ = bot.merge(org, repo, opt, branchSet, pr, title, body, labels)
 	case Overwrite:
 		bot.overwrite()
 	default:
 	}
 
-	comment, err := executeTemplate(syncResultTmpl, struct {
-		URL        string
-		User       string
-		Command    string
-		SyncStatus []syncStatus
-	}{
-		URL:        utils.GetString(evt.HtmlURL),
-		User:       user,
-		Command:    strings.TrimSpace(command),
-		SyncStatus: status,
-	})
-	if err != nil {
-		logger.Errorln("Execute template failed:", err)
-		return err
-	}
-
-	ok = bot.cli.CreatePRComment(org, repo, number, comment)
-	if !ok {
-		logger.Errorln("Create comment failed:", err)
-		return err
-	} else {
-		logger.Infoln("Reply sync.")
-	}
-	return err
+	return status, nil
 }
 
 func (bot *robot) ClosePullRequest(evt *client.GenericEvent, org, repo, number string, logger *logrus.Entry) {
-	sourceBranch := utils.GetString(evt.Head)
-
 	logger.Infoln("ClosePullRequest")
+	bot.pruneSyncBranch(evt, org, repo, logger)
+}
 
+// pruneSyncBranch deletes the source branch of a sync pull request merged or
+// closed, platforms like GitHub keep it.
+func (bot *robot) pruneSyncBranch(evt *client.GenericEvent, org, repo string, logger *logrus.Entry) {
+	sourceBranch := utils.GetString(evt.Head)
 	r, err := bot.GitClient.Clone(org, repo)
 	if err != nil {
 		logger.Errorf("Clone repo failed: %v", err)
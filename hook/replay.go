@@ -0,0 +1,325 @@
+package hook
+
+import (
+	"fmt"
+
+	"sync-bot/fake"
+	"sync-bot/git"
+	"sync-bot/platform"
+	"sync-bot/secret"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// dryClient reads from the platform but records the writes in rec instead of
+// sending them. It implements every method of iClient itself, so that a method
+// added to iClient can't reach the platform without being sorted out here.
+type dryClient struct {
+	platform iClient
+	rec      *fake.Client
+}
+
+var _ iClient = dryClient{}
+
+// The reads are sent to the platform.
+
+func (c dryClient) GetPullRequest(org, repo, number string) (client.PullRequest, bool) {
+	return c.platform.GetPullRequest(org, repo, number)
+}
+
+func (c dryClient) GetPathContent(org, repo, path, branch string) (client.RepoContent, bool) {
+	return c.platform.GetPathContent(org, repo, path, branch)
+}
+
+func (c dryClient) GetRepoAllBranch(org, repo string) ([]client.Branch, bool) {
+	return c.platform.GetRepoAllBranch(org, repo)
+}
+
+func (c dryClient) ListPullRequestComments(org, repo, number string) ([]client.PRComment, bool) {
+	return c.platform.ListPullRequestComments(org, repo, number)
+}
+
+func (c dryClient) ListPullRequestCommentsWithID(org, repo, number string) ([]platform.Comment, bool) {
+	return c.platform.ListPullRequestCommentsWithID(org, repo, number)
+}
+
+func (c dryClient) ListIssueCommentsWithID(org, repo, number string) ([]platform.Comment, bool) {
+	return c.platform.ListIssueCommentsWithID(org, repo, number)
+}
+
+func (c dryClient) GetPRLinkedIssue(org, repo, number string) ([]client.Issue, bool) {
+	return c.platform.GetPRLinkedIssue(org, repo, number)
+}
+
+func (c dryClient) GetPullRequestCommits(org, repo, number string) ([]client.PRCommit, bool) {
+	return c.platform.GetPullRequestCommits(org, repo, number)
+}
+
+func (c dryClient) GetPullRequestLabels(org, repo, number string) ([]string, bool) {
+	return c.platform.GetPullRequestLabels(org, repo, number)
+}
+
+func (c dryClient) GetIssueLabels(org, issueID string) ([]string, bool) {
+	return c.platform.GetIssueLabels(org, issueID)
+}
+
+func (c dryClient) GetRepoIssueLabels(org, repo string) ([]string, bool) {
+	return c.platform.GetRepoIssueLabels(org, repo)
+}
+
+func (c dryClient) CheckPermissionWithBranch(org, repo, username, branch string) (bool, bool) {
+	return c.platform.CheckPermissionWithBranch(org, repo, username, branch)
+}
+
+func (c dryClient) GetPullRequestChanges(org, repo, number string) ([]client.CommitFile, bool) {
+	return c.platform.GetPullRequestChanges(org, repo, number)
+}
+
+func (c dryClient) GetAuthenticatedUser() (string, bool) {
+	return c.platform.GetAuthenticatedUser()
+}
+
+func (c dryClient) ListOpenPullRequests(org, repo string) ([]client.PullRequest, bool) {
+	return c.platform.ListOpenPullRequests(org, repo)
+}
+
+func (c dryClient) ListOrgRepos(org string) ([]string, bool) {
+	return c.platform.ListOrgRepos(org)
+}
+
+func (c dryClient) ListProtectedBranches(org, repo string) ([]string, bool) {
+	return c.platform.ListProtectedBranches(org, repo)
+}
+
+func (c dryClient) GetPullRequestAuthor(org, repo, number string) (string, bool) {
+	return c.platform.GetPullRequestAuthor(org, repo, number)
+}
+
+func (c dryClient) GetPullRequestReviewers(org, repo, number string) ([]string, bool) {
+	return c.platform.GetPullRequestReviewers(org, repo, number)
+}
+
+func (c dryClient) CheckIfPRCreateEvent(evt *client.GenericEvent) bool {
+	return c.platform.CheckIfPRCreateEvent(evt)
+}
+
+func (c dryClient) CheckIfPRReopenEvent(evt *client.GenericEvent) bool {
+	return c.platform.CheckIfPRReopenEvent(evt)
+}
+
+func (c dryClient) CheckIfPRMergeEvent(evt *client.GenericEvent) bool {
+	return c.platform.CheckIfPRMergeEvent(evt)
+}
+
+func (c dryClient) CheckIfPRCloseEvent(evt *client.GenericEvent) bool {
+	return c.platform.CheckIfPRCloseEvent(evt)
+}
+
+func (c dryClient) CheckIfPRSourceCodeUpdateEvent(evt *client.GenericEvent) bool {
+	return c.platform.CheckIfPRSourceCodeUpdateEvent(evt)
+}
+
+// The writes are recorded in rec.
+
+func (c dryClient) CreatePRComment(org, repo, number, comment string) bool {
+	return c.rec.CreatePRComment(org, repo, number, comment)
+}
+
+func (c dryClient) CreateIssueComment(org, repo, number, comment string) bool {
+	return c.rec.CreateIssueComment(org, repo, number, comment)
+}
+
+func (c dryClient) AddIssueLabels(org, repo, number string, labels []string) bool {
+	return c.rec.AddIssueLabels(org, repo, number, labels)
+}
+
+func (c dryClient) RemoveIssueLabels(org, repo, number string, labels []string) bool {
+	return c.rec.RemoveIssueLabels(org, repo, number, labels)
+}
+
+func (c dryClient) AddPRLabels(org, repo, number string, labels []string) bool {
+	return c.rec.AddPRLabels(org, repo, number, labels)
+}
+
+func (c dryClient) RemovePRLabels(org, repo, number string, labels []string) bool {
+	return c.rec.RemovePRLabels(org, repo, number, labels)
+}
+
+func (c dryClient) CreatePR(org, repo string, prContent client.PullRequest) (string, bool) {
+	return c.rec.CreatePR(org, repo, prContent)
+}
+
+// EditPRComment records the edit, the comment is on the platform and not in rec.
+func (c dryClient) EditPRComment(org, repo, number, commentID, comment string) bool {
+	c.rec.Lock()
+	defer c.rec.Unlock()
+	c.rec.EditedComments = append(c.rec.EditedComments, fake.Comment{Org: org, Repo: repo, Number: number, Body: comment})
+	return true
+}
+
+// EditIssueComment records the edit, the comment is on the platform and not in rec.
+func (c dryClient) EditIssueComment(org, repo, number, commentID, comment string) bool {
+	c.rec.Lock()
+	defer c.rec.Unlock()
+	c.rec.EditedComments = append(c.rec.EditedComments, fake.Comment{Org: org, Repo: repo, Number: number, Body: comment})
+	return true
+}
+
+func (c dryClient) RequestPRReviewers(org, repo, number string, reviewers []string) bool {
+	return c.rec.RequestPRReviewers(org, repo, number, reviewers)
+}
+
+func (c dryClient) AssignPR(org, repo, number string, assignees []string) bool {
+	return c.rec.AssignPR(org, repo, number, assignees)
+}
+
+func (c dryClient) CreateRepoBranch(org, repo, createFrom, branch string) bool {
+	return c.rec.CreateRepoBranch(org, repo, createFrom, branch)
+}
+
+// ReplayResult is what the handlers produced for a delivery.
+type ReplayResult struct {
+	// Kind is the kind of the event, empty if the delivery is not handled.
+	Kind string
+	// Event is the event the delivery was mapped to.
+	Event *client.GenericEvent
+	// Comments are the comments created on pull requests.
+	Comments []fake.Comment
+	// IssueComments are the comments created on issues.
+	IssueComments []fake.Comment
+	// PullRequests are the pull requests created.
+	PullRequests []client.PullRequest
+}
+
+// Replayer dispatches recorded webhook deliveries to the handlers of a robot
+// and reports the comments and pull requests they produce. Create with
+// NewFakeReplayer or NewDryReplayer.
+type Replayer struct {
+	bot *robot
+	// rec records the writes of the handlers.
+	rec *fake.Client
+	// seed adds the pull requests of the deliveries to the fake platform.
+	seed bool
+}
+
+// NewFakeReplayer returns a replayer against the fake platform state. The pull
+// requests and comments of the deliveries are added to state as they are
+// replayed, unless state already knows the pull request.
+func NewFakeReplayer(c *Configuration, state *fake.Client, gitClient *git.Client, logger *logrus.Entry) (*Replayer, error) {
+	p, err := platform.New(c.Platform, c.PlatformHost)
+	if err != nil {
+		return nil, err
+	}
+	return &Replayer{
+		bot:  &robot{cli: state, cnf: c, log: logger, GitClient: gitClient, platform: p},
+		rec:  state,
+		seed: true,
+	}, nil
+}
+
+// NewDryReplayer returns a replayer reading from the platform of c with tokens.
+// Comments, labels, branches and pull requests are recorded, never sent. The
+// git client still pushes to its remote, point it to a scratch location.
+func NewDryReplayer(c *Configuration, tokens *secret.Tokens, gitClient *git.Client, logger *logrus.Entry) (*Replayer, error) {
+	p, err := platform.New(c.Platform, c.PlatformHost)
+	if err != nil {
+		return nil, err
+	}
+	cli := newRoleClient(tokens, newClientFactory(p, logger), logger)
+	if err = cli.check(); err != nil {
+		return nil, err
+	}
+	rec := fake.NewClient()
+	return &Replayer{
+		bot: &robot{cli: dryClient{platform: cli, rec: rec}, cnf: c, log: logger, GitClient: gitClient, platform: p},
+		rec: rec,
+	}, nil
+}
+
+// Replay dispatches a delivery to the handlers and returns what they produced.
+// Every repository is handled when the configuration has no items.
+func (r *Replayer) Replay(d *platform.Delivery) (result *ReplayResult, err error) {
+	parse, _, err := webhookOf(d.Platform())
+	if err != nil {
+		return nil, fmt.Errorf("replay delivery of platform %q failed: %v", d.Platform(), err)
+	}
+	kind, evt, err := parse(d.Header, d.Payload)
+	if err != nil {
+		return nil, err
+	}
+	result = &ReplayResult{Kind: kind, Event: evt}
+	if kind == "" {
+		return result, nil
+	}
+
+	org, repo, number := utils.GetString(evt.Org), utils.GetString(evt.Repo), utils.GetString(evt.Number)
+	logger := r.bot.log.WithFields(logrus.Fields{
+		"org":    org,
+		"repo":   repo,
+		"number": number,
+		"kind":   kind,
+		"source": d.Source,
+	})
+	repoCnf := r.bot.cnf.getRepoConfig(org, repo)
+	if repoCnf == nil {
+		if len(r.bot.cnf.ConfigItems) > 0 {
+			logger.Infoln("Repository is not configured, ignore it.")
+			return result, nil
+		}
+		repoCnf = &repoConfig{Repos: []string{org + "/" + repo}}
+	}
+	if r.seed {
+		r.seedEvent(kind, evt)
+	}
+
+	r.rec.Lock()
+	comments, issueComments, prs := len(r.rec.PRComments), len(r.rec.IssueComments), len(r.rec.CreatedPRs)
+	r.rec.Unlock()
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("handler panicked: %v", p)
+		}
+		r.rec.Lock()
+		defer r.rec.Unlock()
+		result.Comments = append(result.Comments, r.rec.PRComments[comments:]...)
+		result.IssueComments = append(result.IssueComments, r.rec.IssueComments[issueComments:]...)
+		result.PullRequests = append(result.PullRequests, r.rec.CreatedPRs[prs:]...)
+	}()
+	r.bot.dispatch(kind, evt, repoCnf, logger)
+	return result, nil
+}
+
+// seedEvent adds the pull request of an event and its comment to the fake
+// platform, so the handlers find them when they look them up. The commenter is
+// let write the repository unless the state says otherwise. Comments on issues
+// need nothing.
+func (r *Replayer) seedEvent(kind string, evt *client.GenericEvent) {
+	if kind == platform.IssueCommentEvent {
+		return
+	}
+	org, repo, number := utils.GetString(evt.Org), utils.GetString(evt.Repo), utils.GetString(evt.Number)
+	r.rec.Lock()
+	_, known := r.rec.PullRequests[fake.Key(org, repo, number)]
+	r.rec.Unlock()
+	if !known {
+		url := r.bot.platform.PullRequestURL(org, repo, number)
+		r.rec.AddPullRequest(org, repo, client.PullRequest{
+			Number: evt.Number,
+			Title:  evt.Title,
+			Head:   evt.Head,
+			Base:   evt.Base,
+			URL:    &url,
+		})
+	}
+	if kind == platform.PullRequestCommentEvent {
+		user := utils.GetString(evt.Commenter)
+		r.rec.AddComment(org, repo, number, user, utils.GetString(evt.Comment))
+		r.rec.Lock()
+		if _, scripted := r.rec.Writers[fake.Key(org, repo, user)]; !scripted {
+			r.rec.Writers[fake.Key(org, repo, user)] = true
+		}
+		r.rec.Unlock()
+	}
+}
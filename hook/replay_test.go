@@ -0,0 +1,154 @@
+package hook
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sync-bot/fake"
+	"sync-bot/platform"
+
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+func TestReplayFixture(t *testing.T) {
+	f, err := os.Open(filepath.Join("..", "tests", "sync.http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := platform.ReadHTTPDelivery(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := fake.NewClient()
+	state.Branches[fake.RepoKey("open-euler", "syncbot-example")] = []string{"master", "branch1"}
+	replayer, err := NewFakeReplayer(&Configuration{}, state, nil, logrus.NewEntry(logrus.StandardLogger()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := replayer.Replay(d)
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if result.Kind != platform.PullRequestCommentEvent || len(result.Comments) != 1 {
+		t.Fatalf("Replay() = %s with comments %v, want the reply of /sync", result.Kind, result.Comments)
+	}
+	reply := result.Comments[0].Body
+	for _, want := range []string{"|branch1|" + branchExist + "|", "|branch2|" + branchNonExist + "|"} {
+		if !strings.Contains(reply, want) {
+			t.Errorf("reply %q doesn't hold %q", reply, want)
+		}
+	}
+	// the command is kept for the merge of the pull request
+	if comments := state.Comments[fake.Key("open-euler", "syncbot-example", "23")]; len(comments) != 2 {
+		t.Errorf("comments of the pull request = %v, want the command and the reply", comments)
+	}
+}
+
+func TestReplayGitCode(t *testing.T) {
+	f, err := os.Open(filepath.Join("..", "tests", "sync.http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := platform.ReadHTTPDelivery(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// captured from the deployed bot, GitCode names the headers after itself
+	d.Header.Set("X-GitCode-Event", d.Header.Get("X-Gitee-Event"))
+	d.Header.Set("X-GitCode-Token", d.Header.Get("X-Gitee-Token"))
+	d.Header.Del("X-Gitee-Event")
+	d.Header.Del("X-Gitee-Token")
+
+	state := fake.NewClient()
+	state.Branches[fake.RepoKey("open-euler", "syncbot-example")] = []string{"master", "branch1"}
+	replayer, err := NewFakeReplayer(&Configuration{Platform: platform.GitCode}, state, nil,
+		logrus.NewEntry(logrus.StandardLogger()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := replayer.Replay(d)
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if result.Kind != platform.PullRequestCommentEvent || len(result.Comments) != 1 {
+		t.Fatalf("Replay() = %s with comments %v, want the reply of /sync", result.Kind, result.Comments)
+	}
+	if reply := result.Comments[0].Body; !strings.Contains(reply, "|branch1|"+branchExist+"|") {
+		t.Errorf("reply %q doesn't hold branch1", reply)
+	}
+}
+
+func TestReplaySync(t *testing.T) {
+	bot, cli, _ := newSyncScenario(t)
+	replayer, err := NewFakeReplayer(&Configuration{}, cli, bot.GitClient, bot.log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := http.Header{}
+	header.Set("X-GitHub-Event", "issue_comment")
+	d := &platform.Delivery{Source: "capture.jsonl:1", Header: header, Payload: []byte(`{"action":"created",
+		"issue":{"number":1,"title":"fix a","state":"closed","pull_request":{"merged_at":"2024-01-02T03:04:05Z"}},
+		"comment":{"body":"/sync stable","user":{"login":"alice"}},"repository":{"name":"r","owner":{"login":"o"}}}`)}
+
+	result, err := replayer.Replay(d)
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if len(result.PullRequests) != 1 || utils.GetString(result.PullRequests[0].Base) != "stable" {
+		t.Fatalf("Replay() created %v, want a pull request to stable", result.PullRequests)
+	}
+	if len(result.Comments) != 2 || !strings.Contains(result.Comments[0].Body, createdPR) {
+		t.Errorf("Replay() commented %v, want the result of /sync and the status board", result.Comments)
+	}
+
+	// configured robots ignore other repositories
+	replayer.bot.cnf = &Configuration{ConfigItems: []repoConfig{{Repos: []string{"other"}}}}
+	if result, err = replayer.Replay(d); err != nil || len(result.Comments)+len(result.PullRequests) != 0 {
+		t.Errorf("Replay() of a repository not configured = %v, %v", result, err)
+	}
+}
+
+func TestDryReplayWritesNothing(t *testing.T) {
+	bot, real, _ := newSyncScenario(t)
+	real.Authors[fake.Key("o", "r", "1")] = "alice"
+	real.Reviewers[fake.Key("o", "r", "1")] = []string{"bob"}
+	real.AddComment("o", "r", "1", "sync-bot", "<!-- sync-status: -->")
+	rec := fake.NewClient()
+	bot.cli = dryClient{platform: real, rec: rec}
+	replayer := &Replayer{bot: bot, rec: rec}
+
+	header := http.Header{}
+	header.Set("X-GitHub-Event", "issue_comment")
+	d := &platform.Delivery{Source: "capture.jsonl:1", Header: header, Payload: []byte(`{"action":"created",
+		"issue":{"number":1,"title":"fix a","state":"closed","pull_request":{"merged_at":"2024-01-02T03:04:05Z"}},
+		"comment":{"body":"/sync stable","user":{"login":"alice"}},"repository":{"name":"r","owner":{"login":"o"}}}`)}
+	result, err := replayer.Replay(d)
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if len(result.PullRequests) != 1 {
+		t.Fatalf("Replay() created %v, want the sync pull request", result.PullRequests)
+	}
+
+	if len(rec.EditedComments) != 1 || len(rec.Assignees) == 0 || len(rec.Reviewers) == 0 {
+		t.Errorf("recorded edits %v, assignees %v and reviewers %v, want the status board edited and the sync pull request assigned",
+			rec.EditedComments, rec.Assignees, rec.Reviewers)
+	}
+	if len(real.PRComments)+len(real.IssueComments)+len(real.EditedComments)+len(real.CreatedPRs) != 0 {
+		t.Errorf("the platform got comments %v %v, edits %v and pull requests %v",
+			real.PRComments, real.IssueComments, real.EditedComments, real.CreatedPRs)
+	}
+	if len(real.Assignees) != 0 || len(real.Reviewers) != 1 {
+		t.Errorf("the platform got assignees %v and reviewers %v", real.Assignees, real.Reviewers)
+	}
+	if body := real.Comments[fake.Key("o", "r", "1")][0].Body; body != "<!-- sync-status: -->" {
+		t.Errorf("the status board on the platform was edited to %q", body)
+	}
+}
@@ -0,0 +1,115 @@
+package hook
+
+import (
+	"fmt"
+
+	"sync-bot/util"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sources of the assignees and the reviewers of the sync pull requests
+const (
+	// sourceAuthor is the author of the original pull request.
+	sourceAuthor = "author"
+	// sourceReviewers are the reviewers and the approvers of the original pull request.
+	sourceReviewers = "reviewers"
+	// sourceMaintainers are the maintainers of the SIGs of the repository.
+	sourceMaintainers = "maintainers"
+)
+
+// reviewerSelectionConfig selects the assignees and the reviewers of the sync
+// pull requests among sourceAuthor, sourceReviewers and sourceMaintainers.
+type reviewerSelectionConfig struct {
+	// Assignees are the sources of the assignees, none if empty.
+	Assignees []string `json:"assignees,omitempty"`
+	// Reviewers are the sources of the reviewers, none if empty.
+	Reviewers []string `json:"reviewers,omitempty"`
+}
+
+// defaultReviewerSelection is used when no selection is configured.
+var defaultReviewerSelection = reviewerSelectionConfig{
+	Assignees: []string{sourceAuthor},
+	Reviewers: []string{sourceReviewers, sourceMaintainers},
+}
+
+func (c *reviewerSelectionConfig) validate() error {
+	for _, source := range append(append([]string{}, c.Assignees...), c.Reviewers...) {
+		switch source {
+		case sourceAuthor, sourceReviewers, sourceMaintainers:
+		default:
+			return fmt.Errorf("sync_reviewers: unknown source %q", source)
+		}
+	}
+	return nil
+}
+
+// reviewerSelection returns the selection configured, the default one if none is.
+func (c *Configuration) reviewerSelection() *reviewerSelectionConfig {
+	if c == nil || c.SyncReviewers == nil {
+		return &defaultReviewerSelection
+	}
+	return c.SyncReviewers
+}
+
+// syncPeople looks up the users of the sources of the pull request number
+// lazily, once each.
+type syncPeople struct {
+	bot               *robot
+	org, repo, number string
+	found             map[string][]string
+}
+
+func (p *syncPeople) of(sources []string) []string {
+	var users []string
+	for _, source := range sources {
+		if _, ok := p.found[source]; !ok {
+			p.found[source] = p.lookUp(source)
+		}
+		for _, u := range p.found[source] {
+			if !util.ContainsString(users, u) {
+				users = append(users, u)
+			}
+		}
+	}
+	return users
+}
+
+func (p *syncPeople) lookUp(source string) []string {
+	switch source {
+	case sourceAuthor:
+		if author, ok := p.bot.cli.GetPullRequestAuthor(p.org, p.repo, p.number); ok && author != "" {
+			return []string{author}
+		}
+		logrus.Warnf("Get the author of %s/%s#%s failed", p.org, p.repo, p.number)
+	case sourceReviewers:
+		if reviewers, ok := p.bot.cli.GetPullRequestReviewers(p.org, p.repo, p.number); ok {
+			return reviewers
+		}
+		logrus.Warnf("Get the reviewers of %s/%s#%s failed", p.org, p.repo, p.number)
+	case sourceMaintainers:
+		maintainers, err := p.bot.sigMaintainers(p.org, p.repo)
+		if err != nil {
+			logrus.WithError(err).Warnf("Get the SIG maintainers of %s/%s failed", p.org, p.repo)
+		}
+		return maintainers
+	}
+	return nil
+}
+
+// assignSyncPR assigns the sync pull request syncNumber of the pull request
+// number and requests its reviews as configured. A failure leaves it without
+// them but created.
+func (bot *robot) assignSyncPR(org, repo, number, syncNumber string) {
+	selection := bot.cnf.reviewerSelection()
+	people := &syncPeople{bot: bot, org: org, repo: repo, number: number, found: make(map[string][]string)}
+
+	if assignees := people.of(selection.Assignees); len(assignees) > 0 &&
+		!bot.cli.AssignPR(org, repo, syncNumber, assignees) {
+		logrus.Errorf("Assign %v to %s/%s#%s failed", assignees, org, repo, syncNumber)
+	}
+	if reviewers := people.of(selection.Reviewers); len(reviewers) > 0 &&
+		!bot.cli.RequestPRReviewers(org, repo, syncNumber, reviewers) {
+		logrus.Errorf("Request reviewers %v on %s/%s#%s failed", reviewers, org, repo, syncNumber)
+	}
+}
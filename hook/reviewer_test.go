@@ -0,0 +1,72 @@
+package hook
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"sync-bot/fake"
+	"sync-bot/sig"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestAssignSyncPR(t *testing.T) {
+	sigs := fake.NewSigServer(map[string][]sig.SIG{"o/r": {{Maintainers: []string{"carol", "erin"}}}})
+	defer sigs.Close()
+
+	tests := []struct {
+		name          string
+		selection     *reviewerSelectionConfig
+		wantAssignees []string
+		wantReviewers []string
+	}{
+		{
+			name:          "default",
+			wantAssignees: []string{"alice"},
+			wantReviewers: []string{"erin", "carol"},
+		},
+		{
+			name:          "author and maintainers review",
+			selection:     &reviewerSelectionConfig{Reviewers: []string{sourceAuthor, sourceMaintainers}},
+			wantReviewers: []string{"alice", "carol", "erin"},
+		},
+		{
+			name:      "nobody",
+			selection: &reviewerSelectionConfig{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bot, cli, _ := newSyncScenario(t)
+			bot.cnf.SyncReviewers = tt.selection
+			bot.sigs = sig.NewClient(sigs.URL, "openeuler", time.Hour)
+			cli.Authors[fake.Key("o", "r", "1")] = "alice"
+			cli.Reviewers[fake.Key("o", "r", "1")] = []string{"erin"}
+
+			bot.handlePullRequestCommentEvent(commentEvent("/sync stable", "merged"), nil, logrus.NewEntry(logrus.StandardLogger()))
+
+			if len(cli.CreatedPRs) != 1 {
+				t.Fatalf("created %v, want the sync pull request to stable", cli.CreatedPRs)
+			}
+			key := fake.Key("o", "r", "1000")
+			if got := cli.Assignees[key]; !reflect.DeepEqual(got, tt.wantAssignees) {
+				t.Errorf("assignees = %v, want %v", got, tt.wantAssignees)
+			}
+			if got := cli.Reviewers[key]; !reflect.DeepEqual(got, tt.wantReviewers) {
+				t.Errorf("reviewers = %v, want %v", got, tt.wantReviewers)
+			}
+		})
+	}
+}
+
+func TestValidateReviewerSelection(t *testing.T) {
+	valid := reviewerSelectionConfig{Assignees: []string{sourceAuthor}, Reviewers: []string{sourceReviewers, sourceMaintainers}}
+	if err := valid.validate(); err != nil {
+		t.Errorf("validate() error = %v", err)
+	}
+	invalid := reviewerSelectionConfig{Reviewers: []string{"committers"}}
+	if err := invalid.validate(); err == nil {
+		t.Errorf("validate() of an unknown source succeeded")
+	}
+}
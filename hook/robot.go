@@ -15,6 +15,10 @@ package hook
 
 import (
 	"sync-bot/git"
+	"sync-bot/platform"
+	"sync-bot/report"
+	"sync-bot/secret"
+	"sync-bot/sig"
 	"sync-bot/util"
 
 	"github.com/opensourceways/robot-framework-lib/client"
@@ -26,6 +30,33 @@ import (
 
 // iClient is an interface that defines methods for client-side interactions
 type iClient interface {
+	frameworkClient
+	// ListPullRequestCommentsWithID lists the comments of a pull request with their ids, the latest first
+	ListPullRequestCommentsWithID(org, repo, number string) (result []platform.Comment, success bool)
+	EditPRComment(org, repo, number, commentID, comment string) (success bool)
+	// ListIssueCommentsWithID lists the comments of an issue with their ids, the latest first
+	ListIssueCommentsWithID(org, repo, number string) (result []platform.Comment, success bool)
+	EditIssueComment(org, repo, number, commentID, comment string) (success bool)
+	// GetAuthenticatedUser gets the login of the user the token belongs to
+	GetAuthenticatedUser() (login string, success bool)
+	// ListOpenPullRequests lists the pull requests open on a repository
+	ListOpenPullRequests(org, repo string) (result []client.PullRequest, success bool)
+	// ListOrgRepos lists the names of the repositories of an organization
+	ListOrgRepos(org string) (result []string, success bool)
+	// ListProtectedBranches lists the protected branches of a repository
+	ListProtectedBranches(org, repo string) (result []string, success bool)
+	// GetPullRequestAuthor gets the login of the user who opened a pull request
+	GetPullRequestAuthor(org, repo, number string) (login string, success bool)
+	// RequestPRReviewers requests the reviews of the users on a pull request
+	RequestPRReviewers(org, repo, number string, reviewers []string) (success bool)
+	// GetPullRequestReviewers gets the users who reviewed or approved a pull request or whose reviews are requested
+	GetPullRequestReviewers(org, repo, number string) (result []string, success bool)
+	// AssignPR assigns the users to a pull request
+	AssignPR(org, repo, number string, assignees []string) (success bool)
+}
+
+// frameworkClient holds the methods of the platform client of the robot framework.
+type frameworkClient interface {
 	// GetPullRequest gets a pull request in a specified organization and repository
 	GetPullRequest(org, repo, number string) (result client.PullRequest, success bool)
 	GetPathContent(org, repo, path, branch string) (result client.RepoContent, success bool)
@@ -59,29 +90,79 @@ type robot struct {
 	cnf       *Configuration
 	log       *logrus.Entry
 	GitClient *git.Client
+	// platform builds the URLs of the code hosting platform.
+	platform *platform.Platform
+	// sigs looks up the SIGs of the repositories, it is nil if no SigInfoURL is configured.
+	sigs *sig.Client
 }
 
 func (bot *robot) GetConfigmap() config.Configmap {
 	return bot.cnf
 }
 
-func NewRobot(c *Configuration, token []byte, logger *logrus.Entry) *robot {
-	cli := client.NewClient(token, logger)
-	if cli == nil {
+// NewRobot creates the robot. The api token is used for reading and commenting,
+// the push token for pushing branches and creating the sync pull requests.
+// Tokens are generated on every use, so the platform and git clients follow a
+// rotated secret without restarting.
+func NewRobot(c *Configuration, tokens *secret.Tokens, logger *logrus.Entry) *robot {
+	bot := newCommandRobot(c, tokens, logger)
+	if bot == nil {
 		return nil
 	}
-	gitClient, err := git.NewClient()
-	if err != nil {
-		logrus.WithError(err).Fatalf("New git client failed: %v", err)
-	}
-	gitClient.SetCredentials("LiYanghang00", token)
 	go func() {
-		if err := gitClient.PrewarmLargeRepos(); err != nil {
+		if err := bot.GitClient.PrewarmLargeRepos(); err != nil {
 			logrus.WithError(err).Warnf("Prewarm large repos failed")
 		}
 	}()
+	return bot
+}
+
+// CommandRobot is what the commands run once from a shell use of the robot.
+type CommandRobot interface {
+	SyncPullRequest(org, repo, number string, branches []string, strategy string, dryRun bool) ([]SyncResult, error)
+	SyncBranch(org, repo, from, to string) (*BranchSyncResult, error)
+	DivergenceReport(org string, repos []string, references []string) (*report.Report, error)
+}
+
+// NewCommandRobot creates the robot for a command run once from a shell, it
+// returns nil if the robot can't be created.
+func NewCommandRobot(c *Configuration, tokens *secret.Tokens, logger *logrus.Entry) CommandRobot {
+	if bot := newCommandRobot(c, tokens, logger); bot != nil {
+		return bot
+	}
+	return nil
+}
+
+// newCommandRobot creates the robot. Unlike NewRobot, it doesn't prewarm the
+// clones of large repositories.
+func newCommandRobot(c *Configuration, tokens *secret.Tokens, logger *logrus.Entry) *robot {
+	p, err := platform.New(c.Platform, c.PlatformHost)
+	if err != nil {
+		logger.WithError(err).Errorln("Unsupported platform")
+		return nil
+	}
+	cli := newRoleClient(tokens, newClientFactory(p, logger), logger)
+	if err = cli.check(); err != nil {
+		logger.WithError(err).Errorln("Check the platform tokens failed")
+		return nil
+	}
+	gitClient, err := git.NewClientWithHost(p.Host())
+	if err != nil {
+		logrus.WithError(err).Fatalf("New git client failed: %v", err)
+	}
+	gitClient.SetPullRequestRef(p.PullRequestRef())
+	gitClient.SetCredentials(cli.pushUser(""), tokens.Generator("", secret.RolePush))
+	for _, org := range tokens.Orgs() {
+		gitClient.SetOrgCredentials(org, cli.pushUser(org), tokens.Generator(org, secret.RolePush))
+	}
+	if c.Committer != nil {
+		gitClient.SetCommitter(c.Committer.Name, c.Committer.Email)
+	}
+	if err = gitClient.ScrubRemotes(); err != nil {
+		logrus.WithError(err).Warnf("Scrub credentials from cached remotes failed")
+	}
 
-	return &robot{cli: cli, cnf: c, log: logger, GitClient: gitClient}
+	return &robot{cli: cli, cnf: c, log: logger, GitClient: gitClient, platform: p, sigs: newSigClient(c)}
 }
 
 func (bot *robot) NewConfig() config.Configmap {
@@ -91,6 +172,7 @@ func (bot *robot) NewConfig() config.Configmap {
 func (bot *robot) RegisterEventHandler(p framework.HandlerRegister) {
 	p.RegisterPullRequestHandler(bot.handlePREvent)
 	p.RegisterPullRequestCommentHandler(bot.handlePullRequestCommentEvent)
+	p.RegisterIssueCommentHandler(bot.handleIssueCommentEvent)
 }
 
 func (bot *robot) GetLogger() *logrus.Entry {
@@ -121,21 +203,25 @@ func (bot *robot) handlePREvent(evt *client.GenericEvent, repoCnfPtr any, logger
 		}
 	} else if bot.cli.CheckIfPRMergeEvent(evt) {
 		if util.MatchTitle(title) {
-			logger.Infoln("Merge Pull Request which created by sync-bot, ignore it.")
+			bot.continueSyncChain(evt, logger)
+			bot.pruneSyncBranch(evt, org, repo, logger)
+			bot.boardSyncPRDone(evt, logger)
 		} else if util.MatchSyncBranch(targetBranch) {
 			logger.Infoln("Merge Pull Request to sync branch, ignore it.")
 		} else {
 			bot.MergePullRequest(evt, logger)
+			bot.offerSyncFollowUp(evt, logger)
 		}
 	} else if bot.cli.CheckIfPRSourceCodeUpdateEvent(evt) {
 		if util.MatchSyncBranch(targetBranch) {
 			bot.AutoMerge(evt, org, repo, number, logger)
 		} else {
-			logger.Infoln("Ignoring unhandled action:", evt.Action)
+			bot.offerSyncUpdate(evt, logger)
 		}
 	} else if bot.cli.CheckIfPRCloseEvent(evt) {
 		if util.MatchTitle(title) {
 			bot.ClosePullRequest(evt, org, repo, number, logger)
+			bot.boardSyncPRDone(evt, logger)
 		} else {
 			logger.Infoln("Pull request not create by sync-bot, ignoring it.")
 		}
@@ -145,3 +231,7 @@ func (bot *robot) handlePREvent(evt *client.GenericEvent, repoCnfPtr any, logger
 func (bot *robot) handlePullRequestCommentEvent(evt *client.GenericEvent, repoCnfPtr any, logger *logrus.Entry) {
 	bot.NotePullRequest(evt, logger)
 }
+
+func (bot *robot) handleIssueCommentEvent(evt *client.GenericEvent, repoCnfPtr any, logger *logrus.Entry) {
+	bot.NoteIssue(evt, logger)
+}
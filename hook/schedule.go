@@ -0,0 +1,30 @@
+package hook
+
+import (
+	"sync"
+	"time"
+)
+
+// every runs fn every interval until stop is called. A run is never started
+// before the previous one returns.
+func every(interval time.Duration, fn func()) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				fn()
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package hook
+
+import (
+	"strings"
+	"time"
+
+	"sync-bot/sig"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// sigCacheTTL is how long the SIGs of a repository are cached.
+const sigCacheTTL = 10 * time.Minute
+
+// newSigClient returns the client of the SIG info API of the configuration, nil
+// if no SigInfoURL is configured.
+func newSigClient(c *Configuration) *sig.Client {
+	if c == nil || c.SigInfoURL == "" {
+		return nil
+	}
+	return sig.NewClient(c.SigInfoURL, c.CommunityName, sigCacheTTL)
+}
+
+// sigMaintainers returns the maintainers of the SIGs org/repo belongs to, none
+// if there is no SIG info.
+func (bot *robot) sigMaintainers(org, repo string) ([]string, error) {
+	if bot.sigs == nil {
+		return nil, nil
+	}
+	return bot.sigs.Maintainers(org, repo)
+}
+
+// canSync checks if user may run /sync on the pull request: its author may, and
+// so may the users the platform lets write its base branch. The client of the
+// robot framework counts the maintainers and committers of the SIGs of the
+// repository in, from the SIG info set with client.SetSigInfoBaseURL.
+func (bot *robot) canSync(org, repo, number, user string, logger *logrus.Entry) bool {
+	if author, ok := bot.cli.GetPullRequestAuthor(org, repo, number); ok && author == user {
+		return true
+	}
+	pr, ok := bot.cli.GetPullRequest(org, repo, number)
+	if !ok {
+		logger.Warnf("Get pull request %s/%s#%s failed", org, repo, number)
+		return false
+	}
+	pass, ok := bot.cli.CheckPermissionWithBranch(org, repo, user, utils.GetString(pr.Base))
+	if !ok {
+		logger.Warnf("Check the permission of %s on %s/%s failed", user, org, repo)
+	}
+	return pass
+}
+
+// replySyncStatus replies status to the /sync command of user, like that the
+// user is not allowed to sync.
+func (bot *robot) replySyncStatus(evt *client.GenericEvent, user, command, status string, logger *logrus.Entry) {
+	comment, err := executeTemplate(replyCloseTmpl, struct {
+		URL     string
+		Command string
+		User    string
+		Status  string
+	}{
+		URL:     utils.GetString(evt.HtmlURL),
+		Command: strings.TrimSpace(command),
+		User:    user,
+		Status:  status,
+	})
+	if err != nil {
+		logger.Errorln("Execute template failed:", err)
+		return
+	}
+	if !bot.cli.CreatePRComment(utils.GetString(evt.Org), utils.GetString(evt.Repo), utils.GetString(evt.Number), comment) {
+		logger.Errorln("Create comment failed")
+	}
+}
+
+// settled are the sync statuses of the branches needing nobody's help.
+var settled = map[string]bool{
+	createdPR:      true,
+	syncUpdated:    true,
+	syncPRExists:   true,
+	syncPRClosed:   true,
+	branchNonExist: true,
+	emptyCherry:    true,
+}
+
+// failureMentions returns the SIG maintainers to mention if some branches of
+// status failed to sync.
+func (bot *robot) failureMentions(org, repo string, status []syncStatus, logger *logrus.Entry) []string {
+	for _, st := range status {
+		if settled[st.Status] {
+			continue
+		}
+		maintainers, err := bot.sigMaintainers(org, repo)
+		if err != nil {
+			logger.WithError(err).Warnf("Get the SIG maintainers of %s/%s failed", org, repo)
+		}
+		return maintainers
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package hook
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"sync-bot/fake"
+	"sync-bot/sig"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSyncWithSigInfo(t *testing.T) {
+	bot, cli, _ := newSyncScenario(t)
+	logger := logrus.NewEntry(logrus.StandardLogger())
+	sigs := fake.NewSigServer(map[string][]sig.SIG{
+		"o/r": {{Name: "Kernel", Maintainers: []string{"carol"}, Committers: []string{"dave"}}},
+	})
+	defer sigs.Close()
+	bot.sigs = sig.NewClient(sigs.URL, "openeuler", time.Hour)
+	cli.Authors[fake.Key("o", "r", "1")] = "bob"
+	// the platform grants the SIG members but alice
+	cli.Writers[fake.Key("o", "r", "alice")] = false
+	cli.Writers[fake.Key("o", "r", "dave")] = true
+
+	bot.handlePullRequestCommentEvent(commentEvent("/sync stable old", "merged"), nil, logger)
+	if len(cli.CreatedPRs) != 0 || len(cli.PRComments) != 1 || !strings.Contains(cli.PRComments[0].Body, notSigMember) {
+		t.Fatalf("created %v and commented %v, want the /sync of alice refused", cli.CreatedPRs, cli.PRComments)
+	}
+	// a dry run is refused as well
+	bot.handlePullRequestCommentEvent(commentEvent("/sync -dry-run stable", "merged"), nil, logger)
+	if len(cli.PRComments) != 2 || !strings.Contains(cli.PRComments[1].Body, notSigMember) {
+		t.Fatalf("commented %v, want the dry run of alice refused", cli.PRComments)
+	}
+	// so is an update
+	bot.handlePullRequestCommentEvent(commentEvent("/sync-update", "merged"), nil, logger)
+	if len(cli.PRComments) != 3 || !strings.Contains(cli.PRComments[2].Body, notSigMember) {
+		t.Fatalf("commented %v, want the update of alice refused", cli.PRComments)
+	}
+
+	evt := commentEvent("/sync stable old", "merged")
+	user := "dave"
+	evt.Commenter = &user
+	bot.handlePullRequestCommentEvent(evt, nil, logger)
+
+	if len(cli.CreatedPRs) != 1 {
+		t.Fatalf("created %v, want the sync pull request to stable", cli.CreatedPRs)
+	}
+	if got, want := cli.Reviewers[fake.Key("o", "r", "1000")], []string{"carol"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("reviewers = %v, want %v", got, want)
+	}
+	if result := cli.PRComments[3].Body; !strings.Contains(result, "请 @carol 协助处理") {
+		t.Errorf("result %q doesn't mention the maintainers of the conflict on old", result)
+	}
+}
+
+func TestCanSync(t *testing.T) {
+	bot, cli, _ := newSyncScenario(t)
+	logger := logrus.NewEntry(logrus.StandardLogger())
+	cli.Authors[fake.Key("o", "r", "1")] = "bob"
+	cli.Writers[fake.Key("o", "r", "carol")] = true
+
+	for user, want := range map[string]bool{"alice": true, "bob": true, "carol": true, "eve": false} {
+		if got := bot.canSync("o", "r", "1", user, logger); got != want {
+			t.Errorf("canSync(%s) = %v, want %v", user, got, want)
+		}
+	}
+
+	// the author may still when the permission can't be checked
+	cli.Fail["CheckPermissionWithBranch"] = true
+	for user, want := range map[string]bool{"bob": true, "carol": false} {
+		if got := bot.canSync("o", "r", "1", user, logger); got != want {
+			t.Errorf("canSync(%s) without the permission = %v, want %v", user, got, want)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package hook
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"sync-bot/git"
+	"sync-bot/util"
+
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// staleSyncPRConfig configures the reminders on the sync pull requests left
+// unmerged.
+type staleSyncPRConfig struct {
+	Org   string   `json:"org"`
+	Repos []string `json:"repos"`
+	// Interval is the interval between two scans, like 24h.
+	Interval string `json:"interval"`
+	// RemindAfter is how long a sync pull request is left unchanged before its
+	// original author and the SIG maintainers are reminded, like 336h.
+	RemindAfter string `json:"remind_after"`
+	// CloseAfter is how long a sync pull request is left unchanged before it is
+	// closed and its source branch deleted, like 2160h. It is never closed if empty.
+	CloseAfter string `json:"close_after,omitempty"`
+}
+
+func (c *staleSyncPRConfig) validate() error {
+	if c.Org == "" || len(c.Repos) == 0 {
+		return errors.New("stale_sync_pr: org and repos are required")
+	}
+	_, err := c.durations()
+	return err
+}
+
+// staleDurations are the durations of a staleSyncPRConfig.
+type staleDurations struct {
+	interval, remindAfter, closeAfter time.Duration
+}
+
+func (c *staleSyncPRConfig) durations() (d staleDurations, err error) {
+	parse := func(name, value string) (time.Duration, error) {
+		v, err := time.ParseDuration(value)
+		if err != nil || v <= 0 {
+			return 0, fmt.Errorf("stale_sync_pr: invalid %s %q", name, value)
+		}
+		return v, nil
+	}
+	if d.interval, err = parse("interval", c.Interval); err != nil {
+		return
+	}
+	if d.remindAfter, err = parse("remind_after", c.RemindAfter); err != nil {
+		return
+	}
+	if c.CloseAfter != "" {
+		if d.closeAfter, err = parse("close_after", c.CloseAfter); err != nil {
+			return
+		}
+		if d.closeAfter <= d.remindAfter {
+			return d, errors.New("stale_sync_pr: close_after must be longer than remind_after")
+		}
+	}
+	return d, nil
+}
+
+// staleRemindedRegex matches the marker of a reminder, it holds the commit time
+// of the sync branch when reminded.
+var staleRemindedRegex = regexp.MustCompile(`<!-- sync-stale: (\d+) -->`)
+
+// StartStaleSyncReminder scans the configured repositories for stale sync
+// pull requests every interval until stop is called. It does nothing if no
+// reminder is configured.
+func (bot *robot) StartStaleSyncReminder() (stop func()) {
+	c := bot.cnf.StaleSyncPR
+	if c == nil {
+		return func() {}
+	}
+	d, err := c.durations()
+	if err != nil {
+		bot.log.WithError(err).Errorln("Schedule stale sync pull request reminder failed")
+		return func() {}
+	}
+	return every(d.interval, func() {
+		bot.remindStaleSyncPRs(c.Org, c.Repos, d, time.Now())
+	})
+}
+
+// remindStaleSyncPRs reminds or closes the sync pull requests of the
+// repositories of org whose source branches are unchanged since long before now.
+func (bot *robot) remindStaleSyncPRs(org string, repos []string, d staleDurations, now time.Time) {
+	for _, repo := range repos {
+		logger := bot.log.WithField("repo", org+"/"+repo)
+		if err := bot.remindStaleRepo(org, repo, d, now, logger); err != nil {
+			logger.WithError(err).Errorln("Remind stale sync pull requests failed")
+		}
+	}
+}
+
+func (bot *robot) remindStaleRepo(org, repo string, d staleDurations, now time.Time, logger *logrus.Entry) error {
+	r, err := bot.GitClient.Clone(org, repo)
+	if err != nil {
+		return err
+	}
+	heads, err := bot.openSyncHeads(org, repo)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(heads))
+	for head := range heads {
+		names = append(names, head)
+	}
+	sort.Strings(names)
+
+	for _, head := range names {
+		match := syncHeadRegex.FindStringSubmatch(head)
+		if match == nil {
+			continue
+		}
+		rec, ok := bot.syncRecordOf(org, repo, match[1], head)
+		if !ok {
+			logger.Infof("No sync pull request recorded for %s, skip it.", head)
+			continue
+		}
+		_, _, number, ok := bot.platform.ParsePullRequestURL(rec.PR)
+		if !ok {
+			logger.Warnf("Unknown pull request URL %s", rec.PR)
+			continue
+		}
+		pr, ok := bot.cli.GetPullRequest(org, repo, number)
+		if !ok || !util.MatchTitle(utils.GetString(pr.Title)) {
+			continue
+		}
+		updated, err := r.CommitTime("origin/" + head)
+		if err != nil {
+			logger.WithError(err).Warnf("Get the update time of %s failed", head)
+			continue
+		}
+
+		stale := staleSyncPR{org: org, repo: repo, number: number, original: match[1], rec: rec, updated: updated}
+		age := now.Sub(updated)
+		switch {
+		case d.closeAfter > 0 && age >= d.closeAfter:
+			err = bot.closeStaleSyncPR(r, stale, d)
+		case age >= d.remindAfter:
+			err = bot.remindStaleSyncPR(stale, d)
+		}
+		if err != nil {
+			logger.WithError(err).Errorf("Handle stale sync pull request %s failed", rec.PR)
+		}
+	}
+	return nil
+}
+
+// syncRecordOf returns the record of the sync pull request whose source branch
+// is head among those of the pull request number.
+func (bot *robot) syncRecordOf(org, repo, number, head string) (syncRecord, bool) {
+	records, err := bot.syncRecords(org, repo, number)
+	if err != nil {
+		return syncRecord{}, false
+	}
+	for _, rec := range records {
+		if rec.Head == head {
+			return rec, true
+		}
+	}
+	return syncRecord{}, false
+}
+
+// staleSyncPR is a sync pull request left unmerged.
+type staleSyncPR struct {
+	org, repo, number string
+	// original is the number of the original pull request.
+	original string
+	rec      syncRecord
+	// updated is when the source branch was last changed.
+	updated time.Time
+}
+
+// remindStaleSyncPR comments a reminder on the sync pull request, once for
+// every change of its source branch.
+func (bot *robot) remindStaleSyncPR(s staleSyncPR, d staleDurations) error {
+	since := strconv.FormatInt(s.updated.Unix(), 10)
+	comments, ok := bot.cli.ListPullRequestComments(s.org, s.repo, s.number)
+	if !ok {
+		return errors.New("list pull request comments failed")
+	}
+	for _, c := range comments {
+		if m := staleRemindedRegex.FindStringSubmatch(c.Body); m != nil && m[1] == since {
+			return nil
+		}
+	}
+
+	comment, err := executeTemplate(staleReminderTmpl, struct {
+		Mentions  []string
+		Days      int
+		CloseDays int
+		Since     string
+	}{
+		Mentions:  bot.staleMentions(s),
+		Days:      days(d.remindAfter),
+		CloseDays: days(d.closeAfter),
+		Since:     since,
+	})
+	if err != nil {
+		return err
+	}
+	if !bot.cli.CreatePRComment(s.org, s.repo, s.number, comment) {
+		return errors.New("create comment failed")
+	}
+	return nil
+}
+
+// closeStaleSyncPR closes the sync pull request as /close does, by deleting
+// its source branch, after telling why.
+func (bot *robot) closeStaleSyncPR(r *git.Repo, s staleSyncPR, d staleDurations) error {
+	comment, err := executeTemplate(staleClosedTmpl, struct {
+		Mentions []string
+		Days     int
+		Branch   string
+	}{
+		Mentions: bot.staleMentions(s),
+		Days:     days(d.closeAfter),
+		Branch:   s.rec.Branch,
+	})
+	if err != nil {
+		return err
+	}
+	if !bot.cli.CreatePRComment(s.org, s.repo, s.number, comment) {
+		return errors.New("create comment failed")
+	}
+	return r.DeleteRemoteBranch(s.rec.Head)
+}
+
+// staleMentions returns the author of the original pull request and the SIG
+// maintainers of the repository, a failure to find some of them is logged.
+func (bot *robot) staleMentions(s staleSyncPR) []string {
+	var mentions []string
+	if author, ok := bot.cli.GetPullRequestAuthor(s.org, s.repo, s.original); ok && author != "" {
+		mentions = append(mentions, author)
+	} else {
+		bot.log.Warnf("Get the author of %s/%s#%s failed", s.org, s.repo, s.original)
+	}
+	maintainers, err := bot.sigMaintainers(s.org, s.repo)
+	if err != nil {
+		bot.log.WithError(err).Warnf("Get the SIG maintainers of %s/%s failed", s.org, s.repo)
+	}
+	for _, m := range maintainers {
+		if !util.ContainsString(mentions, m) {
+			mentions = append(mentions, m)
+		}
+	}
+	return mentions
+}
+
+// days returns d in whole days.
+func days(d time.Duration) int {
+	return int(d / (24 * time.Hour))
+}
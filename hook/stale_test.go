@@ -0,0 +1,92 @@
+package hook
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"sync-bot/fake"
+	"sync-bot/sig"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRemindStaleSyncPRs(t *testing.T) {
+	bot, cli, f := newSyncScenario(t)
+	logger := logrus.NewEntry(logrus.StandardLogger())
+	bot.handlePullRequestCommentEvent(commentEvent("/sync stable", "merged"), nil, logger)
+	cli.Authors[fake.Key("o", "r", "1")] = "bob"
+
+	sigs := fake.NewSigServer(map[string][]sig.SIG{"o/r": {{Name: "Kernel", Maintainers: []string{"carol", "bob"}}}})
+	defer sigs.Close()
+	bot.sigs = sig.NewClient(sigs.URL, "openeuler", time.Hour)
+
+	d := staleDurations{remindAfter: 24 * time.Hour, closeAfter: 72 * time.Hour}
+	key := fake.Key("o", "r", "1000")
+
+	bot.remindStaleSyncPRs("o", []string{"r"}, d, time.Now())
+	if n := len(cli.Comments[key]); n != 0 {
+		t.Fatalf("got %d comments on a fresh sync pull request, want none", n)
+	}
+
+	later := time.Now().Add(48 * time.Hour)
+	bot.remindStaleSyncPRs("o", []string{"r"}, d, later)
+	bot.remindStaleSyncPRs("o", []string{"r"}, d, later.Add(time.Hour))
+	comments := cli.Comments[key]
+	if len(comments) != 1 {
+		t.Fatalf("got %d comments, want a single reminder", len(comments))
+	}
+	if reminder := comments[0].Body; !strings.Contains(reminder, "@bob @carol") || !strings.Contains(reminder, "3 天") {
+		t.Errorf("reminder %q doesn't mention the author and the maintainers", reminder)
+	}
+
+	bot.remindStaleSyncPRs("o", []string{"r"}, d, time.Now().Add(96*time.Hour))
+	if comments = cli.Comments[key]; len(comments) != 2 || !strings.Contains(comments[0].Body, "/sync stable") {
+		t.Errorf("comments %v, want the sync pull request closed", comments)
+	}
+	if heads := f.git(filepath.Join(f.base, "o", "r.git"), "branch", "--list", "sync-pr*"); heads != "" {
+		t.Errorf("sync branches %q left, want the source branch deleted", heads)
+	}
+}
+
+func TestValidateStaleSyncPR(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  staleSyncPRConfig
+		wantErr bool
+	}{
+		{
+			name:   "remind only",
+			config: staleSyncPRConfig{Org: "o", Repos: []string{"r"}, Interval: "24h", RemindAfter: "336h"},
+		},
+		{
+			name: "remind and close",
+			config: staleSyncPRConfig{Org: "o", Repos: []string{"r"}, Interval: "24h", RemindAfter: "336h",
+				CloseAfter: "2160h"},
+		},
+		{
+			name:    "no repos",
+			config:  staleSyncPRConfig{Org: "o", Interval: "24h", RemindAfter: "336h"},
+			wantErr: true,
+		},
+		{
+			name:    "bad remind_after",
+			config:  staleSyncPRConfig{Org: "o", Repos: []string{"r"}, Interval: "24h", RemindAfter: "2w"},
+			wantErr: true,
+		},
+		{
+			name: "close before remind",
+			config: staleSyncPRConfig{Org: "o", Repos: []string{"r"}, Interval: "24h", RemindAfter: "336h",
+				CloseAfter: "24h"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package hook
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// states of the target branches on the status board
+const (
+	statePending  = "pending"
+	stateMerged   = "merged"
+	stateClosed   = "closed"
+	stateConflict = "conflict"
+)
+
+// statusBoardRegex matches the marker of the status board on an original pull request.
+var statusBoardRegex = regexp.MustCompile(`<!-- sync-status:([^>]*) -->`)
+
+// boardEntry is a target branch on the status board of an original pull request.
+// The status board is the one record of how the sync pull requests of the
+// original pull request go, the issues linked to it show the same entries.
+type boardEntry struct {
+	Branch string
+	// PR is the sync pull request, it is empty on conflict.
+	PR string
+	// State is one of statePending, stateMerged, stateClosed and stateConflict.
+	State string
+}
+
+// parseStatusBoard returns the entries of the status board, ok is false if
+// body is no status board.
+func parseStatusBoard(body string) (entries []boardEntry, ok bool) {
+	match := statusBoardRegex.FindStringSubmatch(body)
+	if match == nil {
+		return nil, false
+	}
+	for _, field := range strings.Fields(match[1]) {
+		parts := strings.SplitN(field, "|", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		entries = append(entries, boardEntry{Branch: parts[0], State: parts[1], PR: parts[2]})
+	}
+	return entries, true
+}
+
+// boardEntries returns the entries of the status board for the result of a
+// sync, the branches not synced are left out.
+func boardEntries(status []syncStatus) []boardEntry {
+	var entries []boardEntry
+	for _, st := range status {
+		switch {
+		case st.Status == syncFailed:
+			entries = append(entries, boardEntry{Branch: st.Name, State: stateConflict})
+		case st.PR != "" && (st.Status == createdPR || st.Status == syncUpdated || st.Status == syncPRExists):
+			entries = append(entries, boardEntry{Branch: st.Name, PR: st.PR, State: statePending})
+		}
+	}
+	return entries
+}
+
+// mergeBoardEntries replaces the entries of the branches of updates and adds
+// the others.
+func mergeBoardEntries(entries, updates []boardEntry) []boardEntry {
+	result := append([]boardEntry{}, entries...)
+	for _, u := range updates {
+		replaced := false
+		for i := range result {
+			if result[i].Branch == u.Branch {
+				result[i], replaced = u, true
+			}
+		}
+		if !replaced {
+			result = append(result, u)
+		}
+	}
+	return result
+}
+
+// updateStatusBoard edits the status board on the pull request with the
+// entries returned by update, it is created if there is none yet. It returns
+// the entries shown.
+func (bot *robot) updateStatusBoard(org, repo, number string, update func([]boardEntry) []boardEntry) ([]boardEntry, error) {
+	comments, ok := bot.cli.ListPullRequestCommentsWithID(org, repo, number)
+	if !ok {
+		return nil, errors.New("list pull request comments failed")
+	}
+	var id string
+	var entries []boardEntry
+	for _, c := range comments {
+		if e, ok := parseStatusBoard(c.Body); ok {
+			id, entries = c.ID, e
+			break
+		}
+	}
+	entries = update(entries)
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	board, err := executeTemplate(statusBoardTmpl, entries)
+	if err != nil {
+		return nil, err
+	}
+	if id != "" {
+		if bot.cli.EditPRComment(org, repo, number, id, board) {
+			return entries, nil
+		}
+		logrus.Warnf("Edit the status board of %s/%s#%s failed, comment a new one", org, repo, number)
+	}
+	if !bot.cli.CreatePRComment(org, repo, number, board) {
+		return nil, errors.New("create comment failed")
+	}
+	return entries, nil
+}
+
+// trackSync updates the status board of the original pull request number with
+// update, then shows its entries on the issues linked to the pull request.
+func (bot *robot) trackSync(org, repo, number string, update func([]boardEntry) []boardEntry, logger *logrus.Entry) {
+	entries, err := bot.updateStatusBoard(org, repo, number, update)
+	if err != nil {
+		logger.WithError(err).Errorln("Update the status board failed")
+		return
+	}
+	if len(entries) == 0 {
+		return
+	}
+	if err = bot.linkSyncIssues(org, repo, number, entries, logger); err != nil {
+		logger.WithError(err).Errorln("Comment sync pull requests on linked issues failed")
+	}
+}
+
+// boardSync tracks the result of a sync of the pull request.
+func (bot *robot) boardSync(org, repo, number string, status []syncStatus, logger *logrus.Entry) {
+	updates := boardEntries(status)
+	if len(updates) == 0 {
+		return
+	}
+	bot.trackSync(org, repo, number, func(entries []boardEntry) []boardEntry {
+		return mergeBoardEntries(entries, updates)
+	}, logger)
+}
+
+// boardSyncPRDone tracks, once a sync pull request is merged or closed, its
+// state on the status board of the original pull request.
+func (bot *robot) boardSyncPRDone(evt *client.GenericEvent, logger *logrus.Entry) {
+	org := utils.GetString(evt.Org)
+	repo := utils.GetString(evt.Repo)
+	number := utils.GetString(evt.Number)
+
+	match := syncHeadRegex.FindStringSubmatch(utils.GetString(evt.Head))
+	if match == nil {
+		return
+	}
+	state := stateClosed
+	if bot.cli.CheckIfPRMergeEvent(evt) {
+		state = stateMerged
+	}
+	url := bot.platform.PullRequestURL(org, repo, number)
+	bot.trackSync(org, repo, match[1], func(entries []boardEntry) []boardEntry {
+		for i := range entries {
+			if entries[i].PR == url {
+				entries[i].State = state
+			}
+		}
+		return entries
+	}, logger)
+}
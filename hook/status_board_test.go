@@ -0,0 +1,70 @@
+package hook
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"sync-bot/fake"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestStatusBoard(t *testing.T) {
+	bot, cli, _ := newSyncScenario(t)
+	logger := logrus.NewEntry(logrus.StandardLogger())
+	syncPR := bot.platform.PullRequestURL("o", "r", "1000")
+
+	bot.handlePullRequestCommentEvent(commentEvent("/sync stable old", "merged"), nil, logger)
+
+	board := cli.PRComments[len(cli.PRComments)-1].Body
+	for _, want := range []string{
+		"|stable|" + syncPR + "|" + syncPRPending + "|",
+		"|old||" + syncConflict + "|",
+	} {
+		if !strings.Contains(board, want) {
+			t.Errorf("board %q doesn't hold %q", board, want)
+		}
+	}
+
+	// the sync pull request to stable gets merged
+	evt := mergeEvent("1000", "[sync] PR-1: fix a", "stable")
+	head := "sync-pr1-feature-to-stable"
+	evt.Head = &head
+	comments := len(cli.PRComments)
+	bot.handlePREvent(evt, nil, logger)
+
+	if len(cli.PRComments) != comments {
+		t.Errorf("got %d comments, want the status board edited in place", len(cli.PRComments)-comments)
+	}
+	if len(cli.EditedComments) != 1 {
+		t.Fatalf("edited %v, want the status board", cli.EditedComments)
+	}
+	entries, _ := parseStatusBoard(cli.Comments[fake.Key("o", "r", "1")][0].Body)
+	want := []boardEntry{
+		{Branch: "stable", PR: syncPR, State: stateMerged},
+		{Branch: "old", State: stateConflict},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("board entries = %v, want %v", entries, want)
+	}
+}
+
+func TestMergeBoardEntries(t *testing.T) {
+	entries := []boardEntry{
+		{Branch: "stable", PR: "https://x/1000", State: stateMerged},
+		{Branch: "old", State: stateConflict},
+	}
+	updates := []boardEntry{
+		{Branch: "old", PR: "https://x/1001", State: statePending},
+		{Branch: "next", PR: "https://x/1002", State: statePending},
+	}
+	want := []boardEntry{
+		{Branch: "stable", PR: "https://x/1000", State: stateMerged},
+		{Branch: "old", PR: "https://x/1001", State: statePending},
+		{Branch: "next", PR: "https://x/1002", State: statePending},
+	}
+	if got := mergeBoardEntries(entries, updates); !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeBoardEntries() = %v, want %v", got, want)
+	}
+}
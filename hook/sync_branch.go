@@ -0,0 +1,180 @@
+package hook
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"sync-bot/git"
+	"sync-bot/util"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// BranchSyncResult is the result of syncing the tip of a branch to another.
+type BranchSyncResult struct {
+	From   string
+	To     string
+	Status string
+	// PullRequest is the URL of the sync pull request, if one was created.
+	PullRequest string
+	// Detail tells more about the status, like the conflicting files.
+	Detail string
+	// Picked are the commits of From missing from To, carried by the pull request.
+	Picked []git.Commit
+	// Skipped are the commits of From whose changes are already on To.
+	Skipped []git.Commit
+}
+
+// NoteIssue handles the commands commented on issues.
+func (bot *robot) NoteIssue(evt *client.GenericEvent, logger *logrus.Entry) {
+	comment := utils.GetString(evt.Comment)
+
+	if util.MatchSyncBranchCommand(comment) {
+		logger.Infoln("Receive /sync-branch command")
+		_ = bot.replySyncBranch(evt, logger)
+		return
+	}
+
+	logger.Infoln("Ignoring unhandled comment.")
+}
+
+// replySyncBranch syncs the branches of a /sync-branch command and replies the
+// result on the issue. Only the users who can write to the target branch may
+// run it.
+func (bot *robot) replySyncBranch(evt *client.GenericEvent, logger *logrus.Entry) error {
+	org := utils.GetString(evt.Org)
+	repo := utils.GetString(evt.Repo)
+	number := utils.GetString(evt.Number)
+	comment := utils.GetString(evt.Comment)
+	user := utils.GetString(evt.Commenter)
+
+	from, to, err := parseSyncBranchCommand(comment)
+	if err != nil {
+		logger.Errorln("Parse /sync-branch command failed:", err)
+		return err
+	}
+
+	var result *BranchSyncResult
+	if pass, ok := bot.cli.CheckPermissionWithBranch(org, repo, user, to); !ok || !pass {
+		logger.Infof("%s can't write to %s, ignore /sync-branch.", user, to)
+		result = &BranchSyncResult{From: from, To: to, Status: noPermission}
+	} else if result, err = bot.syncBranch(org, repo, from, to, logger); err != nil {
+		return err
+	}
+
+	reply, err := executeTemplate(syncBranchResultTmpl, struct {
+		URL     string
+		User    string
+		Command string
+		Result  *BranchSyncResult
+	}{
+		URL:     utils.GetString(evt.HtmlURL),
+		User:    user,
+		Command: strings.TrimSpace(comment),
+		Result:  result,
+	})
+	if err != nil {
+		logger.Errorln("Execute template failed:", err)
+		return err
+	}
+	if !bot.cli.CreateIssueComment(org, repo, number, reply) {
+		logger.Errorln("Create comment failed")
+		return errors.New("create comment failed")
+	}
+	logger.Infoln("Reply sync branch.")
+	return nil
+}
+
+// syncBranch opens one pull request carrying the commits of from missing from
+// to. Commits whose changes are already on to, compared by patch id, are
+// skipped.
+func (bot *robot) syncBranch(org, repo, from, to string, logger *logrus.Entry) (*BranchSyncResult, error) {
+	branches, ok := bot.cli.GetRepoAllBranch(org, repo)
+	if !ok {
+		logger.Errorln("List branches failed")
+		return nil, errors.New("list branches failed")
+	}
+	branchSet := make(map[string]bool)
+	for _, b := range branches {
+		branchSet[b.Name] = true
+	}
+	result := &BranchSyncResult{From: from, To: to}
+	for _, b := range []string{from, to} {
+		if !branchSet[b] {
+			result.Status = branchNonExist
+			result.Detail = b
+			return result, nil
+		}
+	}
+
+	r, err := bot.GitClient.Clone(org, repo)
+	if err != nil {
+		logger.Errorf("Clone %s/%s failed: %v", org, repo, err)
+		return nil, err
+	}
+	_ = r.Clean()
+	result.Picked, result.Skipped, err = r.MissingCommits("origin/"+to, "origin/"+from)
+	if err != nil {
+		logger.Errorln("List missing commits failed:", err)
+		return nil, err
+	}
+	if len(result.Picked) == 0 {
+		result.Status = branchInSync
+		return result, nil
+	}
+
+	tempBranch := fmt.Sprintf("sync-branch-%v-to-%v", from, to)
+	if err = r.Checkout("origin/" + to); err != nil {
+		result.Status = err.Error()
+		return result, nil
+	}
+	if err = r.CheckoutNewBranch(tempBranch, true); err != nil {
+		result.Status = err.Error()
+		return result, nil
+	}
+	shas := make([]string, 0, len(result.Picked))
+	for _, c := range result.Picked {
+		shas = append(shas, c.SHA)
+	}
+	if err = r.CherryPickCommits(shas); err != nil {
+		logger.Errorln("Cherry pick failed:", err)
+		files, _ := r.ConflictFiles()
+		_ = r.CherryPickAbort()
+		result.Status = syncFailed
+		result.Detail = strings.Join(files, "<br>")
+		return result, nil
+	}
+	if err = r.Push(tempBranch, true); err != nil {
+		result.Status = err.Error()
+		return result, nil
+	}
+
+	body, err := executeTemplate(syncBranchPRBodyTmpl, result)
+	if err != nil {
+		logger.Errorln("Execute template failed:", err)
+		return nil, err
+	}
+	title := fmt.Sprintf("[sync] %v to %v", from, to)
+	prune := true
+	var forkPath string
+	num, ok := bot.cli.CreatePR(org, repo, client.PullRequest{
+		Title:             &title,
+		Body:              &body,
+		Head:              &tempBranch,
+		Base:              &to,
+		PruneSourceBranch: &prune,
+		ForkPath:          &forkPath,
+	})
+	if !ok {
+		logger.Errorln("Create PullRequest failed")
+		result.Status = createPRFailed
+		return result, nil
+	}
+	logger.Infoln("Create PullRequest:", num)
+	result.Status = createdPR
+	result.PullRequest = bot.platform.PullRequestURL(org, repo, num)
+	return result, nil
+}
@@ -0,0 +1,161 @@
+package hook
+
+import (
+	"strings"
+	"testing"
+
+	"sync-bot/fake"
+	"sync-bot/platform"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// newSyncBranchScenario prepares the repository o/r where master is ahead of
+// stable by "change a" and "add c", the latter already picked onto stable, and
+// old conflicts with "change a".
+func newSyncBranchScenario(t *testing.T) (*robot, *fake.Client, *gitFixture) {
+	f := newGitFixture(t)
+	dir := f.repo("o", "r", map[string]string{"a.txt": "1\n", "b.txt": "b\n"})
+	f.git(dir, "push", "origin", "master:stable", "master:old")
+
+	f.commit(dir, "change a", map[string]string{"a.txt": "2\n"})
+	added := f.commit(dir, "add c", map[string]string{"c.txt": "c\n"})
+	f.git(dir, "push", "origin", "master")
+
+	f.git(dir, "checkout", "-b", "stable", "origin/stable")
+	f.git(dir, "cherry-pick", added)
+	f.git(dir, "push", "origin", "stable")
+
+	f.git(dir, "checkout", "-b", "old", "origin/old")
+	f.commit(dir, "diverge", map[string]string{"a.txt": "x\n"})
+	f.git(dir, "push", "origin", "old")
+
+	bot, cli := newTestBot(t, f)
+	cli.Branches[fake.RepoKey("o", "r")] = []string{"master", "stable", "old"}
+	cli.Writers[fake.Key("o", "r", "alice")] = true
+	return bot, cli, f
+}
+
+func issueCommentEvent(user, comment string) *client.GenericEvent {
+	org, repo, number, url := "o", "r", "5", "https://fake/o/r/issues/5#note_1"
+	return &client.GenericEvent{Org: &org, Repo: &repo, Number: &number, Comment: &comment, Commenter: &user, HtmlURL: &url}
+}
+
+func TestSyncBranchCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    string
+		command string
+		// want are expected in the reply on the issue.
+		want []string
+		// created is the branch of the sync pull request expected.
+		created string
+	}{
+		{
+			name:    "missing commits",
+			user:    "alice",
+			command: "/sync-branch master stable",
+			want:    []string{createdPR, "|change a|" + commitPicked + "|", "|add c|" + commitSkipped + "|"},
+			created: "stable",
+		},
+		{
+			name:    "in sync",
+			user:    "alice",
+			command: "/sync-branch stable master",
+			want:    []string{branchInSync},
+		},
+		{
+			name:    "conflict",
+			user:    "alice",
+			command: "/sync-branch master old",
+			want:    []string{syncFailed, "a.txt"},
+		},
+		{
+			name:    "missing branch",
+			user:    "alice",
+			command: "/sync-branch master missing",
+			want:    []string{branchNonExist},
+		},
+		{
+			name:    "no permission",
+			user:    "mallory",
+			command: "/sync-branch master stable",
+			want:    []string{noPermission},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bot, cli, f := newSyncBranchScenario(t)
+
+			bot.dispatch(platform.IssueCommentEvent, issueCommentEvent(tt.user, tt.command), nil,
+				logrus.NewEntry(logrus.StandardLogger()))
+
+			if len(cli.IssueComments) != 1 || cli.IssueComments[0].Number != "5" {
+				t.Fatalf("issue comments %v, want the reply of /sync-branch", cli.IssueComments)
+			}
+			for _, want := range tt.want {
+				if reply := cli.IssueComments[0].Body; !strings.Contains(reply, want) {
+					t.Errorf("reply %q doesn't hold %q", reply, want)
+				}
+			}
+			if tt.created == "" {
+				if len(cli.CreatedPRs) != 0 {
+					t.Errorf("created pull requests %v, want none", cli.CreatedPRs)
+				}
+				return
+			}
+			if len(cli.CreatedPRs) != 1 {
+				t.Fatalf("created %d pull requests, want 1", len(cli.CreatedPRs))
+			}
+			pr := cli.CreatedPRs[0]
+			head, base := utils.GetString(pr.Head), utils.GetString(pr.Base)
+			if base != tt.created || head != "sync-branch-master-to-"+tt.created {
+				t.Errorf("created pull request %s -> %s", head, base)
+			}
+			if body := utils.GetString(pr.Body); !strings.Contains(body, "|change a|") || !strings.Contains(body, "|add c|") {
+				t.Errorf("pull request body %q doesn't list the picked and skipped commits", body)
+			}
+			if got := f.show("o", "r", head, "a.txt") + f.show("o", "r", head, "c.txt"); got != "2c" {
+				t.Errorf("synced content = %q, want %q", got, "2c")
+			}
+			if log := f.git(f.base+"/o/r.git", "log", "--format=%s", "stable.."+head); log != "change a" {
+				t.Errorf("synced commits = %q, want only the missing one", log)
+			}
+		})
+	}
+}
+
+func TestSyncBranchMerged(t *testing.T) {
+	bot, cli, f := newSyncBranchScenario(t)
+	// a chain from stable, which a sync pull request merged to stable goes on
+	bot.cnf = &Configuration{ConfigItems: []repoConfig{{
+		Repos:    []string{"o/r"},
+		Lineages: [][]string{{"master", "stable", "old"}},
+	}}}
+	logger := logrus.NewEntry(logrus.StandardLogger())
+
+	bot.dispatch(platform.IssueCommentEvent, issueCommentEvent("alice", "/sync-branch master stable"), nil, logger)
+	if len(cli.CreatedPRs) != 1 {
+		t.Fatalf("created %d pull requests, want the one of /sync-branch", len(cli.CreatedPRs))
+	}
+	pr := cli.CreatedPRs[0]
+	number, head := utils.GetString(pr.Number), utils.GetString(pr.Head)
+	cli.Commits[fake.Key("o", "r", number)] = f.mergePullRequest("o", "r", number, head, "stable")
+	cli.SetPullRequestState("o", "r", number, "merged")
+	evt := mergeEvent(number, utils.GetString(pr.Title), "stable")
+	evt.Head = pr.Head
+	bot.handlePREvent(evt, nil, logger)
+
+	// none of the hooks of the sync pull requests of a pull request runs
+	if len(cli.CreatedPRs) != 1 {
+		t.Errorf("created %v after the merge, want no sync chain", cli.CreatedPRs[1:])
+	}
+	if len(cli.PRComments)+len(cli.EditedComments) != 0 || len(cli.IssueComments) != 1 {
+		t.Errorf("commented %v %v and edited %v after the merge", cli.PRComments, cli.IssueComments[1:], cli.EditedComments)
+	}
+	if branches := f.git(f.base+"/o/r.git", "branch", "--list", head); branches != "" {
+		t.Errorf("branch %s is not pruned after the merge", branches)
+	}
+}
@@ -0,0 +1,624 @@
+package hook
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sync-bot/fake"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// newSyncScenario prepares the repository o/r with the branches stable, which
+// takes pull request 1 cleanly, and old, which conflicts with it. Pull request
+// 1 brings feature into master and is merged.
+func newSyncScenario(t *testing.T) (*robot, *fake.Client, *gitFixture) {
+	f := newGitFixture(t)
+	dir := f.repo("o", "r", map[string]string{"a.txt": "1\n", "b.txt": "b\n"})
+	f.git(dir, "push", "origin", "master:stable")
+
+	f.git(dir, "checkout", "-b", "old")
+	f.commit(dir, "diverge", map[string]string{"a.txt": "x\n"})
+	f.git(dir, "push", "origin", "old")
+
+	f.git(dir, "checkout", "-b", "feature", "master")
+	first := f.commit(dir, "change a", map[string]string{"a.txt": "2\n"})
+	last := f.commit(dir, "add c", map[string]string{"c.txt": "c\n"})
+	f.pullRequest(dir, "1")
+
+	bot, cli := newTestBot(t, f)
+	cli.Branches[fake.RepoKey("o", "r")] = []string{"master", "stable", "old"}
+	cli.Writers[fake.Key("o", "r", "alice")] = true
+	number, title, head, base, url := "1", "fix a", "feature", "master", "https://fake/o/r/pulls/1"
+	cli.AddPullRequest("o", "r", client.PullRequest{Number: &number, Title: &title, Head: &head, Base: &base, URL: &url},
+		commitOf(last, "add c"), commitOf(first, "change a"))
+	return bot, cli, f
+}
+
+func commentEvent(comment, state string) *client.GenericEvent {
+	org, repo, number, user, url, title := "o", "r", "1", "alice", "https://fake/o/r/pulls/1#note_1", "fix a"
+	return &client.GenericEvent{
+		Org: &org, Repo: &repo, Number: &number, Title: &title,
+		Comment: &comment, Commenter: &user, HtmlURL: &url, State: &state,
+	}
+}
+
+func TestSyncMergedPullRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		prepare func(f *gitFixture, cli *fake.Client)
+		// status is expected in the result comment for the branch.
+		status string
+		// created is the branch of the sync pull request expected.
+		created string
+		// board is true if the status board is expected after the result comment.
+		board bool
+	}{
+		{
+			name:    "success",
+			command: "/sync stable",
+			status:  createdPR,
+			created: "stable",
+			board:   true,
+		},
+		{
+			name:    "conflict",
+			command: "/sync old",
+			status:  syncFailed,
+			board:   true,
+		},
+		{
+			name:    "missing branch",
+			command: "/sync missing",
+			status:  branchNonExist,
+		},
+		{
+			name:    "push failure",
+			command: "/sync stable",
+			prepare: func(f *gitFixture, cli *fake.Client) {
+				f.rejectPushes("o", "r")
+			},
+			status: "rejected by fixture",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bot, cli, f := newSyncScenario(t)
+			if tt.prepare != nil {
+				tt.prepare(f, cli)
+			}
+
+			bot.handlePullRequestCommentEvent(commentEvent(tt.command, "merged"), nil, logrus.NewEntry(logrus.StandardLogger()))
+
+			want := 1
+			if tt.board {
+				want = 2
+			}
+			if len(cli.PRComments) != want {
+				t.Fatalf("got %d comments, want %d with the result of /sync first", len(cli.PRComments), want)
+			}
+			if result := cli.PRComments[0].Body; !strings.Contains(result, tt.status) {
+				t.Errorf("result comment %q doesn't hold %q", result, tt.status)
+			}
+			if tt.created == "" {
+				if len(cli.CreatedPRs) != 0 {
+					t.Errorf("created pull requests %v, want none", cli.CreatedPRs)
+				}
+				return
+			}
+			if len(cli.CreatedPRs) != 1 {
+				t.Fatalf("created %d pull requests, want 1", len(cli.CreatedPRs))
+			}
+			pr := cli.CreatedPRs[0]
+			head, base := utils.GetString(pr.Head), utils.GetString(pr.Base)
+			if base != tt.created || head != "sync-pr1-feature-to-"+tt.created {
+				t.Errorf("created pull request %s -> %s", head, base)
+			}
+			if title := utils.GetString(pr.Title); title != "[sync] PR-1: fix a" {
+				t.Errorf("created pull request title = %q", title)
+			}
+			// both commits of the pull request are picked
+			if got := f.show("o", "r", head, "a.txt") + f.show("o", "r", head, "c.txt"); got != "2c" {
+				t.Errorf("picked content = %q, want %q", got, "2c")
+			}
+		})
+	}
+}
+
+func TestSyncOnMerge(t *testing.T) {
+	bot, cli, _ := newSyncScenario(t)
+	cli.AddComment("o", "r", "1", "alice", "/sync stable missing")
+	cli.AddComment("o", "r", "1", "bob", "looks good")
+
+	org, repo, number, base, title, action, state := "o", "r", "1", "master", "fix a", "merge", "merged"
+	evt := &client.GenericEvent{Org: &org, Repo: &repo, Number: &number, Base: &base, Title: &title,
+		Action: &action, State: &state}
+	bot.handlePREvent(evt, nil, logrus.NewEntry(logrus.StandardLogger()))
+
+	if len(cli.CreatedPRs) != 1 || utils.GetString(cli.CreatedPRs[0].Base) != "stable" {
+		t.Fatalf("created pull requests %v, want one to stable", cli.CreatedPRs)
+	}
+	if len(cli.PRComments) != 2 || !strings.Contains(cli.PRComments[0].Body, branchNonExist) {
+		t.Errorf("result comments %v, want the missing branch reported and the status board", cli.PRComments)
+	}
+}
+
+func TestSyncOpenPullRequest(t *testing.T) {
+	bot, cli, _ := newSyncScenario(t)
+
+	bot.handlePullRequestCommentEvent(commentEvent("/sync stable missing", "opened"), nil,
+		logrus.NewEntry(logrus.StandardLogger()))
+
+	if len(cli.CreatedPRs) != 0 {
+		t.Errorf("created pull requests %v before the merge", cli.CreatedPRs)
+	}
+	if len(cli.PRComments) != 1 {
+		t.Fatalf("got %d comments, want the reply of /sync", len(cli.PRComments))
+	}
+	for _, want := range []string{branchExist, branchNonExist} {
+		if !strings.Contains(cli.PRComments[0].Body, want) {
+			t.Errorf("reply %q doesn't hold %q", cli.PRComments[0].Body, want)
+		}
+	}
+}
+
+func TestSyncDryRun(t *testing.T) {
+	for _, state := range []string{"opened", "merged"} {
+		t.Run(state, func(t *testing.T) {
+			bot, cli, f := newSyncScenario(t)
+			dir := filepath.Join(f.work, "o", "r")
+			commits := cli.Commits[fake.Key("o", "r", "1")]
+			first, last := commits[1].SHA, commits[0].SHA
+			f.git(dir, "checkout", "-b", "applied", "master")
+			f.git(dir, "cherry-pick", first, last)
+			f.git(dir, "checkout", "-b", "partly", "master")
+			f.git(dir, "cherry-pick", first)
+			f.git(dir, "push", "origin", "applied", "partly")
+			key := fake.RepoKey("o", "r")
+			cli.Branches[key] = append(cli.Branches[key], "applied", "partly")
+
+			bot.handlePullRequestCommentEvent(commentEvent("/sync -dry-run stable old applied partly missing", state), nil,
+				logrus.NewEntry(logrus.StandardLogger()))
+
+			if len(cli.CreatedPRs) != 0 {
+				t.Errorf("dry run created pull requests %v", cli.CreatedPRs)
+			}
+			if branches := f.git(filepath.Join(f.base, "o", "r.git"), "branch", "--list", "sync-*"); branches != "" {
+				t.Errorf("dry run pushed %s", branches)
+			}
+			if len(cli.PRComments) != 1 {
+				t.Fatalf("got %d comments, want the result of the dry run", len(cli.PRComments))
+			}
+			result := cli.PRComments[0].Body
+			for _, want := range []string{
+				"|stable|" + dryRunClean + "||",
+				"|old|" + dryRunConflict + "|a.txt|",
+				"|applied|" + dryRunApplied + "||",
+				"|partly|" + emptyCherry + "||",
+				"|missing|" + branchNonExist + "||",
+			} {
+				if !strings.Contains(result, want) {
+					t.Errorf("result %q doesn't hold %q", result, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSyncDryRunFailed(t *testing.T) {
+	bot, cli, _ := newSyncScenario(t)
+	cli.Fail["GetPullRequestCommits"] = true
+
+	bot.handlePullRequestCommentEvent(commentEvent("/sync -dry-run stable", "merged"), nil,
+		logrus.NewEntry(logrus.StandardLogger()))
+
+	if len(cli.PRComments) != 1 {
+		t.Fatalf("got %d comments, want the failure of the dry run", len(cli.PRComments))
+	}
+	if want := fmt.Sprintf(dryRunFailed, "list commits failed"); !strings.Contains(cli.PRComments[0].Body, want) {
+		t.Errorf("reply %q doesn't hold %q", cli.PRComments[0].Body, want)
+	}
+}
+
+func TestSyncOnMergeIgnoresDryRun(t *testing.T) {
+	bot, cli, _ := newSyncScenario(t)
+	cli.AddComment("o", "r", "1", "alice", "/sync stable")
+	cli.AddComment("o", "r", "1", "alice", "/sync -dry-run old")
+
+	org, repo, number, base, title, action, state := "o", "r", "1", "master", "fix a", "merge", "merged"
+	evt := &client.GenericEvent{Org: &org, Repo: &repo, Number: &number, Base: &base, Title: &title,
+		Action: &action, State: &state}
+	bot.handlePREvent(evt, nil, logrus.NewEntry(logrus.StandardLogger()))
+
+	if len(cli.CreatedPRs) != 1 || utils.GetString(cli.CreatedPRs[0].Base) != "stable" {
+		t.Errorf("created pull requests %v, want the one of the last /sync which is not a dry run", cli.CreatedPRs)
+	}
+}
+
+func TestSyncPullRequest(t *testing.T) {
+	bot, cli, _ := newSyncScenario(t)
+
+	if _, err := bot.SyncPullRequest("o", "r", "1", []string{"stable"}, "overwrite", false); err == nil {
+		t.Error("SyncPullRequest() with strategy overwrite succeeded")
+	}
+
+	results, err := bot.SyncPullRequest("o", "r", "1", []string{"stable", "old"}, "pick", true)
+	if err != nil {
+		t.Fatalf("SyncPullRequest() dry run error = %v", err)
+	}
+	if len(results) != 2 || results[0].Status != dryRunClean || results[1].Status != dryRunConflict || results[1].Detail != "a.txt" {
+		t.Errorf("SyncPullRequest() dry run = %+v", results)
+	}
+	if len(cli.CreatedPRs)+len(cli.PRComments) != 0 {
+		t.Fatalf("dry run created %v and commented %v", cli.CreatedPRs, cli.PRComments)
+	}
+
+	results, err = bot.SyncPullRequest("o", "r", "1", []string{"stable", "missing"}, "pick", false)
+	if err != nil {
+		t.Fatalf("SyncPullRequest() error = %v", err)
+	}
+	want := []SyncResult{
+		{Branch: "stable", Status: createdPR, PullRequest: bot.platform.PullRequestURL("o", "r", "1000")},
+		{Branch: "missing", Status: branchNonExist},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("SyncPullRequest() = %+v, want %+v", results, want)
+	}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Errorf("SyncPullRequest()[%d] = %+v, want %+v", i, results[i], want[i])
+		}
+	}
+	if len(cli.CreatedPRs) != 1 || len(cli.PRComments) != 0 {
+		t.Errorf("created %v and commented %v, want one pull request and no comment", cli.CreatedPRs, cli.PRComments)
+	}
+}
+
+func TestSyncPullRequestMerge(t *testing.T) {
+	bot, cli, _ := newSyncScenario(t)
+
+	// the source branch of the pull request is gone
+	results, err := bot.SyncPullRequest("o", "r", "1", []string{"stable"}, "merge", false)
+	if err != nil {
+		t.Fatalf("SyncPullRequest() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Status != createBranchFailed || len(cli.CreatedPRs) != 0 {
+		t.Fatalf("SyncPullRequest() = %+v and created %v, want the branch creation failed", results, cli.CreatedPRs)
+	}
+
+	cli.Branches[fake.RepoKey("o", "r")] = append(cli.Branches[fake.RepoKey("o", "r")], "feature")
+	results, err = bot.SyncPullRequest("o", "r", "1", []string{"stable"}, "merge", false)
+	if err != nil {
+		t.Fatalf("SyncPullRequest() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Status != createdPR {
+		t.Fatalf("SyncPullRequest() = %+v, want a pull request created", results)
+	}
+	if want := fake.Key("o", "r", "sync-pr1-to-stable"); len(cli.CreatedBranches) != 1 || cli.CreatedBranches[0] != want {
+		t.Errorf("created branches %v, want %s", cli.CreatedBranches, want)
+	}
+	if len(cli.CreatedPRs) != 1 || utils.GetString(cli.CreatedPRs[0].Head) != "sync-pr1-to-stable" {
+		t.Errorf("created %v, want a pull request from sync-pr1-to-stable", cli.CreatedPRs)
+	}
+}
+
+func TestSyncReusesSyncPullRequest(t *testing.T) {
+	bot, cli, f := newSyncScenario(t)
+	logger := logrus.NewEntry(logrus.StandardLogger())
+	syncPR := bot.platform.PullRequestURL("o", "r", "1000")
+
+	bot.handlePullRequestCommentEvent(commentEvent("/sync stable", "merged"), nil, logger)
+	bot.handlePullRequestCommentEvent(commentEvent("/sync stable", "merged"), nil, logger)
+
+	if len(cli.CreatedPRs) != 1 {
+		t.Fatalf("created %d pull requests, want the one of the first /sync only", len(cli.CreatedPRs))
+	}
+	// the status board follows the result of the first /sync
+	if result := cli.PRComments[2].Body; !strings.Contains(result, "|stable|"+syncPRExists+"|"+syncPR+"|") {
+		t.Errorf("result %q, want the existing sync pull request linked", result)
+	}
+
+	// the pull request brings one more commit
+	dir := filepath.Join(f.work, "o", "r")
+	fix := f.commit(dir, "fix c", map[string]string{"c.txt": "c2\n"})
+	f.pullRequest(dir, "1")
+	key := fake.Key("o", "r", "1")
+	cli.Commits[key] = append([]client.PRCommit{commitOf(fix, "fix c")}, cli.Commits[key]...)
+
+	bot.handlePullRequestCommentEvent(commentEvent("/sync stable", "merged"), nil, logger)
+
+	if len(cli.CreatedPRs) != 1 {
+		t.Errorf("created %d pull requests, want the sync pull request updated in place", len(cli.CreatedPRs))
+	}
+	if result := cli.PRComments[3].Body; !strings.Contains(result, "|stable|"+syncUpdated+"|"+syncPR+"|") {
+		t.Errorf("result %q, want the sync pull request updated", result)
+	}
+	if got := f.show("o", "r", "sync-pr1-feature-to-stable", "c.txt"); got != "c2" {
+		t.Errorf("sync branch c.txt = %q, want %q", got, "c2")
+	}
+
+	// the branch of a closed sync pull request is left, the platform tells it isn't open
+	cli.SetPullRequestState("o", "r", "1000", "closed")
+	bot.handlePullRequestCommentEvent(commentEvent("/sync stable", "merged"), nil, logger)
+
+	if len(cli.CreatedPRs) != 2 {
+		t.Errorf("created %d pull requests, want a new one for the closed sync pull request", len(cli.CreatedPRs))
+	}
+}
+
+func TestSyncKeepsAuthorship(t *testing.T) {
+	bot, cli, f := newSyncScenario(t)
+	bot.GitClient.SetCommitter("Release Bot", "release@example.com")
+
+	bot.handlePullRequestCommentEvent(commentEvent("/sync stable", "merged"), nil, logrus.NewEntry(logrus.StandardLogger()))
+
+	if len(cli.CreatedPRs) != 1 {
+		t.Fatalf("created %v, want the sync pull request to stable", cli.CreatedPRs)
+	}
+	bare := filepath.Join(f.base, "o", "r.git")
+	shas := strings.Fields(f.git(bare, "rev-list", "stable..sync-pr1-feature-to-stable"))
+	originals := cli.Commits[fake.Key("o", "r", "1")]
+	if len(shas) != len(originals) {
+		t.Fatalf("synced %d commits, want %d", len(shas), len(originals))
+	}
+	for i, sha := range shas {
+		if got := f.git(bare, "log", "-1", "--format=%an <%ae>|%cn <%ce>", sha); got != "alice <alice@example.com>|Release Bot <release@example.com>" {
+			t.Errorf("author|committer of %s = %q, want the original author and the configured committer", sha, got)
+		}
+		message := f.git(bare, "log", "-1", "--format=%B", sha)
+		want := "(cherry picked from commit " + originals[i].SHA + ")\nSynced-from: o/r!1"
+		if !strings.HasSuffix(message, want) {
+			t.Errorf("message of %s = %q, want it to end with %q", sha, message, want)
+		}
+	}
+}
+
+func TestSyncSquash(t *testing.T) {
+	bot, cli, f := newSyncScenario(t)
+	cli.Authors[fake.Key("o", "r", "1")] = "bob"
+
+	bot.handlePullRequestCommentEvent(commentEvent("/sync -squash stable", "merged"), nil, logrus.NewEntry(logrus.StandardLogger()))
+
+	if len(cli.CreatedPRs) != 1 {
+		t.Fatalf("created %v, want the sync pull request to stable", cli.CreatedPRs)
+	}
+	bare := filepath.Join(f.base, "o", "r.git")
+	if n := f.git(bare, "rev-list", "--count", "stable..sync-pr1-feature-to-stable"); n != "1" {
+		t.Fatalf("synced %s commits, want one", n)
+	}
+	if got := f.show("o", "r", "sync-pr1-feature-to-stable", "a.txt") +
+		f.show("o", "r", "sync-pr1-feature-to-stable", "c.txt"); got != "2c" {
+		t.Errorf("squashed content = %q, want %q", got, "2c")
+	}
+	originals := cli.Commits[fake.Key("o", "r", "1")]
+	want := "fix a\n\nSquashed from o/r!1 of @bob.\n\n" +
+		"(cherry picked from commit " + originals[1].SHA + ")\n" +
+		"(cherry picked from commit " + originals[0].SHA + ")\n" +
+		"Synced-from: o/r!1"
+	if message := f.git(bare, "log", "-1", "--format=%B", "sync-pr1-feature-to-stable"); message != want {
+		t.Errorf("message = %q, want %q", message, want)
+	}
+	if body := utils.GetString(cli.CreatedPRs[0].Body); !strings.Contains(body, originals[0].SHA[:8]) ||
+		!strings.Contains(body, originals[1].SHA[:8]) {
+		t.Errorf("body %q doesn't list the original commits", body)
+	}
+}
+
+func TestSyncSubset(t *testing.T) {
+	tests := []struct {
+		name string
+		// command is the /sync command given the first and last commits of the
+		// pull request.
+		command func(first, last string) string
+		status  func(first, last string) string
+		// created is the branch of the sync pull request expected.
+		created string
+		// files are the contents expected on the sync branch.
+		files map[string]string
+		// picked is the number of commits expected on the sync branch.
+		picked string
+	}{
+		{
+			name:    "commits",
+			command: func(first, last string) string { return "/sync -commits=" + last[:8] + " stable" },
+			created: "stable",
+			files:   map[string]string{"a.txt": "1", "c.txt": "c"},
+			picked:  "1",
+		},
+		{
+			name:    "paths leaving conflicts out",
+			command: func(first, last string) string { return "/sync -paths=c.* old" },
+			created: "old",
+			files:   map[string]string{"a.txt": "x", "c.txt": "c"},
+			picked:  "1",
+		},
+		{
+			name:    "paths matching nothing",
+			command: func(first, last string) string { return "/sync -paths=*.md stable" },
+			status:  func(first, last string) string { return emptyCherry },
+		},
+		{
+			name:    "unknown commit",
+			command: func(first, last string) string { return "/sync -commits=deadbeef stable" },
+			status:  func(first, last string) string { return fmt.Sprintf(commitNotFound, "deadbeef") },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bot, cli, f := newSyncScenario(t)
+			commits := cli.Commits[fake.Key("o", "r", "1")]
+			first, last := commits[1].SHA, commits[0].SHA
+
+			bot.handlePullRequestCommentEvent(commentEvent(tt.command(first, last), "merged"), nil,
+				logrus.NewEntry(logrus.StandardLogger()))
+
+			if len(cli.PRComments) == 0 {
+				t.Fatal("got no result comment")
+			}
+			if tt.created == "" {
+				if result := cli.PRComments[0].Body; !strings.Contains(result, tt.status(first, last)) {
+					t.Errorf("result comment %q doesn't hold %q", result, tt.status(first, last))
+				}
+				if len(cli.CreatedPRs) != 0 {
+					t.Errorf("created pull requests %v, want none", cli.CreatedPRs)
+				}
+				return
+			}
+			if len(cli.CreatedPRs) != 1 {
+				t.Fatalf("created %v, want the sync pull request to %s", cli.CreatedPRs, tt.created)
+			}
+			head := "sync-pr1-feature-to-" + tt.created
+			bare := filepath.Join(f.base, "o", "r.git")
+			if n := f.git(bare, "rev-list", "--count", tt.created+".."+head); n != tt.picked {
+				t.Errorf("synced %s commits, want %s", n, tt.picked)
+			}
+			for name, want := range tt.files {
+				if got := f.show("o", "r", head, name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+			if message := f.git(bare, "log", "-1", "--format=%an%n%B", head); !strings.Contains(message,
+				"alice\nadd c\n\n(cherry picked from commit "+last+")") {
+				t.Errorf("message = %q, want the author and message of %s kept", message, last)
+			}
+		})
+	}
+}
+
+func TestSyncMergeMethods(t *testing.T) {
+	tests := []struct {
+		name string
+		// merge merges the pull request into master in the working copy dir, it
+		// returns the commit expected to be picked last.
+		merge func(f *gitFixture, cli *fake.Client, dir string) string
+		// picked is the number of commits expected on the sync branch.
+		picked string
+	}{
+		{
+			name: "merge commit",
+			merge: func(f *gitFixture, cli *fake.Client, dir string) string {
+				f.git(dir, "checkout", "master")
+				f.git(dir, "merge", "--no-ff", "-m", "Merge pull request !1", "feature")
+				return f.git(dir, "rev-parse", "HEAD")
+			},
+			picked: "1",
+		},
+		{
+			name: "merge commit of a non-linear pull request",
+			merge: func(f *gitFixture, cli *fake.Client, dir string) string {
+				f.git(dir, "checkout", "master")
+				f.commit(dir, "add d", map[string]string{"d.txt": "d\n"})
+				f.git(dir, "checkout", "feature")
+				f.git(dir, "merge", "--no-ff", "-m", "Merge master", "master")
+				merge := f.git(dir, "rev-parse", "HEAD")
+				last := f.commit(dir, "add e", map[string]string{"e.txt": "e\n"})
+				f.pullRequest(dir, "1")
+				key := fake.Key("o", "r", "1")
+				cli.Commits[key] = append([]client.PRCommit{commitOf(last, "add e"), commitOf(merge, "Merge master")},
+					cli.Commits[key]...)
+				f.git(dir, "checkout", "master")
+				f.git(dir, "merge", "--no-ff", "-m", "Merge pull request !1", "feature")
+				return f.git(dir, "rev-parse", "HEAD")
+			},
+			picked: "1",
+		},
+		{
+			name: "squash",
+			merge: func(f *gitFixture, cli *fake.Client, dir string) string {
+				f.git(dir, "checkout", "master")
+				f.commit(dir, "add d", map[string]string{"d.txt": "d\n"})
+				f.git(dir, "merge", "--squash", "feature")
+				f.git(dir, "commit", "-m", "fix a (!1)")
+				return f.git(dir, "rev-parse", "HEAD")
+			},
+			picked: "1",
+		},
+		{
+			name: "rebase",
+			merge: func(f *gitFixture, cli *fake.Client, dir string) string {
+				f.git(dir, "checkout", "master")
+				f.commit(dir, "add d", map[string]string{"d.txt": "d\n"})
+				f.git(dir, "checkout", "-b", "rebased", "feature")
+				f.git(dir, "rebase", "master")
+				f.git(dir, "checkout", "master")
+				f.git(dir, "merge", "--ff-only", "rebased")
+				return f.git(dir, "rev-parse", "HEAD")
+			},
+			picked: "2",
+		},
+		{
+			name: "fast-forward",
+			merge: func(f *gitFixture, cli *fake.Client, dir string) string {
+				f.git(dir, "checkout", "master")
+				f.git(dir, "merge", "--ff-only", "feature")
+				return f.git(dir, "rev-parse", "HEAD")
+			},
+			picked: "2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bot, cli, f := newSyncScenario(t)
+			dir := filepath.Join(f.work, "o", "r")
+			last := tt.merge(f, cli, dir)
+			f.git(dir, "push", "origin", "master")
+
+			bot.handlePullRequestCommentEvent(commentEvent("/sync stable", "merged"), nil,
+				logrus.NewEntry(logrus.StandardLogger()))
+
+			if len(cli.CreatedPRs) != 1 {
+				t.Fatalf("created %v, want the sync pull request to stable, comments %v", cli.CreatedPRs, cli.PRComments)
+			}
+			head := "sync-pr1-feature-to-stable"
+			bare := filepath.Join(f.base, "o", "r.git")
+			if n := f.git(bare, "rev-list", "--count", "stable.."+head); n != tt.picked {
+				t.Errorf("synced %s commits, want %s", n, tt.picked)
+			}
+			if got := f.show("o", "r", head, "a.txt") + f.show("o", "r", head, "c.txt"); got != "2c" {
+				t.Errorf("picked content = %q, want %q", got, "2c")
+			}
+			// the changes of master merged into the pull request are not picked
+			if files := f.git(bare, "ls-tree", "--name-only", head); strings.Contains(files, "d.txt") {
+				t.Errorf("picked files %q, want d.txt left out", files)
+			}
+			if message := f.git(bare, "log", "-1", "--format=%B", head); !strings.Contains(message,
+				"(cherry picked from commit "+last+")") {
+				t.Errorf("message = %q, want %s picked", message, last)
+			}
+		})
+	}
+}
+
+func TestSyncAgainAfterSyncPRMerged(t *testing.T) {
+	bot, cli, f := newSyncScenario(t)
+	logger := logrus.NewEntry(logrus.StandardLogger())
+	bot.handlePullRequestCommentEvent(commentEvent("/sync stable", "merged"), nil, logger)
+	if len(cli.CreatedPRs) != 1 {
+		t.Fatalf("created %v, want the sync pull request to stable", cli.CreatedPRs)
+	}
+	syncPR := cli.CreatedPRs[0]
+	syncNumber := "1000"
+	cli.Commits[fake.Key("o", "r", syncNumber)] = f.mergePullRequest("o", "r", syncNumber, utils.GetString(syncPR.Head), "stable")
+	cli.SetPullRequestState("o", "r", syncNumber, "merged")
+
+	evt := mergeEvent(syncNumber, utils.GetString(syncPR.Title), "stable")
+	evt.Head = syncPR.Head
+	bot.handlePREvent(evt, nil, logger)
+
+	bare := filepath.Join(f.base, "o", "r.git")
+	if branches := f.git(bare, "branch", "--list", "sync-*"); branches != "" {
+		t.Fatalf("branches %q are left after the sync pull request merged", branches)
+	}
+	// the change is on stable already, a new sync pull request is not reported as updated
+	bot.handlePullRequestCommentEvent(commentEvent("/sync stable", "merged"), nil, logger)
+	for _, c := range cli.PRComments {
+		if strings.Contains(c.Body, syncUpdated) || strings.Contains(c.Body, syncPRExists) {
+			t.Errorf("comment %q treats the merged sync pull request as open", c.Body)
+		}
+	}
+}
@@ -0,0 +1,520 @@
+package hook
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sync-bot/git"
+	"sync-bot/util"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// syncRecordRegex matches the marker of the sync pull requests in the result of /sync.
+	syncRecordRegex = regexp.MustCompile(`<!-- sync-prs:([^>]*) -->`)
+	// syncFollowUpRegex matches the marker of the pull request a follow-up was offered to.
+	syncFollowUpRegex = regexp.MustCompile(`<!-- sync-follow-up: (\d+) -->`)
+	// syncHeadRegex matches the source branch of a sync pull request created by pick.
+	syncHeadRegex = regexp.MustCompile(`^sync-pr(\d+)-.+-to-.+$`)
+)
+
+// syncRecord is a sync pull request created for a pull request.
+type syncRecord struct {
+	Branch string
+	// Head is the source branch of the sync pull request.
+	Head string
+	PR   string
+}
+
+// parseSyncRecords returns the sync pull requests marked in the result of /sync.
+func parseSyncRecords(body string) []syncRecord {
+	match := syncRecordRegex.FindStringSubmatch(body)
+	if match == nil {
+		return nil
+	}
+	var records []syncRecord
+	for _, field := range strings.Fields(match[1]) {
+		if strings.HasPrefix(field, "-") {
+			// an option of the sync, see parseSyncOptions
+			continue
+		}
+		// branch names never hold a colon, the url of the pull request may
+		parts := strings.SplitN(field, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		records = append(records, syncRecord{Branch: parts[0], Head: parts[1], PR: parts[2]})
+	}
+	return records
+}
+
+// parseSyncOptions returns the options of the sync marked with the sync pull
+// requests in the result of /sync, see SyncCmdOption.recordOptions.
+func parseSyncOptions(body string) *SyncCmdOption {
+	opt := &SyncCmdOption{strategy: Pick}
+	match := syncRecordRegex.FindStringSubmatch(body)
+	if match == nil {
+		return opt
+	}
+	for _, field := range strings.Fields(match[1]) {
+		name, value, _ := strings.Cut(field, "=")
+		switch name {
+		case "-squash":
+			opt.squash = true
+		case "-commits":
+			opt.commits = util.SplitList(value)
+		case "-paths":
+			opt.paths = util.SplitList(value)
+		}
+	}
+	return opt
+}
+
+// syncResult returns the result of the latest /sync which created sync pull
+// requests for the pull request, empty if none did.
+func (bot *robot) syncResult(org, repo, number string) (string, error) {
+	comments, ok := bot.cli.ListPullRequestComments(org, repo, number)
+	if !ok {
+		return "", errors.New("list pull request comments failed")
+	}
+	for _, comment := range comments {
+		if records := parseSyncRecords(comment.Body); len(records) > 0 {
+			return comment.Body, nil
+		}
+	}
+	return "", nil
+}
+
+// syncRecords returns the sync pull requests created for the pull request by
+// the latest /sync which created any.
+func (bot *robot) syncRecords(org, repo, number string) ([]syncRecord, error) {
+	result, err := bot.syncResult(org, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	return parseSyncRecords(result), nil
+}
+
+// followUpSource returns the number of the pull request whose sync pull
+// requests the latest follow-up offer on the pull request is about.
+func (bot *robot) followUpSource(org, repo, number string) (string, error) {
+	comments, ok := bot.cli.ListPullRequestComments(org, repo, number)
+	if !ok {
+		return "", errors.New("list pull request comments failed")
+	}
+	for _, comment := range comments {
+		if match := syncFollowUpRegex.FindStringSubmatch(comment.Body); match != nil {
+			return match[1], nil
+		}
+	}
+	return "", nil
+}
+
+// openSyncHeads returns the source branches of the sync pull requests created
+// by pick which the platform tells are still open.
+func (bot *robot) openSyncHeads(org, repo string) (map[string]bool, error) {
+	prs, ok := bot.cli.ListOpenPullRequests(org, repo)
+	if !ok {
+		return nil, errors.New("list open pull requests failed")
+	}
+	heads := make(map[string]bool, len(prs))
+	for _, pr := range prs {
+		// the head of a fork may be given as "owner:branch"
+		head := utils.GetString(pr.Head)
+		head = head[strings.LastIndex(head, ":")+1:]
+		if util.MatchSyncBranch(head) {
+			heads[head] = true
+		}
+	}
+	return heads, nil
+}
+
+// reuseSyncBranch updates the source branch of an open sync pull request with
+// the commits picked onto head, or leaves it as it is when the trees are the
+// same. The sync pull request is looked up in records, it is linked by its
+// branch otherwise.
+func (bot *robot) reuseSyncBranch(r *git.Repo, org, repo, branch, head string, records []syncRecord) syncStatus {
+	st := syncStatus{Name: branch, PR: bot.platform.TreeURL(org, repo, head), Head: head}
+	for _, rec := range records {
+		if rec.Head == head {
+			st.PR = rec.PR
+		}
+	}
+	// the branch of an open sync pull request may be gone, it is pushed again then
+	changed := !r.RemoteBranchExists(head)
+	if !changed {
+		var err error
+		if changed, err = r.HasDiff("origin/"+head, head); err != nil {
+			st.Status = err.Error()
+			return st
+		}
+	}
+	if !changed {
+		st.Status = syncPRExists
+		return st
+	}
+	if err := r.Push(head, true); err != nil {
+		st.Status = err.Error()
+		return st
+	}
+	st.Status = syncUpdated
+	return st
+}
+
+// offerSyncUpdate tells on a pull request whose source branch was updated
+// which of its sync pull requests still carry the former changes, unless it
+// was told already since the last update.
+func (bot *robot) offerSyncUpdate(evt *client.GenericEvent, logger *logrus.Entry) {
+	org := utils.GetString(evt.Org)
+	repo := utils.GetString(evt.Repo)
+	number := utils.GetString(evt.Number)
+
+	records, err := bot.syncRecords(org, repo, number)
+	if err != nil {
+		logger.WithError(err).Errorln("Find sync pull requests failed")
+		return
+	}
+	if len(records) == 0 {
+		logger.Infoln("Ignoring unhandled action:", evt.Action)
+		return
+	}
+	heads, err := bot.openSyncHeads(org, repo)
+	if err != nil {
+		logger.WithError(err).Errorln("List open sync pull requests failed")
+		return
+	}
+	var open []syncRecord
+	for _, rec := range records {
+		if heads[rec.Head] {
+			open = append(open, rec)
+		}
+	}
+	if len(open) == 0 {
+		logger.Infoln("Sync pull requests are merged or closed, nothing to update.")
+		return
+	}
+
+	comment, err := executeTemplate(syncUpdateOfferTmpl, open)
+	if err != nil {
+		logger.Errorln("Execute template failed:", err)
+		return
+	}
+	if comments, ok := bot.cli.ListPullRequestComments(org, repo, number); ok && len(comments) > 0 &&
+		comments[0].Body == comment {
+		logger.Infoln("Sync update offered already.")
+		return
+	}
+	if !bot.cli.CreatePRComment(org, repo, number, comment) {
+		logger.Errorln("Create comment failed")
+		return
+	}
+	logger.Infoln("Offer sync update.")
+}
+
+// offerSyncFollowUp looks, once a pull request is merged, for the open sync
+// pull requests of other pull requests to the same branch changing the same
+// files, and offers to bring the changes of the merged one into them.
+func (bot *robot) offerSyncFollowUp(evt *client.GenericEvent, logger *logrus.Entry) {
+	org := utils.GetString(evt.Org)
+	repo := utils.GetString(evt.Repo)
+	number := utils.GetString(evt.Number)
+	base := utils.GetString(evt.Base)
+
+	heads, err := bot.openSyncHeads(org, repo)
+	if err != nil {
+		logger.WithError(err).Errorln("List open sync pull requests failed")
+		return
+	}
+	sourceSet := make(map[string]bool)
+	for head := range heads {
+		if match := syncHeadRegex.FindStringSubmatch(head); match != nil && match[1] != number {
+			sourceSet[match[1]] = true
+		}
+	}
+	if len(sourceSet) == 0 {
+		return
+	}
+	sources := make([]string, 0, len(sourceSet))
+	for s := range sourceSet {
+		sources = append(sources, s)
+	}
+	sort.Strings(sources)
+
+	changes, ok := bot.cli.GetPullRequestChanges(org, repo, number)
+	if !ok {
+		logger.Errorln("List changed files failed")
+		return
+	}
+	changed := make(map[string]bool, len(changes))
+	for _, c := range changes {
+		changed[c.Filename] = true
+	}
+
+	for _, source := range sources {
+		pr, ok := bot.cli.GetPullRequest(org, repo, source)
+		if !ok || utils.GetString(pr.Base) != base {
+			continue
+		}
+		records, err := bot.syncRecords(org, repo, source)
+		if err != nil {
+			logger.WithError(err).Errorf("Find sync pull requests of %s failed", source)
+			continue
+		}
+		var open []syncRecord
+		for _, rec := range records {
+			if heads[rec.Head] {
+				open = append(open, rec)
+			}
+		}
+		if len(open) == 0 {
+			continue
+		}
+		sourceChanges, ok := bot.cli.GetPullRequestChanges(org, repo, source)
+		if !ok {
+			logger.Errorf("List changed files of %s failed", source)
+			continue
+		}
+		var files []string
+		for _, c := range sourceChanges {
+			if changed[c.Filename] {
+				files = append(files, c.Filename)
+			}
+		}
+		if len(files) == 0 {
+			continue
+		}
+
+		comment, err := executeTemplate(syncFollowUpTmpl, struct {
+			Source  string
+			Number  string
+			Files   []string
+			Records []syncRecord
+		}{
+			Source:  utils.GetString(pr.URL),
+			Number:  source,
+			Files:   files,
+			Records: open,
+		})
+		if err != nil {
+			logger.Errorln("Execute template failed:", err)
+			return
+		}
+		if !bot.cli.CreatePRComment(org, repo, number, comment) {
+			logger.Errorln("Create comment failed")
+			return
+		}
+		logger.Infof("Offer sync follow-up to the sync pull requests of %s.", source)
+		// a single offer at a time, /sync-update follows the latest one
+		return
+	}
+}
+
+// syncUpdate handles /sync-update. The commits of a pull request with sync pull
+// requests are picked again onto their target branches and force pushed; the
+// commits of a follow-up pull request are picked on top of the sync pull
+// requests it was offered to. The result is replied as the one of /sync.
+func (bot *robot) syncUpdate(evt *client.GenericEvent, logger *logrus.Entry) error {
+	org := utils.GetString(evt.Org)
+	repo := utils.GetString(evt.Repo)
+	number := utils.GetString(evt.Number)
+	command := utils.GetString(evt.Comment)
+	user := utils.GetString(evt.Commenter)
+
+	result, err := bot.syncResult(org, repo, number)
+	if err != nil {
+		logger.WithError(err).Errorln("Find sync pull requests failed")
+		return err
+	}
+	followUp := false
+	if result == "" {
+		source, err := bot.followUpSource(org, repo, number)
+		if err != nil {
+			logger.WithError(err).Errorln("Find follow-up offer failed")
+			return err
+		}
+		if source != "" {
+			if result, err = bot.syncResult(org, repo, source); err != nil {
+				logger.WithError(err).Errorf("Find sync pull requests of %s failed", source)
+				return err
+			}
+			followUp = true
+		}
+	}
+	records, opt := parseSyncRecords(result), parseSyncOptions(result)
+
+	var comment string
+	if len(records) == 0 {
+		comment, err = executeTemplate(replyCloseTmpl, struct {
+			URL     string
+			Command string
+			User    string
+			Status  string
+		}{
+			URL:     utils.GetString(evt.HtmlURL),
+			Command: strings.TrimSpace(command),
+			User:    user,
+			Status:  noSyncRecord,
+		})
+	} else {
+		var status []syncStatus
+		status, err = bot.updateSyncPullRequests(org, repo, number, records, opt, followUp, logger)
+		if err != nil {
+			return err
+		}
+		options := ""
+		if !followUp {
+			options = opt.recordOptions()
+		}
+		comment, err = executeTemplate(syncResultTmpl, struct {
+			URL        string
+			User       string
+			Command    string
+			SyncStatus []syncStatus
+			Options    string
+			Mentions   []string
+		}{
+			URL:        utils.GetString(evt.HtmlURL),
+			User:       user,
+			Command:    strings.TrimSpace(command),
+			SyncStatus: status,
+			Options:    options,
+			Mentions:   bot.failureMentions(org, repo, status, logger),
+		})
+		if !followUp {
+			defer bot.boardSync(org, repo, number, status, logger)
+		}
+	}
+	if err != nil {
+		logger.Errorln("Execute template failed:", err)
+		return err
+	}
+	if !bot.cli.CreatePRComment(org, repo, number, comment) {
+		logger.Errorln("Create comment failed")
+		return errors.New("create comment failed")
+	}
+	logger.Infoln("Reply sync update.")
+	return nil
+}
+
+// updateSyncPullRequests picks the commits of the pull request onto the source
+// branches of the sync pull requests of records. They are rebuilt from their
+// target branches with the options of the sync which created them and force
+// pushed unless followUp, then the commits are added on top of them, keeping
+// the changes to the files of the paths of opt only.
+func (bot *robot) updateSyncPullRequests(org, repo, number string, records []syncRecord, opt *SyncCmdOption,
+	followUp bool, logger *logrus.Entry) ([]syncStatus, error) {
+	prNumber, err := strconv.Atoi(number)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pull request number: %s", number)
+	}
+	commits, ok := bot.cli.GetPullRequestCommits(org, repo, number)
+	if !ok {
+		logger.Errorln("List commits failed")
+		return nil, errors.New("list commits failed")
+	}
+	if len(commits) == 0 {
+		logger.Errorln("Pull request has no commits")
+		return nil, errors.New("pull request has no commits")
+	}
+	firstSha := commits[len(commits)-1].SHA
+	lastSha := commits[0].SHA
+	pick := func(r *git.Repo) error { return r.CherryPick(firstSha, lastSha, git.Theirs) }
+	partial := false
+	switch {
+	case followUp:
+		if len(opt.paths) > 0 {
+			shas := commitSHAs(commits)
+			pick = func(r *git.Repo) error { return r.CherryPickPaths(shas, opt.paths) }
+			partial = true
+		}
+	case opt.squash:
+		pr, ok := bot.cli.GetPullRequest(org, repo, number)
+		if !ok {
+			return nil, errors.New("get pull request failed")
+		}
+		message := bot.squashMessage(org, repo, pr)
+		pick = func(r *git.Repo) error { return r.CherryPickSquash(firstSha, lastSha, message) }
+	case opt.partial():
+		selected, unknown := selectCommits(commits, opt.commits)
+		if unknown != "" {
+			var status []syncStatus
+			for _, rec := range records {
+				status = append(status, syncStatus{Name: rec.Branch, Status: fmt.Sprintf(commitNotFound, unknown),
+					PR: rec.PR, Head: rec.Head})
+			}
+			return status, nil
+		}
+		shas := commitSHAs(selected)
+		pick = func(r *git.Repo) error { return r.CherryPickPaths(shas, opt.paths) }
+		partial = true
+	}
+
+	r, err := bot.GitClient.Clone(org, repo)
+	if err != nil {
+		logger.Errorf("Clone %s/%s failed: %v", org, repo, err)
+		return nil, err
+	}
+	heads, err := bot.openSyncHeads(org, repo)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.FetchPullRequest(prNumber); err != nil {
+		logger.Errorf("Fetch pull request failed: %v", err)
+		return nil, err
+	}
+
+	var status []syncStatus
+	for _, rec := range records {
+		if !heads[rec.Head] {
+			status = append(status, syncStatus{Name: rec.Branch, Status: syncPRClosed, PR: rec.PR})
+			continue
+		}
+		st := syncStatus{Name: rec.Branch, PR: rec.PR}
+		if !followUp {
+			// the result stays the record of the sync pull requests
+			st.Head = rec.Head
+		}
+		st.Status = updateSyncBranch(r, rec, followUp, partial, pick, syncedFrom(org, repo, number))
+		status = append(status, st)
+	}
+	return status, nil
+}
+
+// updateSyncBranch picks the commits onto the source branch of the sync pull
+// request with pick, adds the trailer and pushes it, it returns the status of
+// the update. A partial pick changing nothing is not pushed.
+func updateSyncBranch(r *git.Repo, rec syncRecord, followUp, partial bool, pick func(r *git.Repo) error,
+	trailer string) string {
+	onto := "origin/" + rec.Branch
+	if followUp {
+		onto = "origin/" + rec.Head
+	}
+	_ = r.Clean()
+	if err := r.Checkout(onto); err != nil {
+		return err.Error()
+	}
+	if err := r.CheckoutNewBranch(rec.Head, true); err != nil {
+		return err.Error()
+	}
+	if err := pick(r); err != nil {
+		logrus.Errorln("Cherry pick failed:", err.Error())
+		return syncFailed
+	}
+	if changed, err := r.HasDiff(onto, "HEAD"); partial && err == nil && !changed {
+		return emptyCherry
+	}
+	if err := r.AddTrailer(onto, trailer); err != nil {
+		return err.Error()
+	}
+	if err := r.Push(rec.Head, !followUp); err != nil {
+		return err.Error()
+	}
+	return syncUpdated
+}
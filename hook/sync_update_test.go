@@ -0,0 +1,204 @@
+package hook
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sync-bot/fake"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSyncUpdate(t *testing.T) {
+	bot, cli, f := newSyncScenario(t)
+	logger := logrus.NewEntry(logrus.StandardLogger())
+	bot.handlePullRequestCommentEvent(commentEvent("/sync stable missing", "merged"), nil, logger)
+	if len(cli.CreatedPRs) != 1 {
+		t.Fatalf("created %v, want the sync pull request to stable", cli.CreatedPRs)
+	}
+	syncPR := bot.platform.PullRequestURL("o", "r", "1000")
+
+	// the source branch gets a fix-up
+	dir := filepath.Join(f.work, "o", "r")
+	fix := f.commit(dir, "fix c", map[string]string{"c.txt": "c2\n"})
+	f.pullRequest(dir, "1")
+	key := fake.Key("o", "r", "1")
+	cli.Commits[key] = append([]client.PRCommit{commitOf(fix, "fix c")}, cli.Commits[key]...)
+
+	org, repo, number, base, title, action := "o", "r", "1", "master", "fix a", "update"
+	update := &client.GenericEvent{Org: &org, Repo: &repo, Number: &number, Base: &base, Title: &title, Action: &action}
+	bot.handlePREvent(update, nil, logger)
+	bot.handlePREvent(update, nil, logger)
+
+	if len(cli.PRComments) != 3 {
+		t.Fatalf("got %d comments, want the result of /sync, the status board and a single offer", len(cli.PRComments))
+	}
+	if offer := cli.PRComments[2].Body; !strings.Contains(offer, "|stable|"+syncPR+"|") ||
+		!strings.Contains(offer, "/sync-update") {
+		t.Errorf("offer %q doesn't list the sync pull request to stable", offer)
+	}
+
+	bot.handlePullRequestCommentEvent(commentEvent("/sync-update", "merged"), nil, logger)
+
+	if len(cli.CreatedPRs) != 1 {
+		t.Errorf("created %v, want the sync pull request updated in place", cli.CreatedPRs)
+	}
+	result := cli.PRComments[len(cli.PRComments)-1].Body
+	for _, want := range []string{
+		"|stable|" + syncUpdated + "|" + syncPR + "|",
+		"<!-- sync-prs: stable:sync-pr1-feature-to-stable:" + syncPR + " -->",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("result %q doesn't hold %q", result, want)
+		}
+	}
+	if got := f.show("o", "r", "sync-pr1-feature-to-stable", "a.txt") +
+		f.show("o", "r", "sync-pr1-feature-to-stable", "c.txt"); got != "2c2" {
+		t.Errorf("updated content = %q, want %q", got, "2c2")
+	}
+	if n := f.git(filepath.Join(f.base, "o", "r.git"), "rev-list", "--count", "stable..sync-pr1-feature-to-stable"); n != "3" {
+		t.Errorf("sync branch holds %s commits, want the 3 of the pull request", n)
+	}
+}
+
+func TestSyncFollowUp(t *testing.T) {
+	bot, cli, f := newSyncScenario(t)
+	logger := logrus.NewEntry(logrus.StandardLogger())
+	cli.Changes[fake.Key("o", "r", "1")] = []client.CommitFile{{Filename: "a.txt"}, {Filename: "c.txt"}}
+	bot.handlePullRequestCommentEvent(commentEvent("/sync stable", "merged"), nil, logger)
+
+	// pull request 2 fixes what pull request 1 brought into master
+	dir := filepath.Join(f.work, "o", "r")
+	f.git(dir, "checkout", "-b", "fixup")
+	fix := f.commit(dir, "fix c", map[string]string{"c.txt": "c2\n"})
+	f.pullRequest(dir, "2")
+	number, title, head, base, url := "2", "fix c", "fixup", "master", "https://fake/o/r/pulls/2"
+	cli.AddPullRequest("o", "r", client.PullRequest{Number: &number, Title: &title, Head: &head, Base: &base, URL: &url},
+		commitOf(fix, "fix c"))
+	cli.Changes[fake.Key("o", "r", "2")] = []client.CommitFile{{Filename: "c.txt"}}
+
+	bot.handlePREvent(mergeEvent("2", "fix c", "master"), nil, logger)
+
+	offers := cli.Comments[fake.Key("o", "r", "2")]
+	if len(offers) != 1 || !strings.Contains(offers[0].Body, "<!-- sync-follow-up: 1 -->") ||
+		!strings.Contains(offers[0].Body, "c.txt") {
+		t.Fatalf("comments of pull request 2 = %v, want the follow-up offer", offers)
+	}
+
+	org, repo, comment, user, state := "o", "r", "/sync-update", "alice", "merged"
+	bot.handlePullRequestCommentEvent(&client.GenericEvent{Org: &org, Repo: &repo, Number: &number, Title: &title,
+		Comment: &comment, Commenter: &user, HtmlURL: &url, State: &state}, nil, logger)
+
+	comments := cli.Comments[fake.Key("o", "r", "2")]
+	syncPR := bot.platform.PullRequestURL("o", "r", "1000")
+	if result := comments[0].Body; !strings.Contains(result, "|stable|"+syncUpdated+"|"+syncPR+"|") ||
+		!strings.Contains(result, "<!-- sync-prs: -->") {
+		t.Errorf("result %q, want the sync pull request of 1 updated and not recorded for 2", result)
+	}
+	if got := f.show("o", "r", "sync-pr1-feature-to-stable", "a.txt") +
+		f.show("o", "r", "sync-pr1-feature-to-stable", "c.txt"); got != "2c2" {
+		t.Errorf("followed-up content = %q, want %q", got, "2c2")
+	}
+
+	// once the sync pull request is closed, nothing is offered although its branch is left
+	cli.SetPullRequestState("o", "r", "1000", "closed")
+	bot.handlePREvent(mergeEvent("2", "fix c", "master"), nil, logger)
+	if n := len(cli.Comments[fake.Key("o", "r", "2")]); n != 2 {
+		t.Errorf("got %d comments on pull request 2, want no new offer", n)
+	}
+}
+
+func TestSyncUpdateWithoutRecord(t *testing.T) {
+	bot, cli, _ := newSyncScenario(t)
+
+	bot.handlePullRequestCommentEvent(commentEvent("/sync-update", "merged"), nil, logrus.NewEntry(logrus.StandardLogger()))
+
+	if len(cli.PRComments) != 1 || !strings.Contains(cli.PRComments[0].Body, noSyncRecord) {
+		t.Errorf("comments %v, want %q replied", cli.PRComments, noSyncRecord)
+	}
+}
+
+func TestSyncUpdateKeepsOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string
+		// commits is the number of commits synced onto stable after the update
+		commits string
+		files   string
+	}{
+		{name: "squash", options: "-squash", commits: "1", files: "a.txt:2 b.txt:b c.txt:c2 d.txt:d"},
+		{name: "paths", options: "-paths=a.txt,d.txt", commits: "2", files: "a.txt:2 b.txt:b d.txt:d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bot, cli, f := newSyncScenario(t)
+			logger := logrus.NewEntry(logrus.StandardLogger())
+			command := "/sync " + strings.Replace(tt.options, "=", " ", 1) + " stable"
+			bot.handlePullRequestCommentEvent(commentEvent(command, "merged"), nil, logger)
+			if len(cli.CreatedPRs) != 1 {
+				t.Fatalf("created %v, want the sync pull request to stable", cli.CreatedPRs)
+			}
+
+			dir := filepath.Join(f.work, "o", "r")
+			fix := f.commit(dir, "fix c", map[string]string{"c.txt": "c2\n", "d.txt": "d\n"})
+			f.pullRequest(dir, "1")
+			key := fake.Key("o", "r", "1")
+			cli.Commits[key] = append([]client.PRCommit{commitOf(fix, "fix c")}, cli.Commits[key]...)
+			bot.handlePullRequestCommentEvent(commentEvent("/sync-update", "merged"), nil, logger)
+
+			head := "sync-pr1-feature-to-stable"
+			bare := filepath.Join(f.base, "o", "r.git")
+			if n := f.git(bare, "rev-list", "--count", "stable.."+head); n != tt.commits {
+				t.Errorf("synced %s commits, want %s", n, tt.commits)
+			}
+			var files []string
+			for _, name := range strings.Fields(f.git(bare, "ls-tree", "--name-only", head)) {
+				files = append(files, name+":"+f.show("o", "r", head, name))
+			}
+			if got := strings.Join(files, " "); got != tt.files {
+				t.Errorf("updated files = %q, want %q", got, tt.files)
+			}
+			// the options are kept for the next update
+			result := cli.PRComments[len(cli.PRComments)-1].Body
+			if got := parseSyncOptions(result).recordOptions(); got != tt.options {
+				t.Errorf("options of the result = %q, want %q", got, tt.options)
+			}
+		})
+	}
+}
+
+func TestParseSyncOptions(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{body: "<!-- sync-prs: stable:sync-pr1-f-to-stable:https://x/1000 -->", want: ""},
+		{body: "<!-- sync-prs: -squash stable:sync-pr1-f-to-stable:https://x/1000 -->", want: "-squash"},
+		{body: "<!-- sync-prs: -commits=abcdef1,1234567 -paths=*.patch,a/** stable:h:https://x/1000 -->",
+			want: "-commits=abcdef1,1234567 -paths=*.patch,a/**"},
+		{body: "no marker", want: ""},
+	}
+	for _, tt := range tests {
+		if got := parseSyncOptions(tt.body).recordOptions(); got != tt.want {
+			t.Errorf("parseSyncOptions(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestParseSyncRecords(t *testing.T) {
+	records := parseSyncRecords("table\n<!-- sync-prs: -paths=*.patch stable:sync-pr1-f-to-stable:https://x/1000 bad next:sync-pr1-f-to-next:https://x/1001 -->\n")
+	want := []syncRecord{
+		{Branch: "stable", Head: "sync-pr1-f-to-stable", PR: "https://x/1000"},
+		{Branch: "next", Head: "sync-pr1-f-to-next", PR: "https://x/1001"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("parseSyncRecords() = %v, want %v", records, want)
+	}
+	for i := range want {
+		if records[i] != want[i] {
+			t.Errorf("parseSyncRecords()[%d] = %v, want %v", i, records[i], want[i])
+		}
+	}
+}
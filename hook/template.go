@@ -2,6 +2,7 @@ package hook
 
 import (
 	"bytes"
+	"strings"
 	"text/template"
 )
 
@@ -17,10 +18,14 @@ const (
 评论 ` + "`/sync <branch1> <branch2> ...`" + ` 可将当前 PR 修改同步到其它分支（创建同步 PR）：
 a) 如果当前 PR 是 Open 状态，同步操作将延迟到 PR 被合并时执行
 b) 如果当前 PR 已经 Merged，将立即执行同步操作
+c) 评论 ` + "`/sync -dry-run <branch1> <branch2> ...`" + ` 可预演同步结果，不会推送分支或创建 PR
+d) 评论 ` + "`/sync -squash <branch1> <branch2> ...`" + ` 可将当前 PR 的提交合并为一个提交同步
+e) 评论 ` + "`/sync -commits=<sha1,sha2> -paths=<*.patch> <branch1> ...`" + ` 可只同步指定的提交，或只同步指定文件的修改
 
 > 注意：
 > 1. /sync 命令可以指定同步到多个分支，仅最后一个 /sync 命令生效
 > 2. 如果创建的同步 PR 不正确，可通过向同步 PR 的源分支提交轻量级 PR 完善，或使用 /close 命令关闭
+> 3. 同步 PR 创建后，当前 PR 或后续修改相同文件的 PR 可使用 /sync-update 命令更新同步 PR
 `
 
 	replySync = `
@@ -52,13 +57,41 @@ In response to [this]({{.URL}}):
 {{- range .Commits}}
 |[{{slice .SHA 0 8}}]({{.HTMLURL}})|{{.CommitTime}}|{{.Message}}|
 {{- end}}
-`
+{{- if .Paths}}
+
+Only the changes to the files matching {{range $i, $p := .Paths}}{{if $i}}, {{end}}` + "`{{$p}}`" + `{{end}} are synced.
+{{- end}}
+` + syncLabelSection + syncChainSection
 	syncKernelPRBody = `
 ### 1. Origin pull request:
 {{.PR}}
 
 ### 2. Original pull request body:
 {{.Body}}
+` + syncLabelSection + syncChainSection
+
+	// syncLabelSection lists the labels of the sync pull request.
+	syncLabelSection = `{{- if .Labels}}
+
+### Labels:
+| Label | Description |
+|---|---|
+{{- range .Labels}}
+|{{.LabelName}}|{{.Description}}|
+{{- end}}
+{{- end}}
+`
+
+	// syncChainSection links the pull requests a sync pull request descends from,
+	// the marker is read back when the sync continues along a lineage.
+	syncChainSection = `{{- if gt (len .Chain) 1}}
+
+### Sync chain:
+{{- range $i, $pr := .Chain}}
+{{add $i 1}}. {{$pr}}
+{{- end}}
+{{- end}}
+<!-- sync-chain: {{join .Chain " "}} -->
 `
 
 	syncResult = `
@@ -74,6 +107,152 @@ In response to [this]({{.URL}}):
 {{- range .SyncStatus}}
 |{{print .Name}}|{{print .Status}}|{{print .PR}}|
 {{- end}}
+{{- if .Mentions}}
+
+部分分支同步失败，请 {{range .Mentions}}@{{.}} {{end}}协助处理。
+{{- end}}
+` + syncRecordSection
+
+	// syncRecordSection records the sync pull requests created for a pull
+	// request and the options of the sync, the marker is read back by /sync-update.
+	syncRecordSection = `<!-- sync-prs:{{with .Options}} {{.}}{{end}}{{range .SyncStatus}}{{if .Head}} {{.Name}}:{{.Head}}:{{.PR}}{{end}}{{end}} -->
+`
+
+	syncUpdateOffer = `
+当前 PR 的源分支已更新，以下同步 PR 仍是更新前的修改:
+
+| Branch | Pull Request |
+|---|---|
+{{- range .}}
+|{{print .Branch}}|{{print .PR}}|
+{{- end}}
+
+评论 ` + "`/sync-update`" + ` 可将当前 PR 的提交重新同步到这些分支，并强制推送同步 PR 的源分支
+`
+
+	syncFollowUpOffer = `
+当前 PR 与 {{.Source}} 修改了相同的文件：{{join .Files ", "}}，{{.Source}} 的以下同步 PR 尚未合入:
+
+| Branch | Pull Request |
+|---|---|
+{{- range .Records}}
+|{{print .Branch}}|{{print .PR}}|
+{{- end}}
+
+评论 ` + "`/sync-update`" + ` 可将当前 PR 的提交追加到这些同步 PR
+<!-- sync-follow-up: {{.Number}} -->
+`
+
+	syncDryRunResult = `
+In response to [this]({{.URL}}):
+> {{.Command}}
+
+@{{.User}}
+
+同步预演结果（未推送分支，未创建 PR）:
+
+| Branch | Result | Detail |
+|---|---|---|
+{{- range .SyncStatus}}
+|{{print .Name}}|{{print .Status}}|{{print .Detail}}|
+{{- end}}
+`
+
+	syncBranchPRBody = `
+### 1. Source branch:
+{{.From}}
+
+### 2. Commit(s) missing from {{.To}}:
+| Sha | Message |
+|---|---|
+{{- range .Picked}}
+|{{slice .SHA 0 8}}|{{.Subject}}|
+{{- end}}
+{{- if .Skipped}}
+
+### 3. Commit(s) skipped, their changes are already on {{.To}}:
+| Sha | Message |
+|---|---|
+{{- range .Skipped}}
+|{{slice .SHA 0 8}}|{{.Subject}}|
+{{- end}}
+{{- end}}
+`
+
+	syncBranchResult = `
+In response to [this]({{.URL}}):
+> {{.Command}}
+
+@{{.User}}
+
+{{.Result.From}} 同步到 {{.Result.To}}：{{.Result.Status}} {{.Result.PullRequest}}
+{{- if .Result.Detail}}
+
+{{.Result.Detail}}
+{{- end}}
+{{- if or .Result.Picked .Result.Skipped}}
+
+| Sha | Message | Result |
+|---|---|---|
+{{- range .Result.Picked}}
+|{{slice .SHA 0 8}}|{{.Subject}}|` + commitPicked + `|
+{{- end}}
+{{- range .Result.Skipped}}
+|{{slice .SHA 0 8}}|{{.Subject}}|` + commitSkipped + `|
+{{- end}}
+{{- end}}
+`
+
+	syncChainResult = `
+当前同步 PR 已合入 {{.Branch}}，按分支链继续同步:
+
+| Branch | Status | Pull Request |
+|---|---|---|
+{{- range .SyncStatus}}
+|{{print .Name}}|{{print .Status}}|{{print .PR}}|
+{{- end}}
+`
+
+	// syncIssueLinks is edited in place on the linked issues, the marker tells
+	// which pull request it is of.
+	syncIssueLinks = `
+<!-- sync-issue-links: {{.PR}} -->
+{{.PR}} 的修改已同步到以下分支:
+
+| Branch | Pull Request | Status |
+|---|---|---|
+{{- range .Entries}}
+|{{print .Branch}}|{{print .PR}}|{{state .State}}|
+{{- end}}
+`
+
+	// statusBoard is edited in place on the original pull request as its sync
+	// pull requests go, the marker holds the entries to read back.
+	statusBoard = `
+### 同步状态
+
+| Branch | Pull Request | Status |
+|---|---|---|
+{{- range .}}
+|{{print .Branch}}|{{print .PR}}|{{state .State}}|
+{{- end}}
+<!-- sync-status:{{range .}} {{.Branch}}|{{.State}}|{{.PR}}{{end}} -->
+`
+
+	// staleReminder reminds of a sync pull request left unmerged, the marker
+	// holds the commit time of its source branch to remind once per change.
+	staleReminder = `
+{{range .Mentions}}@{{.}} {{end}}
+当前同步 PR 已超过 {{.Days}} 天未更新或合入，请尽快评审；如不再需要，可评论 /close 关闭。
+{{- if .CloseDays}}
+超过 {{.CloseDays}} 天未更新的同步 PR 将被自动关闭。
+{{- end}}
+<!-- sync-stale: {{.Since}} -->
+`
+
+	staleClosed = `
+{{range .Mentions}}@{{.}} {{end}}
+当前同步 PR 已超过 {{.Days}} 天未更新或合入，已自动关闭并删除源分支。如仍需同步，请在原 PR 中重新评论 ` + "`/sync {{.Branch}}`" + `。
 `
 
 	replyClose = `
@@ -86,12 +265,45 @@ In response to [this]({{.URL}}):
 `
 )
 
+var bodyFuncs = template.FuncMap{
+	"add":  func(a, b int) int { return a + b },
+	"join": strings.Join,
+}
+
+// boardFuncs shows the states of the status board, on the board and on the
+// issues linked to the original pull request.
+var boardFuncs = template.FuncMap{
+	"state": func(state string) string {
+		switch state {
+		case statePending:
+			return syncPRPending
+		case stateMerged:
+			return syncPRMerged
+		case stateClosed:
+			return syncPRAbandoned
+		case stateConflict:
+			return syncConflict
+		}
+		return state
+	},
+}
+
 var (
 	replySyncCheckTmpl   = template.Must(template.New("greeting").Parse(replySyncCheck))
 	replySyncTmpl        = template.Must(template.New("replySync").Parse(replySync))
-	syncPRBodyTmpl       = template.Must(template.New("syncPRBody").Parse(syncPRBody))
-	syncPRBodyTmplKernel = template.Must(template.New("syncKernelPRBody").Parse(syncKernelPRBody))
+	syncPRBodyTmpl       = template.Must(template.New("syncPRBody").Funcs(bodyFuncs).Parse(syncPRBody))
+	syncPRBodyTmplKernel = template.Must(template.New("syncKernelPRBody").Funcs(bodyFuncs).Parse(syncKernelPRBody))
+	syncChainResultTmpl  = template.Must(template.New("syncChainResult").Parse(syncChainResult))
 	syncResultTmpl       = template.Must(template.New("syncPRBody").Parse(syncResult))
+	syncUpdateOfferTmpl  = template.Must(template.New("syncUpdateOffer").Parse(syncUpdateOffer))
+	syncFollowUpTmpl     = template.Must(template.New("syncFollowUpOffer").Funcs(bodyFuncs).Parse(syncFollowUpOffer))
+	syncDryRunResultTmpl = template.Must(template.New("syncDryRunResult").Parse(syncDryRunResult))
+	syncBranchPRBodyTmpl = template.Must(template.New("syncBranchPRBody").Parse(syncBranchPRBody))
+	syncBranchResultTmpl = template.Must(template.New("syncBranchResult").Parse(syncBranchResult))
+	syncIssueLinksTmpl   = template.Must(template.New("syncIssueLinks").Funcs(boardFuncs).Parse(syncIssueLinks))
+	statusBoardTmpl      = template.Must(template.New("statusBoard").Funcs(boardFuncs).Parse(statusBoard))
+	staleReminderTmpl    = template.Must(template.New("staleReminder").Parse(staleReminder))
+	staleClosedTmpl      = template.Must(template.New("staleClosed").Parse(staleClosed))
 	replyCloseTmpl       = template.Must(template.New("syncPRBody").Parse(replyClose))
 )
 
@@ -104,6 +316,16 @@ type syncStatus struct {
 	Name   string
 	Status string
 	PR     string
+	// Head is the source branch of the sync pull request created, it is empty
+	// when none was.
+	Head string
+}
+
+type dryRunStatus struct {
+	Name   string
+	Status string
+	// Detail lists the conflicting files or the error.
+	Detail string
 }
 
 func executeTemplate(tmpl *template.Template, data interface{}) (string, error) {
@@ -0,0 +1,168 @@
+package hook
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"sync-bot/gitee"
+	"sync-bot/github"
+	"sync-bot/gitlab"
+	"sync-bot/platform"
+
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxPayloadSize is the largest webhook delivery accepted.
+	maxPayloadSize = 25 << 20
+	// webhookWorkers is how many webhook deliveries are handled at a time.
+	webhookWorkers = 4
+	// webhookQueueSize is how many more deliveries wait for a worker, the ones
+	// beyond are refused.
+	webhookQueueSize = 100
+)
+
+// webhookParser maps a webhook delivery to a generic event, it returns an
+// empty kind for deliveries which are not handled.
+type webhookParser func(header http.Header, payload []byte) (kind string, evt *client.GenericEvent, err error)
+
+// webhookVerifier checks a webhook delivery against the secret.
+type webhookVerifier func(header http.Header, payload, secret []byte) error
+
+// webhookOf returns the parser and verifier of the webhooks of a platform. The
+// framework serves those of GitCode, they are parsed here for replays.
+func webhookOf(name string) (webhookParser, webhookVerifier, error) {
+	switch name {
+	case platform.GitCode:
+		// the webhooks of GitCode follow the Gitee ones but for the headers
+		parse := func(header http.Header, payload []byte) (string, *client.GenericEvent, error) {
+			return gitee.ParseWebhook(giteeHeader(header), payload)
+		}
+		verify := func(header http.Header, payload, secret []byte) error {
+			return gitee.VerifySignature(giteeHeader(header), payload, secret)
+		}
+		return parse, verify, nil
+	case platform.GitHub:
+		return github.ParseWebhook, github.VerifySignature, nil
+	case platform.Gitee:
+		return gitee.ParseWebhook, gitee.VerifySignature, nil
+	case platform.GitLab:
+		return gitlab.ParseWebhook, gitlab.VerifySignature, nil
+	default:
+		return nil, nil, fmt.Errorf("webhooks of %s are served by the framework", name)
+	}
+}
+
+// giteeHeader maps the X-GitCode-* headers of a GitCode delivery to the
+// X-Gitee-* ones.
+func giteeHeader(header http.Header) http.Header {
+	h := header.Clone()
+	for _, name := range []string{"Event", "Token", "Timestamp"} {
+		if v := header.Get("X-GitCode-" + name); v != "" {
+			h.Set("X-Gitee-"+name, v)
+		}
+	}
+	return h
+}
+
+// Dispatch handles an event of kind with the handlers registered to the framework.
+func (bot *robot) Dispatch(kind string, evt *client.GenericEvent) {
+	org, repo := utils.GetString(evt.Org), utils.GetString(evt.Repo)
+	logger := bot.log.WithFields(logrus.Fields{
+		"org":    org,
+		"repo":   repo,
+		"number": utils.GetString(evt.Number),
+		"kind":   kind,
+	})
+	repoCnf := bot.cnf.getRepoConfig(org, repo)
+	if repoCnf == nil {
+		logger.Infoln("Repository is not configured, ignore it.")
+		return
+	}
+	bot.dispatch(kind, evt, repoCnf, logger)
+}
+
+func (bot *robot) dispatch(kind string, evt *client.GenericEvent, repoCnf *repoConfig, logger *logrus.Entry) {
+	switch kind {
+	case platform.PullRequestEvent:
+		bot.handlePREvent(evt, repoCnf, logger)
+	case platform.PullRequestCommentEvent:
+		bot.handlePullRequestCommentEvent(evt, repoCnf, logger)
+	case platform.IssueCommentEvent:
+		bot.handleIssueCommentEvent(evt, repoCnf, logger)
+	default:
+		logger.Infoln("Ignoring unhandled event.")
+	}
+}
+
+// webhookEvent is a webhook delivery waiting to be dispatched.
+type webhookEvent struct {
+	kind string
+	evt  *client.GenericEvent
+}
+
+// WebhookHandler returns the handler of the webhooks of the platforms which the
+// framework server doesn't serve. Deliveries are verified with the secret, all
+// of them are rejected while it is empty. They are dispatched by a few workers
+// and refused while too many wait.
+func (bot *robot) WebhookHandler(secret func() []byte) (http.Handler, error) {
+	return bot.webhookHandler(secret, webhookWorkers, webhookQueueSize)
+}
+
+func (bot *robot) webhookHandler(secret func() []byte, workers, queueSize int) (http.Handler, error) {
+	parse, verify, err := webhookOf(bot.platform.Name())
+	if err != nil {
+		return nil, err
+	}
+	queue := make(chan webhookEvent, queueSize)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for e := range queue {
+				bot.Dispatch(e.kind, e.evt)
+			}
+		}()
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		payload, err := io.ReadAll(io.LimitReader(r.Body, maxPayloadSize))
+		if err != nil {
+			http.Error(w, "read payload failed", http.StatusBadRequest)
+			return
+		}
+		key := secret()
+		if len(key) == 0 {
+			// an empty secret would accept deliveries signed by anyone
+			bot.log.Errorln("Reject webhook delivery, the webhook secret is empty")
+			http.Error(w, "webhook secret is not configured", http.StatusUnauthorized)
+			return
+		}
+		if err = verify(r.Header, payload, key); err != nil {
+			bot.log.WithError(err).Warnln("Reject webhook delivery")
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		kind, evt, err := parse(r.Header, payload)
+		if err != nil {
+			bot.log.WithError(err).Warnln("Parse webhook delivery failed")
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		if kind != "" {
+			// the platform expects a quick answer, syncing may take minutes
+			select {
+			case queue <- webhookEvent{kind: kind, evt: evt}:
+			default:
+				bot.log.Warnf("Reject webhook delivery, %d deliveries are waiting", queueSize)
+				http.Error(w, "too many deliveries", http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}), nil
+}
@@ -0,0 +1,83 @@
+package hook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"sync-bot/platform"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	payload := `{"action":"created"}`
+	sign := func(key string) string {
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write([]byte(payload))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		want      int
+	}{
+		{name: "signed", secret: "secret", signature: sign("secret"), want: http.StatusOK},
+		{name: "wrong signature", secret: "secret", signature: sign("other"), want: http.StatusUnauthorized},
+		{name: "empty secret", secret: "", signature: sign(""), want: http.StatusUnauthorized},
+	}
+	p, err := platform.New(platform.GitHub, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot := &robot{cnf: &Configuration{}, log: logrus.NewEntry(logrus.StandardLogger()), platform: p}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := bot.WebhookHandler(func() []byte { return []byte(tt.secret) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload))
+			r.Header.Set("X-GitHub-Event", "ping")
+			r.Header.Set("X-Hub-Signature-256", tt.signature)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookHandlerQueue(t *testing.T) {
+	payload := `{"action":"created","issue":{"number":1,"title":"fix a"},"comment":{"body":"/sync stable","user":{"login":"alice"}},
+		"repository":{"name":"r","owner":{"login":"o"}}}`
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(payload))
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	p, err := platform.New(platform.GitHub, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot := &robot{cnf: &Configuration{}, log: logrus.NewEntry(logrus.StandardLogger()), platform: p}
+	// no worker takes the deliveries, a single one may wait
+	handler, err := bot.webhookHandler(func() []byte { return []byte("secret") }, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []int{http.StatusOK, http.StatusServiceUnavailable} {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload))
+		r.Header.Set("X-GitHub-Event", "issue_comment")
+		r.Header.Set("X-Hub-Signature-256", signature)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != want {
+			t.Errorf("status = %d, want %d", w.Code, want)
+		}
+	}
+}
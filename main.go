@@ -2,15 +2,40 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"io"
+	"net/http"
 	"os"
 
 	"github.com/opensourceways/robot-framework-lib/framework"
 	"sync-bot/hook"
+	"sync-bot/platform"
 )
 
 const component = "robot-sync-bot"
 
 func main() {
+	if len(os.Args) > 1 {
+		var run func([]string, io.Writer) error
+		switch os.Args[1] {
+		case "replay":
+			run = runReplay
+		case "sync":
+			run = runSync
+		case "sync-branch":
+			run = runSyncBranch
+		case "divergence":
+			run = runDivergence
+		}
+		if run != nil {
+			if err := run(os.Args[2:], os.Stdout); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	logger := framework.NewLogger().WithField("component", component)
 	opt := new(robotOptions)
 	// Gather the necessary arguments from command line for project startup
@@ -20,9 +45,41 @@ func main() {
 	}
 
 	cnf := opt.service.ConfigmapAgentValue.GetConfigmap().(*hook.Configuration)
-	bot := hook.NewRobot(cnf, opt.service.TokenValue, logger)
+	p, err := platform.New(cnf.Platform, cnf.PlatformHost)
+	if err != nil {
+		logger.WithError(err).Errorln("Unsupported platform")
+		return
+	}
+	if err = opt.validateWebhookSecret(p.Name()); err != nil {
+		logger.WithError(err).Errorln("Invalid options")
+		return
+	}
+
+	tokens, webhookSecret, agent, err := opt.loadSecrets()
+	if err != nil {
+		logger.WithError(err).Errorln("Load secrets failed")
+		return
+	}
+	defer agent.Stop()
+
+	bot := hook.NewRobot(cnf, tokens, logger)
 	if bot == nil {
 		return
 	}
+	stopReport := bot.StartDivergenceReport()
+	defer stopReport()
+	stopReminder := bot.StartStaleSyncReminder()
+	defer stopReminder()
+	if p.Name() != platform.GitCode {
+		// the framework server only serves the webhooks of GitCode
+		handler, err := bot.WebhookHandler(webhookSecret)
+		if err != nil {
+			logger.WithError(err).Errorln("Create webhook handler failed")
+			return
+		}
+		logger.Infof("Serve %s webhooks on %s", p.Name(), opt.webhookAddr)
+		logger.WithError(http.ListenAndServe(opt.webhookAddr, handler)).Errorln("Webhook server stopped")
+		return
+	}
 	framework.StartupServer(framework.NewServer(bot, opt.service), opt.service)
 }
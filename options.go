@@ -15,22 +15,57 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/opensourceways/robot-framework-lib/client"
 	"github.com/opensourceways/robot-framework-lib/config"
 	"github.com/opensourceways/robot-framework-lib/framework"
 	"github.com/sirupsen/logrus"
 	"sync-bot/hook"
+	"sync-bot/platform"
+	"sync-bot/secret"
 )
 
 type robotOptions struct {
 	service config.FrameworkOptions
+	// tokenPath is the file of the platform token, it is watched and reloaded on change.
+	tokenPath string
+	// pushTokenPath is the file of the token used for pushing and creating pull requests.
+	pushTokenPath string
+	// pushUser is the user the push token belongs to, the git username of the pushes.
+	pushUser string
+	// orgTokens are the tokens of organizations in the form of org[:role]=path.
+	orgTokens orgTokenFlag
+	// orgPushUsers are the users the push tokens of organizations belong to in the form of org=user.
+	orgPushUsers orgUserFlag
+	// secretInterval is the interval of polling the watched secret files.
+	secretInterval time.Duration
+	// webhookAddr is the listen address of the webhooks of platforms the framework doesn't serve.
+	webhookAddr string
+	// webhookSecretPath is the file of the secret signing those webhooks.
+	webhookSecretPath string
 }
 
 // gatherOptions gather the necessary arguments from command line for project startup.
 // It save the configuration, the token etc. It will to be used for subsequent processes.
 func (o *robotOptions) gatherOptions(fs *flag.FlagSet, logger *logrus.Entry, args ...string) {
 	o.service.AddFlags(fs)
+	fs.StringVar(&o.tokenPath, "sync-token-path", "",
+		"Path to the file holding the platform token, reloaded when it changes. The framework token is used if empty.")
+	fs.StringVar(&o.pushTokenPath, "sync-push-token-path", "",
+		"Path to the file holding the token for pushing branches and creating pull requests. The platform token is used if empty.")
+	fs.StringVar(&o.pushUser, "sync-push-user", "",
+		"User the push token belongs to, used as the git username. The platform is asked for it if empty.")
+	fs.Var(&o.orgTokens, "sync-org-token", "Token of an organization in the form of org[:role]=path, role is api or push. Can be repeated.")
+	fs.Var(&o.orgPushUsers, "sync-org-push-user",
+		"User the push token of an organization belongs to in the form of org=user. Can be repeated.")
+	fs.DurationVar(&o.secretInterval, "sync-secret-interval", time.Minute, "Interval of polling the watched secret files.")
+	fs.StringVar(&o.webhookAddr, "sync-webhook-addr", ":8888",
+		"Listen address of the webhooks of platforms not served by the framework, like GitHub.")
+	fs.StringVar(&o.webhookSecretPath, "sync-webhook-secret-path", "",
+		"Path to the file holding the secret of the webhooks of platforms not served by the framework.")
 	_ = fs.Parse(args)
 	cnf := new(hook.Configuration)
 	o.service.ValidateComposite(cnf, logger)
@@ -42,3 +77,101 @@ func (o *robotOptions) gatherOptions(fs *flag.FlagSet, logger *logrus.Entry, arg
 	client.SetCommunityName(cnf.CommunityName)
 	framework.SetRobotUserID(cnf.CommunityRobotID)
 }
+
+// orgTokenFlag collects the repeated --sync-org-token flags.
+type orgTokenFlag []string
+
+func (f *orgTokenFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *orgTokenFlag) Set(value string) error {
+	if _, _, _, err := secret.ParseOrgToken(value); err != nil {
+		return err
+	}
+	*f = append(*f, value)
+	return nil
+}
+
+// orgUserFlag collects the repeated --sync-org-push-user flags.
+type orgUserFlag []string
+
+func (f *orgUserFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *orgUserFlag) Set(value string) error {
+	if _, _, err := secret.ParseOrgUser(value); err != nil {
+		return err
+	}
+	*f = append(*f, value)
+	return nil
+}
+
+// validateWebhookSecret checks that the webhooks of the platform name are
+// verified: the framework server only serves those of GitCode, the others are
+// served by sync-bot and must be signed with the secret.
+func (o *robotOptions) validateWebhookSecret(name string) error {
+	if name != platform.GitCode && o.webhookSecretPath == "" {
+		return fmt.Errorf("--sync-webhook-secret-path is required to serve the webhooks of %s", name)
+	}
+	return nil
+}
+
+// loadSecrets loads the secret files given by the options and returns the
+// tokens of every role and the generator of the webhook secret. The files are
+// polled for changes until the agent is stopped.
+func (o *robotOptions) loadSecrets() (*secret.Tokens, func() []byte, *secret.Agent, error) {
+	agent := secret.NewAgent()
+	var paths []string
+	for _, p := range []string{o.tokenPath, o.pushTokenPath, o.webhookSecretPath} {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	type orgToken struct {
+		org  string
+		role secret.Role
+		path string
+	}
+	orgTokens := make([]orgToken, 0, len(o.orgTokens))
+	for _, value := range o.orgTokens {
+		org, role, path, err := secret.ParseOrgToken(value)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		orgTokens = append(orgTokens, orgToken{org: org, role: role, path: path})
+		paths = append(paths, path)
+	}
+	if err := agent.LoadSecrets(paths); err != nil {
+		return nil, nil, nil, err
+	}
+
+	api := func() []byte { return o.service.TokenValue }
+	if o.tokenPath != "" {
+		api = agent.GetGenerator(o.tokenPath)
+	}
+	tokens := secret.NewTokens(api)
+	if o.pushTokenPath != "" {
+		tokens.Set(secret.RolePush, agent.GetGenerator(o.pushTokenPath))
+	}
+	for _, t := range orgTokens {
+		tokens.SetOrg(t.org, t.role, agent.GetGenerator(t.path))
+	}
+	if o.pushUser != "" {
+		tokens.SetUser("", o.pushUser)
+	}
+	for _, value := range o.orgPushUsers {
+		org, user, err := secret.ParseOrgUser(value)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		tokens.SetUser(org, user)
+	}
+	webhookSecret := func() []byte { return nil }
+	if o.webhookSecretPath != "" {
+		webhookSecret = agent.GetGenerator(o.webhookSecretPath)
+	}
+	agent.Start(o.secretInterval)
+	return tokens, webhookSecret, agent, nil
+}
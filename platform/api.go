@@ -0,0 +1,126 @@
+package platform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// perPage is the page size of list requests, the maximum of most platforms.
+const perPage = 100
+
+// StatusError is returned when the API answers with an unexpected status.
+type StatusError struct {
+	Method string
+	URL    string
+	Code   int
+	Body   string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s returned status %d: %s", e.Method, e.URL, e.Code, e.Body)
+}
+
+// IsNotFound returns true if err is a 404 answer of the API.
+func IsNotFound(err error) bool {
+	se, ok := err.(*StatusError)
+	return ok && se.Code == http.StatusNotFound
+}
+
+// API is a minimal JSON client of a REST API. Create with NewAPI.
+type API struct {
+	// base is the base URL every path is appended to.
+	base string
+	// auth adds the credentials to a request.
+	auth func(req *http.Request)
+	// client sends the requests.
+	client *http.Client
+}
+
+// NewAPI returns a client of the REST API at base, auth adds the credentials
+// to every request.
+func NewAPI(base string, auth func(req *http.Request)) *API {
+	return &API{
+		base:   strings.TrimSuffix(base, "/"),
+		auth:   auth,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Do sends a request with in encoded as the JSON body and decodes the JSON
+// answer into out. Both in and out may be nil.
+func (a *API) Do(method, path string, query url.Values, in, out interface{}) error {
+	u := a.base + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if a.auth != nil {
+		a.auth(req)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		// the url in the error may hold a token in its query, drop it
+		if ue, ok := err.(*url.Error); ok {
+			err = ue.Err
+		}
+		return fmt.Errorf("%s %s failed: %v", method, a.base+path, err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &StatusError{Method: method, URL: a.base + path, Code: resp.StatusCode, Body: string(b)}
+	}
+	if out != nil && len(bytes.TrimSpace(b)) > 0 {
+		if err = json.Unmarshal(b, out); err != nil {
+			return fmt.Errorf("decode answer of %s %s failed: %v", method, a.base+path, err)
+		}
+	}
+	return nil
+}
+
+// GetAll gets every page of a list with the page and per_page parameters.
+func GetAll[T any](a *API, path string, query url.Values) ([]T, error) {
+	q := url.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+	q.Set("per_page", strconv.Itoa(perPage))
+	var all []T
+	for page := 1; ; page++ {
+		q.Set("page", strconv.Itoa(page))
+		var items []T
+		if err := a.Do(http.MethodGet, path, q, nil, &items); err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+		if len(items) < perPage {
+			return all, nil
+		}
+	}
+}
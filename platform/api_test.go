@@ -0,0 +1,50 @@
+package platform
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGetAll(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		size, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		// 150 items in two pages
+		n := size
+		if page == 2 {
+			n = 150 - size
+		} else if page > 2 {
+			n = 0
+		}
+		items := make([]string, n)
+		for i := range items {
+			items[i] = `"x"`
+		}
+		_, _ = w.Write([]byte("[" + strings.Join(items, ",") + "]"))
+	}))
+	defer srv.Close()
+
+	a := NewAPI(srv.URL, func(req *http.Request) {
+		req.Header.Set("Authorization", "token secret")
+	})
+	got, err := GetAll[string](a, "/items", nil)
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if len(got) != 150 {
+		t.Errorf("GetAll() got %d items, want %d", len(got), 150)
+	}
+
+	a = NewAPI(srv.URL, nil)
+	err = a.Do(http.MethodGet, "/items", nil, nil, nil)
+	if se, ok := err.(*StatusError); !ok || se.Code != http.StatusUnauthorized {
+		t.Errorf("Do() error = %v, want status %d", err, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,120 @@
+package platform
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Delivery is a recorded webhook delivery.
+type Delivery struct {
+	// Source names where the delivery was read from, like "sync.http" or "deliveries.jsonl:3".
+	Source string
+	// Header holds the headers of the delivery.
+	Header http.Header
+	// Payload is the body of the delivery.
+	Payload []byte
+}
+
+// eventHeaders are the headers naming the event of a delivery on each platform.
+var eventHeaders = map[string]string{
+	"X-GitCode-Event": GitCode,
+	"X-GitHub-Event":  GitHub,
+	"X-Gitee-Event":   Gitee,
+	"X-Gitlab-Event":  GitLab,
+}
+
+// Platform returns the name of the platform which sent the delivery, or an
+// empty string if it can't be told from the headers.
+func (d *Delivery) Platform() string {
+	for h, name := range eventHeaders {
+		if d.Header.Get(h) != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// ReadHTTPDelivery reads a delivery recorded as an HTTP request, like the
+// files of the tests directory: a request line and the headers, then the
+// payload after an empty line.
+func ReadHTTPDelivery(r io.Reader) (*Delivery, error) {
+	br := bufio.NewReader(r)
+	d := &Delivery{Header: http.Header{}}
+	requestLine := true
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if err == io.EOF {
+				return nil, errors.New("no payload after the headers")
+			}
+			break
+		}
+		if requestLine {
+			requestLine = false
+		} else if k, v, ok := strings.Cut(line, ":"); ok {
+			d.Header.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+		} else {
+			return nil, fmt.Errorf("malformed header %q", line)
+		}
+		if err == io.EOF {
+			return nil, errors.New("no payload after the headers")
+		}
+	}
+	payload, err := io.ReadAll(br)
+	if err != nil {
+		return nil, err
+	}
+	d.Payload = bytes.TrimSpace(payload)
+	return d, nil
+}
+
+// recordedDelivery is a line of a JSON lines capture of deliveries.
+type recordedDelivery struct {
+	Header  map[string]string `json:"header"`
+	Payload json.RawMessage   `json:"payload"`
+}
+
+// ReadJSONDeliveries reads deliveries captured as JSON lines, one object with
+// "header" and "payload" per line. Lines of another shape are skipped and
+// returned as errors, so a capture can be replayed partly.
+func ReadJSONDeliveries(r io.Reader, source string) ([]*Delivery, []error) {
+	var deliveries []*Delivery
+	var errs []error
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64<<10), 32<<20)
+	for n := 1; s.Scan(); n++ {
+		line := bytes.TrimSpace(s.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		src := fmt.Sprintf("%s:%d", source, n)
+		var rec recordedDelivery
+		if err := json.Unmarshal(line, &rec); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", src, err))
+			continue
+		}
+		if len(rec.Header) == 0 || len(rec.Payload) == 0 {
+			errs = append(errs, fmt.Errorf("%s: not a delivery, header or payload is missing", src))
+			continue
+		}
+		d := &Delivery{Source: src, Header: http.Header{}, Payload: rec.Payload}
+		for k, v := range rec.Header {
+			d.Header.Set(k, v)
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := s.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %v", source, err))
+	}
+	return deliveries, errs
+}
@@ -0,0 +1,53 @@
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadHTTPDelivery(t *testing.T) {
+	f, err := os.Open(filepath.Join("..", "tests", "sync.http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	d, err := ReadHTTPDelivery(f)
+	if err != nil {
+		t.Fatalf("ReadHTTPDelivery() error = %v", err)
+	}
+	if got := d.Header.Get("X-Gitee-Event"); got != "Note Hook" {
+		t.Errorf("X-Gitee-Event = %q, want %q", got, "Note Hook")
+	}
+	if got := d.Platform(); got != Gitee {
+		t.Errorf("Platform() = %q, want %q", got, Gitee)
+	}
+	if !strings.HasPrefix(string(d.Payload), "{") || !strings.HasSuffix(string(d.Payload), "}") {
+		t.Errorf("Payload is not the JSON body: %.40q", d.Payload)
+	}
+
+	if _, err = ReadHTTPDelivery(strings.NewReader("POST /hook\nX-Gitee-Event: Note Hook\n")); err == nil {
+		t.Errorf("ReadHTTPDelivery() accepted a delivery without payload")
+	}
+}
+
+func TestReadJSONDeliveries(t *testing.T) {
+	capture := `{"header":{"X-GitHub-Event":"issue_comment"},"payload":{"action":"created"}}
+
+{"request_id":"user-001","title":"not a delivery"}
+not json
+{"header":{"X-Gitlab-Event":"Note Hook"},"payload":{}}
+`
+	deliveries, errs := ReadJSONDeliveries(strings.NewReader(capture), "capture.jsonl")
+	if len(deliveries) != 2 || len(errs) != 2 {
+		t.Fatalf("ReadJSONDeliveries() = %d deliveries, errors %v", len(deliveries), errs)
+	}
+	if d := deliveries[0]; d.Source != "capture.jsonl:1" || d.Platform() != GitHub || string(d.Payload) != `{"action":"created"}` {
+		t.Errorf("first delivery = %s %s %s", d.Source, d.Platform(), d.Payload)
+	}
+	if d := deliveries[1]; d.Source != "capture.jsonl:5" || d.Platform() != GitLab {
+		t.Errorf("second delivery = %s %s", d.Source, d.Platform())
+	}
+}
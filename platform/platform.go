@@ -0,0 +1,178 @@
+// Package platform describes the code hosting platforms sync-bot works with.
+package platform
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// platform names
+const (
+	GitCode = "gitcode"
+	GitHub  = "github"
+	Gitee   = "gitee"
+	GitLab  = "gitlab"
+)
+
+// event kinds of webhook deliveries
+const (
+	PullRequestEvent        = "pull_request"
+	PullRequestCommentEvent = "pull_request_comment"
+	IssueCommentEvent       = "issue_comment"
+)
+
+// Comment is a comment on a pull request with the id it is edited by.
+type Comment struct {
+	ID        string
+	Commenter string
+	Body      string
+}
+
+// Platform builds the URLs of a code hosting platform. Create with New.
+type Platform struct {
+	// name is one of the platform names.
+	name string
+	// host is the web and git host.
+	host string
+	// apiBase is the base URL of the REST API.
+	apiBase string
+	// prURL is the format of a pull request URL: host, owner, repo, number.
+	prURL string
+	// treeURL is the format of a branch URL: host, owner, repo, branch.
+	treeURL string
+	// prRef is the format of the ref holding the head of a pull request: number.
+	prRef string
+}
+
+// New returns the platform of name. Host is only needed for self-hosted
+// instances, it defaults to the public host of the platform.
+func New(name, host string) (*Platform, error) {
+	var p Platform
+	switch strings.ToLower(name) {
+	case "", GitCode:
+		p = Platform{
+			name:    GitCode,
+			host:    "gitcode.com",
+			apiBase: "https://api.gitcode.com/api/v5",
+			prURL:   "https://%s/%s/%s/merge_requests/%s",
+			treeURL: "https://%s/%s/%s/tree/%s",
+			prRef:   "refs/merge-requests/%d/head",
+		}
+	case GitHub:
+		p = Platform{
+			name:    GitHub,
+			host:    "github.com",
+			apiBase: "https://api.github.com",
+			prURL:   "https://%s/%s/%s/pull/%s",
+			treeURL: "https://%s/%s/%s/tree/%s",
+			prRef:   "refs/pull/%d/head",
+		}
+		if host != "" && host != p.host {
+			// GitHub Enterprise Server
+			p.apiBase = fmt.Sprintf("https://%s/api/v3", host)
+		}
+	case Gitee:
+		p = Platform{
+			name:    Gitee,
+			host:    "gitee.com",
+			apiBase: "https://gitee.com/api/v5",
+			prURL:   "https://%s/%s/%s/pulls/%s",
+			treeURL: "https://%s/%s/%s/tree/%s",
+			prRef:   "refs/pull/%d/head",
+		}
+	case GitLab:
+		p = Platform{
+			name:    GitLab,
+			host:    "gitlab.com",
+			prURL:   "https://%s/%s/%s/-/merge_requests/%s",
+			treeURL: "https://%s/%s/%s/-/tree/%s",
+			prRef:   "refs/merge-requests/%d/head",
+		}
+		if host != "" {
+			p.host = host
+		}
+		p.apiBase = fmt.Sprintf("https://%s/api/v4", p.host)
+	default:
+		return nil, fmt.Errorf("unsupported platform %q", name)
+	}
+	if host != "" {
+		p.host = host
+	}
+	return &p, nil
+}
+
+// Name returns the name of the platform.
+func (p *Platform) Name() string {
+	return p.name
+}
+
+// Host returns the web and git host of the platform.
+func (p *Platform) Host() string {
+	return p.host
+}
+
+// APIBase returns the base URL of the REST API.
+func (p *Platform) APIBase() string {
+	return p.apiBase
+}
+
+// RepoURL returns the clone URL of a repository.
+func (p *Platform) RepoURL(owner, repo string) string {
+	return fmt.Sprintf("https://%s/%s/%s.git", p.host, owner, repo)
+}
+
+// PullRequestURL returns the web URL of a pull request.
+func (p *Platform) PullRequestURL(owner, repo, number string) string {
+	return fmt.Sprintf(p.prURL, p.host, owner, repo, number)
+}
+
+// TreeURL returns the web URL of a branch.
+func (p *Platform) TreeURL(owner, repo, branch string) string {
+	return fmt.Sprintf(p.treeURL, p.host, owner, repo, branch)
+}
+
+// PullRequestRef returns the format of the ref holding the head of a pull
+// request, it takes the pull request number.
+func (p *Platform) PullRequestRef() string {
+	return p.prRef
+}
+
+// ParseIssueURL returns the repository and number of the issue at the web URL
+// u, like https://gitee.com/owner/repo/issues/I8ABCD. It fails for URLs of
+// other hosts or which are no issues.
+func (p *Platform) ParseIssueURL(u string) (owner, repo, number string, ok bool) {
+	return p.parseURL(u, "issues")
+}
+
+// ParsePullRequestURL returns the repository and number of the pull request at
+// the web URL u, as built by PullRequestURL. It fails for URLs of other hosts
+// or which are no pull requests.
+func (p *Platform) ParsePullRequestURL(u string) (owner, repo, number string, ok bool) {
+	// the path element before the number, like pulls or merge_requests
+	return p.parseURL(u, path.Base(path.Dir(p.prURL)))
+}
+
+// parseURL returns the repository and number of the web URL u whose path ends
+// with kind and the number.
+func (p *Platform) parseURL(u, kind string) (owner, repo, number string, ok bool) {
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Host != p.host {
+		return "", "", "", false
+	}
+	parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	// owner, repo, kind and the number at least; GitLab puts "-" before kind
+	if len(parts) < 4 || parts[len(parts)-2] != kind || parts[len(parts)-1] == "" {
+		return "", "", "", false
+	}
+	number = parts[len(parts)-1]
+	parts = parts[:len(parts)-2]
+	if parts[len(parts)-1] == "-" {
+		parts = parts[:len(parts)-1]
+	}
+	if len(parts) < 2 {
+		return "", "", "", false
+	}
+	return strings.Join(parts[:len(parts)-1], "/"), parts[len(parts)-1], number, true
+}
@@ -0,0 +1,186 @@
+package platform
+
+import (
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	type want struct {
+		host    string
+		apiBase string
+		pr      string
+		tree    string
+		ref     string
+	}
+	tests := []struct {
+		name     string
+		platform string
+		host     string
+		want     want
+		wantErr  bool
+	}{
+		{
+			name:     "default is gitcode",
+			platform: "",
+			want: want{
+				host:    "gitcode.com",
+				apiBase: "https://api.gitcode.com/api/v5",
+				pr:      "https://gitcode.com/openEuler/kernel/merge_requests/1",
+				tree:    "https://gitcode.com/openEuler/kernel/tree/master",
+				ref:     "refs/merge-requests/%d/head",
+			},
+		},
+		{
+			name:     "github",
+			platform: "github",
+			want: want{
+				host:    "github.com",
+				apiBase: "https://api.github.com",
+				pr:      "https://github.com/openEuler/kernel/pull/1",
+				tree:    "https://github.com/openEuler/kernel/tree/master",
+				ref:     "refs/pull/%d/head",
+			},
+		},
+		{
+			name:     "github enterprise",
+			platform: "GitHub",
+			host:     "github.example.com",
+			want: want{
+				host:    "github.example.com",
+				apiBase: "https://github.example.com/api/v3",
+				pr:      "https://github.example.com/openEuler/kernel/pull/1",
+				tree:    "https://github.example.com/openEuler/kernel/tree/master",
+				ref:     "refs/pull/%d/head",
+			},
+		},
+		{
+			name:     "gitee",
+			platform: "gitee",
+			want: want{
+				host:    "gitee.com",
+				apiBase: "https://gitee.com/api/v5",
+				pr:      "https://gitee.com/openEuler/kernel/pulls/1",
+				tree:    "https://gitee.com/openEuler/kernel/tree/master",
+				ref:     "refs/pull/%d/head",
+			},
+		},
+		{
+			name:     "self-hosted gitlab",
+			platform: "gitlab",
+			host:     "gitlab.example.com",
+			want: want{
+				host:    "gitlab.example.com",
+				apiBase: "https://gitlab.example.com/api/v4",
+				pr:      "https://gitlab.example.com/openEuler/kernel/-/merge_requests/1",
+				tree:    "https://gitlab.example.com/openEuler/kernel/-/tree/master",
+				ref:     "refs/merge-requests/%d/head",
+			},
+		},
+		{
+			name:     "unsupported",
+			platform: "svn",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(tt.platform, tt.host)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got := want{
+				host:    p.Host(),
+				apiBase: p.APIBase(),
+				pr:      p.PullRequestURL("openEuler", "kernel", "1"),
+				tree:    p.TreeURL("openEuler", "kernel", "master"),
+				ref:     p.PullRequestRef(),
+			}
+			if got != tt.want {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIssueURL(t *testing.T) {
+	type want struct {
+		owner, repo, number string
+		ok                  bool
+	}
+	tests := []struct {
+		name     string
+		platform string
+		host     string
+		url      string
+		want     want
+	}{
+		{
+			name:     "gitee",
+			platform: "gitee",
+			url:      "https://gitee.com/src-openeuler/kernel/issues/I8ABCD",
+			want:     want{"src-openeuler", "kernel", "I8ABCD", true},
+		},
+		{
+			name:     "gitcode",
+			platform: "gitcode",
+			url:      "https://gitcode.com/openeuler/kernel/issues/12",
+			want:     want{"openeuler", "kernel", "12", true},
+		},
+		{
+			name:     "gitlab subgroup",
+			platform: "gitlab",
+			host:     "gitlab.example.com",
+			url:      "https://gitlab.example.com/group/sub/kernel/-/issues/3",
+			want:     want{"group/sub", "kernel", "3", true},
+		},
+		{
+			name:     "other host",
+			platform: "github",
+			url:      "https://gitee.com/openeuler/kernel/issues/12",
+		},
+		{
+			name:     "pull request",
+			platform: "github",
+			url:      "https://github.com/openeuler/kernel/pull/12",
+		},
+		{
+			name:     "no repository",
+			platform: "github",
+			url:      "https://github.com/openeuler/issues/12",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(tt.platform, tt.host)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got want
+			got.owner, got.repo, got.number, got.ok = p.ParseIssueURL(tt.url)
+			if got != tt.want {
+				t.Errorf("ParseIssueURL() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePullRequestURL(t *testing.T) {
+	for _, name := range []string{GitCode, GitHub, Gitee, GitLab} {
+		t.Run(name, func(t *testing.T) {
+			p, err := New(name, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			owner, repo, number, ok := p.ParsePullRequestURL(p.PullRequestURL("src-openeuler", "kernel", "12"))
+			if !ok || owner != "src-openeuler" || repo != "kernel" || number != "12" {
+				t.Errorf("ParsePullRequestURL() = %s, %s, %s, %v", owner, repo, number, ok)
+			}
+			if _, _, _, ok = p.ParsePullRequestURL("https://" + p.Host() + "/src-openeuler/kernel/issues/12"); ok {
+				t.Errorf("ParsePullRequestURL() of an issue succeeded")
+			}
+		})
+	}
+}
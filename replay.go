@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+	"sync-bot/fake"
+	"sync-bot/git"
+	"sync-bot/hook"
+	"sync-bot/platform"
+	"sync-bot/secret"
+)
+
+// replayOptions are the options of the replay subcommand.
+type replayOptions struct {
+	// configPath is the JSON file of the robot configuration.
+	configPath string
+	// statePath is the JSON file of the fake platform state.
+	statePath string
+	// tokenPath is the file of the platform token, the real platform is read when set.
+	tokenPath string
+	// gitBase is the base URL of the repositories cloned and pushed to.
+	gitBase string
+}
+
+func (o *replayOptions) addFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.configPath, "config", "",
+		"Path to the JSON configuration of the robot. Every repository is handled if empty.")
+	fs.StringVar(&o.statePath, "state", "",
+		"Path to the JSON state of the fake platform: pull requests, commits, comments and branches.")
+	fs.StringVar(&o.tokenPath, "token-path", "",
+		"Path to the platform token. If set, the real platform is read instead of the fake one, writes are still only printed.")
+	fs.StringVar(&o.gitBase, "git-base", "",
+		"Base URL of the repositories cloned and pushed to, like file:///srv/git. An empty scratch directory is used if empty.")
+}
+
+// runReplay replays recorded webhook deliveries, the .http files of the tests
+// directory or JSON lines captures, and prints what the robot produced.
+func runReplay(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sync-bot replay [flags] <file.http|file.jsonl>...")
+		fs.PrintDefaults()
+	}
+	var o replayOptions
+	o.addFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no deliveries to replay")
+	}
+
+	logger := logrus.NewEntry(logrus.StandardLogger()).WithField("component", component)
+	replayer, cleanup, err := o.newReplayer(logger)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	for _, name := range fs.Args() {
+		deliveries, errs := readDeliveries(name)
+		for _, err := range errs {
+			fmt.Fprintf(out, "== %v\n", err)
+		}
+		for _, d := range deliveries {
+			result, err := replayer.Replay(d)
+			printReplay(out, d, result, err)
+		}
+	}
+	return nil
+}
+
+func (o *replayOptions) newReplayer(logger *logrus.Entry) (*hook.Replayer, func(), error) {
+	cnf := new(hook.Configuration)
+	if o.configPath != "" {
+		if err := readJSON(o.configPath, cnf); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	scratch, err := os.MkdirTemp("", "sync-bot-replay-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	base := o.gitBase
+	if base == "" {
+		base = "file://" + filepath.Join(scratch, "remote")
+	}
+	gitClient, err := git.NewClientWithBase(base, filepath.Join(scratch, "repos"))
+	if err != nil {
+		_ = os.RemoveAll(scratch)
+		return nil, nil, err
+	}
+	cleanup := func() {
+		_ = gitClient.Clean()
+		_ = os.RemoveAll(scratch)
+	}
+
+	var replayer *hook.Replayer
+	if o.tokenPath != "" {
+		agent := secret.NewAgent()
+		if err = agent.LoadSecrets([]string{o.tokenPath}); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		tokens := secret.NewTokens(agent.GetGenerator(o.tokenPath))
+		gitClient.SetCredentials("sync-bot", tokens.Generator("", secret.RolePush))
+		replayer, err = hook.NewDryReplayer(cnf, tokens, gitClient, logger)
+	} else {
+		state := fake.NewClient()
+		if o.statePath != "" {
+			if err = readJSON(o.statePath, state); err != nil {
+				cleanup()
+				return nil, nil, err
+			}
+		}
+		gitClient.SetCredentials("sync-bot", func() []byte { return []byte("replay") })
+		replayer, err = hook.NewFakeReplayer(cnf, state, gitClient, logger)
+	}
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return replayer, cleanup, nil
+}
+
+func readJSON(path string, v interface{}) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("decode %s failed: %v", path, err)
+	}
+	return nil
+}
+
+// readDeliveries reads the deliveries of a JSON lines capture, or the single
+// delivery of an HTTP request file.
+func readDeliveries(name string) ([]*platform.Delivery, []error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, []error{err}
+	}
+	defer f.Close()
+	if strings.HasSuffix(name, ".jsonl") {
+		return platform.ReadJSONDeliveries(f, name)
+	}
+	d, err := platform.ReadHTTPDelivery(f)
+	if err != nil {
+		return nil, []error{fmt.Errorf("%s: %v", name, err)}
+	}
+	d.Source = name
+	return []*platform.Delivery{d}, nil
+}
+
+func printReplay(out io.Writer, d *platform.Delivery, result *hook.ReplayResult, err error) {
+	if result == nil {
+		fmt.Fprintf(out, "== %s: %v\n\n", d.Source, err)
+		return
+	}
+	if result.Kind == "" {
+		fmt.Fprintf(out, "== %s: not handled\n\n", d.Source)
+		return
+	}
+	evt := result.Event
+	fmt.Fprintf(out, "== %s: %s %s %s/%s#%s\n", d.Source, d.Platform(), result.Kind,
+		utils.GetString(evt.Org), utils.GetString(evt.Repo), utils.GetString(evt.Number))
+	if err != nil {
+		fmt.Fprintf(out, "error: %v\n", err)
+	}
+	for _, c := range result.Comments {
+		fmt.Fprintf(out, "-- comment on pull request %s/%s#%s\n%s\n", c.Org, c.Repo, c.Number, strings.TrimSpace(c.Body))
+	}
+	for _, c := range result.IssueComments {
+		fmt.Fprintf(out, "-- comment on issue %s/%s#%s\n%s\n", c.Org, c.Repo, c.Number, strings.TrimSpace(c.Body))
+	}
+	for _, pr := range result.PullRequests {
+		fmt.Fprintf(out, "-- pull request %s: %s -> %s\n%s\n%s\n", utils.GetString(pr.URL),
+			utils.GetString(pr.Head), utils.GetString(pr.Base), utils.GetString(pr.Title), strings.TrimSpace(utils.GetString(pr.Body)))
+	}
+	if len(result.Comments)+len(result.IssueComments)+len(result.PullRequests) == 0 {
+		fmt.Fprintln(out, "-- nothing produced")
+	}
+	fmt.Fprintln(out)
+}
@@ -0,0 +1,212 @@
+// Package report generates the divergence report of the protected branches of
+// repositories: the commits of the reference branches, like master and the
+// LTS-Next ones, missing from every other branch, and how the versions of the
+// packages built from them differ.
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"text/template"
+	"time"
+
+	"sync-bot/git"
+	"sync-bot/util/rpm"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultReferences are the branches compared with when none are given.
+var DefaultReferences = []string{"master", "*-LTS-Next"}
+
+// Report is the divergence report of the repositories of an organization.
+type Report struct {
+	Org string `json:"org"`
+	// References are the patterns of the reference branches.
+	References []string     `json:"references"`
+	Generated  time.Time    `json:"generated"`
+	Repos      []RepoReport `json:"repos"`
+}
+
+// RepoReport lists the diverged branches of a repository.
+type RepoReport struct {
+	Repo string `json:"repo"`
+	// Error tells why the repository could not be compared.
+	Error string `json:"error,omitempty"`
+	// Branches are the branches which diverged from a reference, those in sync
+	// are left out.
+	Branches []BranchReport `json:"branches,omitempty"`
+}
+
+// BranchReport compares a branch with a reference branch.
+type BranchReport struct {
+	Branch    string `json:"branch"`
+	Reference string `json:"reference"`
+	// EVR is the one of the spec file of the repository on Branch.
+	EVR rpm.EVR `json:"evr"`
+	// ReferenceEVR is the one of the spec file on Reference.
+	ReferenceEVR rpm.EVR `json:"reference_evr"`
+	// EVRCompare is -1, 0 or 1 when EVR is older than, equal to or newer than
+	// ReferenceEVR.
+	EVRCompare int `json:"evr_compare"`
+	// Missing are the commits of Reference whose changes are not on Branch,
+	// oldest first.
+	Missing []git.Commit `json:"missing,omitempty"`
+}
+
+// BranchLister lists the protected branches of a repository.
+type BranchLister func(org, repo string) ([]string, error)
+
+// Generator generates divergence reports from clones of the repositories.
+// Create with NewGenerator.
+type Generator struct {
+	git        *git.Client
+	branches   BranchLister
+	references []string
+	log        *logrus.Entry
+}
+
+// NewGenerator returns a generator comparing the branches listed by branches
+// with those matching the patterns of references, DefaultReferences if empty.
+func NewGenerator(c *git.Client, branches BranchLister, references []string, logger *logrus.Entry) *Generator {
+	if len(references) == 0 {
+		references = DefaultReferences
+	}
+	return &Generator{git: c, branches: branches, references: references, log: logger}
+}
+
+// Generate compares the branches of every repository of org. A repository
+// which fails is reported with its error, the others are still compared.
+func (g *Generator) Generate(org string, repos []string) *Report {
+	r := &Report{Org: org, References: g.references, Generated: time.Now().UTC()}
+	for _, repo := range repos {
+		rr, err := g.repo(org, repo)
+		if err != nil {
+			g.log.WithError(err).Errorf("Compare the branches of %s/%s failed", org, repo)
+			rr = RepoReport{Repo: repo, Error: err.Error()}
+		}
+		r.Repos = append(r.Repos, rr)
+	}
+	return r
+}
+
+func (g *Generator) repo(org, repo string) (RepoReport, error) {
+	rr := RepoReport{Repo: repo}
+	branches, err := g.branches(org, repo)
+	if err != nil {
+		return rr, err
+	}
+	sort.Strings(branches)
+	var refs []string
+	for _, b := range branches {
+		if g.isReference(b) {
+			refs = append(refs, b)
+		}
+	}
+	if len(refs) == 0 {
+		return rr, nil
+	}
+
+	r, err := g.git.Clone(org, repo)
+	if err != nil {
+		return rr, err
+	}
+	specs := make(map[string]rpm.EVR)
+	evr := func(branch string) rpm.EVR {
+		if e, ok := specs[branch]; ok {
+			return e
+		}
+		content, err := r.ReadFile("origin/"+branch, repo+".spec")
+		if err != nil {
+			g.log.WithError(err).Debugf("Read the spec file of %s/%s on %s failed", org, repo, branch)
+		}
+		specs[branch] = rpm.ParseSpec(string(content)).EVR()
+		return specs[branch]
+	}
+
+	for _, ref := range refs {
+		for _, branch := range branches {
+			if branch == ref {
+				continue
+			}
+			missing, _, err := r.MissingCommits("origin/"+branch, "origin/"+ref)
+			if err != nil {
+				return rr, err
+			}
+			br := BranchReport{
+				Branch:       branch,
+				Reference:    ref,
+				EVR:          evr(branch),
+				ReferenceEVR: evr(ref),
+				Missing:      missing,
+			}
+			br.EVRCompare = rpm.CompareEVR(br.EVR, br.ReferenceEVR)
+			if len(br.Missing) == 0 && br.EVRCompare == 0 {
+				continue
+			}
+			rr.Branches = append(rr.Branches, br)
+		}
+	}
+	return rr, nil
+}
+
+func (g *Generator) isReference(branch string) bool {
+	for _, pattern := range g.references {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// JSON encodes the report as indented JSON.
+func (r *Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+const markdown = `
+# Divergence report of {{.Org}}
+
+Reference branches: {{range $i, $r := .References}}{{if $i}}, {{end}}` + "`{{$r}}`" + `{{end}}
+{{range .Repos}}
+## {{.Repo}}
+{{if .Error}}
+Compare failed: {{.Error}}
+{{else if not .Branches}}
+All branches are in sync with the reference branches.
+{{else}}
+| Branch | Reference | EVR | Reference EVR | Missing commits |
+|---|---|---|---|---|
+{{- range .Branches}}
+|{{.Branch}}|{{.Reference}}|{{.EVR}}{{evr .EVRCompare}}|{{.ReferenceEVR}}|{{len .Missing}}|
+{{- end}}
+{{range .Branches}}{{if .Missing}}
+<details><summary>{{.Branch}} lacks {{len .Missing}} commit(s) of {{.Reference}}</summary>
+
+{{range .Missing}}- {{slice .SHA 0 8}} {{.Subject}}
+{{end}}</details>
+{{end}}{{end}}{{end}}{{end}}`
+
+var markdownTmpl = template.Must(template.New("markdown").Funcs(template.FuncMap{
+	"evr": func(c int) string {
+		switch c {
+		case -1:
+			return " (older)"
+		case 1:
+			return " (newer)"
+		}
+		return ""
+	},
+}).Parse(markdown))
+
+// Markdown renders the report as Markdown, like for an issue.
+func (r *Report) Markdown() (string, error) {
+	var buffer bytes.Buffer
+	if err := markdownTmpl.Execute(&buffer, r); err != nil {
+		return "", fmt.Errorf("render the report failed: %v", err)
+	}
+	return buffer.String(), nil
+}
@@ -0,0 +1,116 @@
+package report
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sync-bot/git"
+
+	"github.com/sirupsen/logrus"
+)
+
+func run(t *testing.T, dir string, arg ...string) {
+	t.Helper()
+	cmd := exec.Command("git", arg...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=alice", "GIT_AUTHOR_EMAIL=alice@example.com",
+		"GIT_COMMITTER_NAME=alice", "GIT_COMMITTER_EMAIL=alice@example.com")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v, output: %s", arg, err, out)
+	}
+}
+
+func commit(t *testing.T, dir, message string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run(t, dir, "add", "-A")
+	run(t, dir, "commit", "-m", message)
+}
+
+func spec(version, release string) string {
+	return "Name: foo\nVersion: " + version + "\nRelease: " + release + "\n"
+}
+
+// newRepo creates o/foo where openEuler-22.03-LTS lacks the "bump" commit of
+// master and openEuler-22.03-LTS-Next, and openEuler-24.03-LTS is in sync.
+func newRepo(t *testing.T) (*git.Client, string) {
+	base, work := t.TempDir(), t.TempDir()
+	run(t, base, "init", "--bare", "-q", "o/foo.git")
+	run(t, filepath.Join(base, "o", "foo.git"), "symbolic-ref", "HEAD", "refs/heads/master")
+	run(t, work, "clone", "-q", filepath.Join(base, "o", "foo.git"), "foo")
+	dir := filepath.Join(work, "foo")
+	run(t, dir, "symbolic-ref", "HEAD", "refs/heads/master")
+	commit(t, dir, "init", map[string]string{"foo.spec": spec("1.0", "1")})
+	run(t, dir, "push", "-q", "origin", "master", "master:openEuler-22.03-LTS")
+	commit(t, dir, "bump", map[string]string{"foo.spec": spec("1.1", "1")})
+	run(t, dir, "push", "-q", "origin", "master", "master:openEuler-22.03-LTS-Next", "master:openEuler-24.03-LTS")
+
+	c, err := git.NewClientWithBase("file://"+base, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetCredentials("sync-bot", func() []byte { return []byte("token") })
+	t.Cleanup(func() { _ = c.Clean() })
+	return c, base
+}
+
+func TestGenerate(t *testing.T) {
+	c, _ := newRepo(t)
+	branches := func(org, repo string) ([]string, error) {
+		if repo != "foo" {
+			return nil, os.ErrNotExist
+		}
+		return []string{"master", "openEuler-22.03-LTS", "openEuler-22.03-LTS-Next", "openEuler-24.03-LTS"}, nil
+	}
+	g := NewGenerator(c, branches, nil, logrus.NewEntry(logrus.StandardLogger()))
+
+	r := g.Generate("o", []string{"foo", "missing"})
+
+	if len(r.Repos) != 2 || r.Repos[1].Error == "" {
+		t.Fatalf("Generate() = %+v, want the missing repository reported with its error", r.Repos)
+	}
+	got := make(map[string]BranchReport)
+	for _, b := range r.Repos[0].Branches {
+		got[b.Branch+" "+b.Reference] = b
+	}
+	if len(got) != 2 {
+		t.Fatalf("Generate() branches = %+v, want openEuler-22.03-LTS diverged from both references", r.Repos[0].Branches)
+	}
+	for _, ref := range []string{"master", "openEuler-22.03-LTS-Next"} {
+		b, ok := got["openEuler-22.03-LTS "+ref]
+		if !ok {
+			t.Errorf("openEuler-22.03-LTS is not compared with %s", ref)
+			continue
+		}
+		if len(b.Missing) != 1 || b.Missing[0].Subject != "bump" {
+			t.Errorf("missing commits of %s = %v, want bump", ref, b.Missing)
+		}
+		if b.EVR.String() != "1.0-1" || b.ReferenceEVR.String() != "1.1-1" || b.EVRCompare != -1 {
+			t.Errorf("EVR = %v, reference EVR = %v, compare = %d", b.EVR, b.ReferenceEVR, b.EVRCompare)
+		}
+	}
+
+	md, err := r.Markdown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"|openEuler-22.03-LTS|master|1.0-1 (older)|1.1-1|1|",
+		"openEuler-22.03-LTS lacks 1 commit(s) of master",
+		"## missing\n\nCompare failed:",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown() = %s\ndoesn't hold %q", md, want)
+		}
+	}
+	if _, err = r.JSON(); err != nil {
+		t.Errorf("JSON() error = %v", err)
+	}
+}
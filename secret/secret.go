@@ -5,15 +5,34 @@ import (
 	"fmt"
 	"io/ioutil"
 	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
-var lock sync.RWMutex
-var secretsMap map[string][]byte
+// Agent keeps the values of secret files in memory and reloads them when the
+// files change, e.g. when Kubernetes rotates a mounted secret.
+type Agent struct {
+	lock        sync.RWMutex
+	secretsMap  map[string][]byte
+	subscribers []func(path string)
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+var defaultAgent = NewAgent()
+
+// NewAgent returns an agent without any secret loaded.
+func NewAgent() *Agent {
+	return &Agent{
+		secretsMap: make(map[string][]byte),
+		stop:       make(chan struct{}),
+	}
+}
 
 // LoadSecrets loads multiple paths of secrets and add them in a map.
-func LoadSecrets(paths []string) error {
-	lock.Lock()
-	defer lock.Unlock()
+func (a *Agent) LoadSecrets(paths []string) error {
 	secrets := make(map[string][]byte)
 	for _, path := range paths {
 		secretValue, err := loadSingleSecret(path)
@@ -22,10 +41,98 @@ func LoadSecrets(paths []string) error {
 		}
 		secrets[path] = secretValue
 	}
-	secretsMap = secrets
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.secretsMap = secrets
 	return nil
 }
 
+// Start polls the loaded secret files every interval until Stop is called.
+func (a *Agent) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-a.stop:
+				return
+			case <-ticker.C:
+				a.reload()
+			}
+		}
+	}()
+}
+
+// Stop stops polling the secret files.
+func (a *Agent) Stop() {
+	a.stopOnce.Do(func() {
+		close(a.stop)
+	})
+}
+
+// Subscribe registers fn to be called with the path of every secret that changed.
+func (a *Agent) Subscribe(fn func(path string)) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.subscribers = append(a.subscribers, fn)
+}
+
+// reload reads all secret files and swaps them in at once. If any file cannot
+// be read, e.g. in the middle of a rotation, the current values are kept.
+func (a *Agent) reload() {
+	a.lock.RLock()
+	current := a.secretsMap
+	a.lock.RUnlock()
+
+	secrets := make(map[string][]byte, len(current))
+	var changed []string
+	for path, value := range current {
+		secretValue, err := loadSingleSecret(path)
+		if err != nil {
+			logrus.WithError(err).Warnf("Reload secret %s failed, keep the current value", path)
+			return
+		}
+		secrets[path] = secretValue
+		if !bytes.Equal(value, secretValue) {
+			changed = append(changed, path)
+		}
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	a.lock.Lock()
+	a.secretsMap = secrets
+	subscribers := a.subscribers
+	a.lock.Unlock()
+
+	for _, path := range changed {
+		logrus.Infof("Secret %s reloaded.", path)
+		for _, fn := range subscribers {
+			fn(path)
+		}
+	}
+}
+
+// GetSecret returns the value of a secret stored in a map.
+func (a *Agent) GetSecret(secretPath string) []byte {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	return a.secretsMap[secretPath]
+}
+
+// GetGenerator returns a function that gets the current value of a given secret.
+func (a *Agent) GetGenerator(secretPath string) func() []byte {
+	return func() []byte {
+		return a.GetSecret(secretPath)
+	}
+}
+
+// LoadSecrets loads multiple paths of secrets and add them in a map.
+func LoadSecrets(paths []string) error {
+	return defaultAgent.LoadSecrets(paths)
+}
+
 // loadSingleSecret reads and returns the value of a single file.
 func loadSingleSecret(path string) ([]byte, error) {
 	b, err := ioutil.ReadFile(path)
@@ -37,14 +144,10 @@ func loadSingleSecret(path string) ([]byte, error) {
 
 // GetSecret returns the value of a secret stored in a map.
 func GetSecret(secretPath string) []byte {
-	lock.RLock()
-	defer lock.RUnlock()
-	return secretsMap[secretPath]
+	return defaultAgent.GetSecret(secretPath)
 }
 
 // GetGenerator returns a function that gets the value of a given secret.
 func GetGenerator(secretPath string) func() []byte {
-	return func() []byte {
-		return GetSecret(secretPath)
-	}
+	return defaultAgent.GetGenerator(secretPath)
 }
@@ -6,6 +6,7 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"time"
 )
 
 var files []string
@@ -167,3 +168,53 @@ func Test_loadSingleSecret(t *testing.T) {
 		})
 	}
 }
+
+func TestAgentReload(t *testing.T) {
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("failed to set up a temporary file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.WriteString("TOKEN1"); err != nil {
+		t.Fatalf("failed to write a fake secret to a file: %v", err)
+	}
+	f.Close()
+
+	a := NewAgent()
+	if err = a.LoadSecrets([]string{f.Name()}); err != nil {
+		t.Fatalf("LoadSecrets() error = %v", err)
+	}
+	changed := make(chan string, 1)
+	a.Subscribe(func(path string) {
+		changed <- path
+	})
+	generator := a.GetGenerator(f.Name())
+	a.Start(10 * time.Millisecond)
+	defer a.Stop()
+
+	if got := generator(); !reflect.DeepEqual(got, []byte("TOKEN1")) {
+		t.Errorf("GetGenerator() = %s, want %s", got, "TOKEN1")
+	}
+
+	if err = ioutil.WriteFile(f.Name(), []byte("TOKEN2\n"), 0600); err != nil {
+		t.Fatalf("failed to rotate the fake secret: %v", err)
+	}
+	select {
+	case path := <-changed:
+		if path != f.Name() {
+			t.Errorf("Subscribe() notified %v, want %v", path, f.Name())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("secret was not reloaded")
+	}
+	if got := generator(); !reflect.DeepEqual(got, []byte("TOKEN2")) {
+		t.Errorf("GetGenerator() = %s, want %s", got, "TOKEN2")
+	}
+
+	// a missing file keeps the current value
+	os.Remove(f.Name())
+	a.reload()
+	if got := generator(); !reflect.DeepEqual(got, []byte("TOKEN2")) {
+		t.Errorf("GetGenerator() = %s, want %s", got, "TOKEN2")
+	}
+}
@@ -0,0 +1,113 @@
+package secret
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Role is the identity a token acts as on the platform.
+type Role string
+
+// Role enum
+const (
+	// RoleAPI reads pull requests, comments and labels them.
+	RoleAPI Role = "api"
+	// RolePush pushes branches, owns the forks and creates the sync pull requests.
+	RolePush Role = "push"
+)
+
+// Tokens holds the token generators of every role, optionally per organization,
+// and the users the push tokens belong to.
+type Tokens struct {
+	roles map[Role]func() []byte
+	orgs  map[string]map[Role]func() []byte
+	users map[string]string
+}
+
+// NewTokens returns tokens using api for every role until another token is set.
+func NewTokens(api func() []byte) *Tokens {
+	return &Tokens{
+		roles: map[Role]func() []byte{RoleAPI: api},
+		orgs:  make(map[string]map[Role]func() []byte),
+		users: make(map[string]string),
+	}
+}
+
+// SetUser sets the user the push token of org belongs to, the empty org stands
+// for the push token of all organizations.
+func (t *Tokens) SetUser(org, user string) {
+	t.users[org] = user
+}
+
+// User returns the user set for the push token of org, empty if none is set.
+func (t *Tokens) User(org string) string {
+	return t.users[org]
+}
+
+// Set sets the token generator of role.
+func (t *Tokens) Set(role Role, generator func() []byte) {
+	t.roles[role] = generator
+}
+
+// SetOrg sets the token generator of role for the repositories of org. An empty
+// role sets it for all roles.
+func (t *Tokens) SetOrg(org string, role Role, generator func() []byte) {
+	if _, ok := t.orgs[org]; !ok {
+		t.orgs[org] = make(map[Role]func() []byte)
+	}
+	if role == "" {
+		t.orgs[org][RoleAPI] = generator
+		t.orgs[org][RolePush] = generator
+		return
+	}
+	t.orgs[org][role] = generator
+}
+
+// Generator returns the token generator of role for org. It falls back to the
+// token of role for all organizations, then to the api token.
+func (t *Tokens) Generator(org string, role Role) func() []byte {
+	if g, ok := t.orgs[org][role]; ok {
+		return g
+	}
+	if g, ok := t.roles[role]; ok {
+		return g
+	}
+	return t.roles[RoleAPI]
+}
+
+// Orgs returns the organizations which have their own tokens.
+func (t *Tokens) Orgs() []string {
+	orgs := make([]string, 0, len(t.orgs))
+	for org := range t.orgs {
+		orgs = append(orgs, org)
+	}
+	sort.Strings(orgs)
+	return orgs
+}
+
+// ParseOrgUser parses an organization user option like "org=user".
+func ParseOrgUser(option string) (org, user string, err error) {
+	org, user, ok := strings.Cut(option, "=")
+	if !ok || org == "" || user == "" {
+		return "", "", fmt.Errorf("invalid organization user %q, want org=user", option)
+	}
+	return org, user, nil
+}
+
+// ParseOrgToken parses an organization token option like "org=path" or
+// "org:role=path".
+func ParseOrgToken(option string) (org string, role Role, path string, err error) {
+	key, path, ok := strings.Cut(option, "=")
+	if !ok || key == "" || path == "" {
+		return "", "", "", fmt.Errorf("invalid organization token %q, want org[:role]=path", option)
+	}
+	org, r, _ := strings.Cut(key, ":")
+	role = Role(r)
+	switch role {
+	case "", RoleAPI, RolePush:
+	default:
+		return "", "", "", fmt.Errorf("invalid role %q of organization token %q", r, option)
+	}
+	return org, role, path, nil
+}
@@ -0,0 +1,100 @@
+package secret
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokensGenerator(t *testing.T) {
+	static := func(s string) func() []byte {
+		return func() []byte {
+			return []byte(s)
+		}
+	}
+	tokens := NewTokens(static("api"))
+	tokens.Set(RolePush, static("push"))
+	tokens.SetOrg("openEuler", "", static("openEuler"))
+	tokens.SetOrg("src-openeuler", RolePush, static("src-openeuler-push"))
+
+	type args struct {
+		org  string
+		role Role
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{"default api", args{"foo", RoleAPI}, "api"},
+		{"default push", args{"foo", RolePush}, "push"},
+		{"org for all roles", args{"openEuler", RolePush}, "openEuler"},
+		{"org push", args{"src-openeuler", RolePush}, "src-openeuler-push"},
+		{"org falls back to default", args{"src-openeuler", RoleAPI}, "api"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tokens.Generator(tt.args.org, tt.args.role)(); string(got) != tt.want {
+				t.Errorf("Generator() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if got := tokens.Orgs(); !reflect.DeepEqual(got, []string{"openEuler", "src-openeuler"}) {
+		t.Errorf("Orgs() = %v", got)
+	}
+}
+
+func TestParseOrgToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		option   string
+		wantOrg  string
+		wantRole Role
+		wantPath string
+		wantErr  bool
+	}{
+		{"all roles", "openEuler=/etc/token", "openEuler", "", "/etc/token", false},
+		{"push role", "openEuler:push=/etc/token", "openEuler", RolePush, "/etc/token", false},
+		{"unknown role", "openEuler:admin=/etc/token", "", "", "", true},
+		{"no path", "openEuler=", "", "", "", true},
+		{"no org", "/etc/token", "", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			org, role, path, err := ParseOrgToken(tt.option)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseOrgToken() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if org != tt.wantOrg || role != tt.wantRole || path != tt.wantPath {
+				t.Errorf("ParseOrgToken() = %v, %v, %v", org, role, path)
+			}
+		})
+	}
+}
+
+func TestParseOrgUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		option   string
+		wantOrg  string
+		wantUser string
+		wantErr  bool
+	}{
+		{"org user", "openEuler=sync-bot", "openEuler", "sync-bot", false},
+		{"no user", "openEuler=", "", "", true},
+		{"no org", "sync-bot", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			org, user, err := ParseOrgUser(tt.option)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseOrgUser() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if org != tt.wantOrg || user != tt.wantUser {
+				t.Errorf("ParseOrgUser() = %v, %v", org, user)
+			}
+		})
+	}
+}
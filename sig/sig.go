@@ -0,0 +1,118 @@
+// Package sig looks up the SIGs (special interest groups) repositories belong
+// to, and their maintainers and committers, from the SIG info API of a
+// community.
+package sig
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"sync-bot/platform"
+)
+
+// SIG is a special interest group owning repositories.
+type SIG struct {
+	Name        string   `json:"sig_name"`
+	Maintainers []string `json:"maintainers"`
+	Committers  []string `json:"committers"`
+}
+
+// Answer is the answer of the SIG info API for a repository.
+type Answer struct {
+	Data []SIG `json:"data"`
+}
+
+// Client looks up the SIGs of repositories with GET <url>?community=<community>&repo=<org>/<repo>.
+// The answers are cached for ttl, the failures are not.
+type Client struct {
+	api       *platform.API
+	community string
+	ttl       time.Duration
+
+	lock  sync.Mutex
+	cache map[string]cached
+}
+
+type cached struct {
+	sigs    []SIG
+	expires time.Time
+}
+
+// NewClient returns a client of the SIG info API at url for community.
+func NewClient(url, community string, ttl time.Duration) *Client {
+	return &Client{
+		api:       platform.NewAPI(url, nil),
+		community: community,
+		ttl:       ttl,
+		cache:     make(map[string]cached),
+	}
+}
+
+// SIGs returns the SIGs org/repo belongs to.
+func (c *Client) SIGs(org, repo string) ([]SIG, error) {
+	fullName := org + "/" + repo
+	c.lock.Lock()
+	e, ok := c.cache[fullName]
+	c.lock.Unlock()
+	if ok && time.Now().Before(e.expires) {
+		return e.sigs, nil
+	}
+
+	var answer Answer
+	query := url.Values{"community": {c.community}, "repo": {fullName}}
+	if err := c.api.Do(http.MethodGet, "", query, nil, &answer); err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	c.cache[fullName] = cached{sigs: answer.Data, expires: time.Now().Add(c.ttl)}
+	c.lock.Unlock()
+	return answer.Data, nil
+}
+
+// Maintainers returns the maintainers of the SIGs org/repo belongs to.
+func (c *Client) Maintainers(org, repo string) ([]string, error) {
+	sigs, err := c.SIGs(org, repo)
+	if err != nil {
+		return nil, err
+	}
+	var users []string
+	for _, s := range sigs {
+		users = appendUnique(users, s.Maintainers...)
+	}
+	return users, nil
+}
+
+// Members returns the maintainers and then the committers of the SIGs org/repo
+// belongs to.
+func (c *Client) Members(org, repo string) ([]string, error) {
+	sigs, err := c.SIGs(org, repo)
+	if err != nil {
+		return nil, err
+	}
+	var users []string
+	for _, s := range sigs {
+		users = appendUnique(users, s.Maintainers...)
+	}
+	for _, s := range sigs {
+		users = appendUnique(users, s.Committers...)
+	}
+	return users, nil
+}
+
+func appendUnique(users []string, more ...string) []string {
+	for _, u := range more {
+		found := false
+		for _, v := range users {
+			if v == u {
+				found = true
+				break
+			}
+		}
+		if !found {
+			users = append(users, u)
+		}
+	}
+	return users
+}
@@ -0,0 +1,81 @@
+package sig
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T, requests *int32) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		if r.URL.Query().Get("community") != "openeuler" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var answer Answer
+		if r.URL.Query().Get("repo") == "src-openeuler/kernel" {
+			answer.Data = []SIG{
+				{Name: "Kernel", Maintainers: []string{"alice", "bob"}, Committers: []string{"carol", "alice"}},
+				{Name: "sig-security", Maintainers: []string{"dave"}},
+			}
+		}
+		_ = json.NewEncoder(w).Encode(answer)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestMembers(t *testing.T) {
+	var requests int32
+	c := NewClient(newTestServer(t, &requests).URL, "openeuler", time.Hour)
+
+	maintainers, err := c.Maintainers("src-openeuler", "kernel")
+	if want := []string{"alice", "bob", "dave"}; err != nil || !reflect.DeepEqual(maintainers, want) {
+		t.Errorf("Maintainers() = %v, %v, want %v", maintainers, err, want)
+	}
+	members, err := c.Members("src-openeuler", "kernel")
+	if want := []string{"alice", "bob", "dave", "carol"}; err != nil || !reflect.DeepEqual(members, want) {
+		t.Errorf("Members() = %v, %v, want %v", members, err, want)
+	}
+	if requests != 1 {
+		t.Errorf("sent %d requests, want the answer cached", requests)
+	}
+
+	if members, err = c.Members("src-openeuler", "other"); err != nil || len(members) != 0 {
+		t.Errorf("Members() of a repository of no SIG = %v, %v", members, err)
+	}
+}
+
+func TestCacheExpires(t *testing.T) {
+	var requests int32
+	c := NewClient(newTestServer(t, &requests).URL, "openeuler", time.Nanosecond)
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.SIGs("src-openeuler", "kernel"); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if requests != 2 {
+		t.Errorf("sent %d requests, want the expired answer asked again", requests)
+	}
+}
+
+func TestFailureNotCached(t *testing.T) {
+	var requests int32
+	c := NewClient(newTestServer(t, &requests).URL, "other", time.Hour)
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.SIGs("src-openeuler", "kernel"); err == nil {
+			t.Fatalf("SIGs() of an unknown community succeeded")
+		}
+	}
+	if requests != 2 {
+		t.Errorf("sent %d requests, want the failure not cached", requests)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/opensourceways/robot-framework-lib/framework"
+	"sync-bot/hook"
+	"sync-bot/util"
+)
+
+// syncOptions are the options of the sync subcommand, the configuration and
+// tokens are given by the flags of the robot.
+type syncOptions struct {
+	org      string
+	repo     string
+	number   string
+	branches string
+	strategy string
+	dryRun   bool
+}
+
+func (o *syncOptions) addFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.org, "org", "", "Organization of the pull request.")
+	fs.StringVar(&o.repo, "repo", "", "Repository of the pull request.")
+	fs.StringVar(&o.number, "pr", "", "Number of the pull request.")
+	fs.StringVar(&o.branches, "branches", "", "Comma separated branches to sync the pull request to.")
+	fs.StringVar(&o.strategy, "strategy", "pick", "Strategy of the sync, pick or merge.")
+	fs.BoolVar(&o.dryRun, "dry-run", false, "Preview the sync without pushing or creating pull requests.")
+}
+
+func (o *syncOptions) validate() error {
+	var missing []string
+	for _, f := range []struct{ name, value string }{
+		{"--org", o.org}, {"--repo", o.repo}, {"--pr", o.number}, {"--branches", o.branches},
+	} {
+		if f.value == "" {
+			missing = append(missing, f.name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func (o *syncOptions) branchList() []string {
+	return util.SplitList(o.branches)
+}
+
+// newCommandRobot parses args with the flags of the robot added to fs, and
+// creates the robot with the configuration and the tokens they give. Call stop
+// when done with it.
+func newCommandRobot(fs *flag.FlagSet, args []string) (bot hook.CommandRobot, stop func(), err error) {
+	logger := framework.NewLogger().WithField("component", component)
+	opt := new(robotOptions)
+	opt.gatherOptions(fs, logger, args...)
+	if opt.service.Interrupt {
+		return nil, nil, errors.New("invalid configuration")
+	}
+
+	tokens, _, agent, err := opt.loadSecrets()
+	if err != nil {
+		return nil, nil, fmt.Errorf("load secrets failed: %v", err)
+	}
+	cnf := opt.service.ConfigmapAgentValue.GetConfigmap().(*hook.Configuration)
+	r := hook.NewCommandRobot(cnf, tokens, logger)
+	if r == nil {
+		agent.Stop()
+		return nil, nil, errors.New("create the robot failed")
+	}
+	return r, agent.Stop, nil
+}
+
+// runSync syncs a pull request from a shell, like /sync does after the merge,
+// and prints the result of every branch. It is meant for re-running a sync
+// whose webhook was lost.
+func runSync(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	var so syncOptions
+	so.addFlags(fs)
+	bot, stop, err := newCommandRobot(fs, args)
+	if err != nil {
+		return err
+	}
+	defer stop()
+	if err = so.validate(); err != nil {
+		return err
+	}
+
+	results, err := bot.SyncPullRequest(so.org, so.repo, so.number, so.branchList(), so.strategy, so.dryRun)
+	if err != nil {
+		return err
+	}
+	printSync(out, results)
+	return nil
+}
+
+func printSync(out io.Writer, results []hook.SyncResult) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "BRANCH\tSTATUS\tPULL REQUEST\tDETAIL")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Branch, r.Status, r.PullRequest, strings.ReplaceAll(r.Detail, "<br>", " "))
+	}
+	_ = w.Flush()
+}
+
+// syncBranchOptions are the options of the sync-branch subcommand.
+type syncBranchOptions struct {
+	org  string
+	repo string
+	from string
+	to   string
+}
+
+func (o *syncBranchOptions) addFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.org, "org", "", "Organization of the repository.")
+	fs.StringVar(&o.repo, "repo", "", "Name of the repository.")
+	fs.StringVar(&o.from, "from", "", "Branch whose commits are synced.")
+	fs.StringVar(&o.to, "to", "", "Branch the commits missing from are synced to.")
+}
+
+func (o *syncBranchOptions) validate() error {
+	if o.org == "" || o.repo == "" || o.from == "" || o.to == "" {
+		return errors.New("--org, --repo, --from and --to are required")
+	}
+	return nil
+}
+
+// runSyncBranch syncs the commits of a branch missing from another with one
+// pull request, like /sync-branch does on an issue, and prints the result.
+func runSyncBranch(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("sync-branch", flag.ExitOnError)
+	var so syncBranchOptions
+	so.addFlags(fs)
+	bot, stop, err := newCommandRobot(fs, args)
+	if err != nil {
+		return err
+	}
+	defer stop()
+	if err = so.validate(); err != nil {
+		return err
+	}
+
+	result, err := bot.SyncBranch(so.org, so.repo, so.from, so.to)
+	if err != nil {
+		return err
+	}
+	printSyncBranch(out, result)
+	return nil
+}
+
+func printSyncBranch(out io.Writer, result *hook.BranchSyncResult) {
+	fmt.Fprintf(out, "%s -> %s: %s %s\n", result.From, result.To, result.Status, result.PullRequest)
+	if result.Detail != "" {
+		fmt.Fprintln(out, strings.ReplaceAll(result.Detail, "<br>", " "))
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "COMMIT\tSUBJECT\tRESULT")
+	for _, c := range result.Picked {
+		fmt.Fprintf(w, "%s\t%s\tpicked\n", c.SHA, c.Subject)
+	}
+	for _, c := range result.Skipped {
+		fmt.Fprintf(w, "%s\t%s\tskipped, already present\n", c.SHA, c.Subject)
+	}
+	_ = w.Flush()
+}
@@ -2,6 +2,7 @@ package util
 
 import (
 	"regexp"
+	"strings"
 )
 
 var (
@@ -9,12 +10,16 @@ var (
 	titleRegex = regexp.MustCompile(`^(\[sync-bot\]|\[sync\])`)
 	// just /sync-check
 	syncCheckRegex = regexp.MustCompile(`^\s*/sync-check\s*$`)
-	// like "/sync new_branch branch-1.0 foo/bar"
-	syncRegex = regexp.MustCompile(`^\s*/sync([ \t]+[\w\./_-]+)+\s*$`)
+	// like "/sync new_branch branch-1.0 foo/bar" or "/sync -paths=*.patch,src/[ab].c foo"
+	syncRegex = regexp.MustCompile(`^\s*/sync([ \t]+[\w\./_*?,=\[\]-]+)+\s*$`)
+	// like "/sync-branch master openEuler-20.03-LTS"
+	syncBranchCmdRegex = regexp.MustCompile(`^\s*/sync-branch[ \t]+[\w\./_-]+[ \t]+[\w\./_-]+\s*$`)
+	// just /sync-update
+	syncUpdateRegex = regexp.MustCompile(`^\s*/sync-update\s*$`)
 	// /close
 	closeRegex = regexp.MustCompile(`^\s*/close\s*$`)
-	// sync branch name like "sync-pr103-master-to-openEuler-20.03-LTS"
-	syncBranchRegex = regexp.MustCompile(`^sync-pr[\d]+-.+-to-.+$`)
+	// sync branch name like "sync-pr103-master-to-openEuler-20.03-LTS" or "sync-branch-master-to-openEuler-20.03-LTS"
+	syncBranchRegex = regexp.MustCompile(`^sync-(pr[\d]+|branch)-.+-to-.+$`)
 	// repo url contain secret
 	secretURL = regexp.MustCompile(`^([^:]+://)[^:]+:[^@]+(@.+)$`)
 )
@@ -34,6 +39,16 @@ func MatchSyncCheck(content string) bool {
 	return syncCheckRegex.MatchString(content)
 }
 
+// MatchSyncBranchCommand match SyncBranch command
+func MatchSyncBranchCommand(content string) bool {
+	return syncBranchCmdRegex.MatchString(content)
+}
+
+// MatchSyncUpdate match SyncUpdate command
+func MatchSyncUpdate(content string) bool {
+	return syncUpdateRegex.MatchString(content)
+}
+
 // MatchClose match close command
 func MatchClose(content string) bool {
 	return closeRegex.MatchString(content)
@@ -54,6 +69,26 @@ func DeSecret(url string) string {
 	return secretURL.ReplaceAllString(url, "$1******:******$2")
 }
 
+// RemoveSecret remove secret from url
+func RemoveSecret(url string) string {
+	match := secretURL.FindStringSubmatch(url)
+	if match == nil {
+		return url
+	}
+	return match[1] + strings.TrimPrefix(match[2], "@")
+}
+
+// SplitList splits a comma separated list, empty items are left out.
+func SplitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // ContainsString checks if a string is present in a slice of strings.
 func ContainsString(slice []string, s string) bool {
 	for _, item := range slice {
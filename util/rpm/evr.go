@@ -0,0 +1,144 @@
+package rpm
+
+import (
+	"strings"
+)
+
+// EVR is the epoch, version and release of a package
+type EVR struct {
+	Epoch   string `json:"epoch,omitempty"`
+	Version string `json:"version"`
+	Release string `json:"release"`
+}
+
+// String formats EVR like "1:2.0-3", the epoch is left out when empty
+func (e EVR) String() string {
+	if e.Version == "" && e.Release == "" {
+		return ""
+	}
+	s := e.Version + "-" + e.Release
+	if e.Epoch != "" {
+		s = e.Epoch + ":" + s
+	}
+	return s
+}
+
+// CompareEVR compares two EVRs like rpm does, it returns -1, 0 or 1 when a is
+// older than, equal to or newer than b. An empty epoch is 0.
+func CompareEVR(a, b EVR) int {
+	epochA, epochB := a.Epoch, b.Epoch
+	if epochA == "" {
+		epochA = "0"
+	}
+	if epochB == "" {
+		epochB = "0"
+	}
+	if c := CompareVersion(epochA, epochB); c != 0 {
+		return c
+	}
+	if c := CompareVersion(a.Version, b.Version); c != 0 {
+		return c
+	}
+	return CompareVersion(a.Release, b.Release)
+}
+
+// CompareVersion compares two versions or releases with the algorithm of
+// rpmvercmp, it returns -1, 0 or 1.
+func CompareVersion(a, b string) int {
+	if a == b {
+		return 0
+	}
+	for a != "" || b != "" {
+		a = strings.TrimLeftFunc(a, isSeparator)
+		b = strings.TrimLeftFunc(b, isSeparator)
+
+		// a tilde sorts before anything, even the end of the string
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		// a caret sorts after the end of the string, before anything else
+		if strings.HasPrefix(a, "^") || strings.HasPrefix(b, "^") {
+			if a == "" {
+				return -1
+			}
+			if b == "" {
+				return 1
+			}
+			if !strings.HasPrefix(a, "^") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "^") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if a == "" || b == "" {
+			break
+		}
+
+		numeric := isDigit(rune(a[0]))
+		span := isLetter
+		if numeric {
+			span = isDigit
+		}
+		var segA, segB string
+		segA, a = cut(a, span)
+		segB, b = cut(b, span)
+		// a numeric segment is newer than an alphabetic one
+		if segB == "" {
+			if numeric {
+				return 1
+			}
+			return -1
+		}
+		if numeric {
+			segA = strings.TrimLeft(segA, "0")
+			segB = strings.TrimLeft(segB, "0")
+			if len(segA) != len(segB) {
+				if len(segA) > len(segB) {
+					return 1
+				}
+				return -1
+			}
+		}
+		if c := strings.Compare(segA, segB); c != 0 {
+			return c
+		}
+	}
+	if a == "" && b == "" {
+		return 0
+	}
+	if a == "" {
+		return -1
+	}
+	return 1
+}
+
+// cut splits s after the leading runes satisfying f.
+func cut(s string, f func(rune) bool) (string, string) {
+	i := strings.IndexFunc(s, func(r rune) bool { return !f(r) })
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isLetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+func isSeparator(r rune) bool {
+	return !isDigit(r) && !isLetter(r) && r != '~' && r != '^'
+}
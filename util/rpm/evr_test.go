@@ -0,0 +1,73 @@
+package rpm
+
+import (
+	"testing"
+)
+
+func TestCompareVersion(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "2.0", -1},
+		{"2.0.1", "2.0", 1},
+		{"1.10", "1.9", 1},
+		{"1.010", "1.10", 0},
+		{"5.5p1", "5.5p10", -1},
+		{"1.0a", "1.0", 1},
+		{"1a", "1.0", -1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0~rc1", "1.0~rc2", -1},
+		{"1.0^git1", "1.0", 1},
+		{"1.0^git1", "1.0.1", -1},
+		{"1_0", "1.0", 0},
+		{"4.oe2203", "4.oe2203sp1", -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			if got := CompareVersion(tt.a, tt.b); got != tt.want {
+				t.Errorf("CompareVersion(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if got := CompareVersion(tt.b, tt.a); got != -tt.want {
+				t.Errorf("CompareVersion(%q, %q) = %v, want %v", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareEVR(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b EVR
+		want int
+	}{
+		{"equal", EVR{Version: "1.0", Release: "1"}, EVR{Version: "1.0", Release: "1"}, 0},
+		{"empty epoch is zero", EVR{Epoch: "0", Version: "1.0", Release: "1"}, EVR{Version: "1.0", Release: "1"}, 0},
+		{"epoch wins", EVR{Epoch: "1", Version: "1.0", Release: "1"}, EVR{Version: "2.0", Release: "1"}, 1},
+		{"release", EVR{Version: "1.0", Release: "2"}, EVR{Version: "1.0", Release: "10"}, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CompareEVR(tt.a, tt.b); got != tt.want {
+				t.Errorf("CompareEVR(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSpec(t *testing.T) {
+	s := ParseSpec(`
+%global ver 2.0
+Name:    foo
+Epoch:   1
+Version: %{ver}
+Release: 3
+`)
+	if got := s.EVR().String(); got != "1:2.0-3" {
+		t.Errorf("EVR() = %q, want %q", got, "1:2.0-3")
+	}
+	if got := ParseSpec("Version: 2.0\nRelease: 3\n").EVR().String(); got != "2.0-3" {
+		t.Errorf("EVR() without epoch = %q, want %q", got, "2.0-3")
+	}
+}
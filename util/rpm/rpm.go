@@ -9,7 +9,7 @@ import (
 )
 
 // referred fields's key
-var keys = []string{"Version", "Release"}
+var keys = []string{"Epoch", "Version", "Release"}
 
 // Spec spec information
 type Spec struct {
@@ -28,11 +28,16 @@ func NewSpec(content string) *Spec {
 		logrus.Errorf("Failed to decode file content: %v", err)
 		return nil
 	}
+	return ParseSpec(string(decodedContent))
+}
+
+// ParseSpec parses the plain text content of a spec file
+func ParseSpec(content string) *Spec {
 	s := &Spec{
 		macros: make(map[string]string),
 		values: make(map[string]string),
 	}
-	s.lines = strings.Split(string(decodedContent), "\n")
+	s.lines = strings.Split(content, "\n")
 	s.parse()
 	return s
 }
@@ -84,3 +89,13 @@ func (s *Spec) Version() string {
 func (s *Spec) Release() string {
 	return s.values["Release"]
 }
+
+// Epoch get Epoch from spec
+func (s *Spec) Epoch() string {
+	return s.values["Epoch"]
+}
+
+// EVR get Epoch, Version and Release from spec
+func (s *Spec) EVR() EVR {
+	return EVR{Epoch: s.Epoch(), Version: s.Version(), Release: s.Release()}
+}